@@ -3,8 +3,13 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -13,9 +18,109 @@ type Schema struct {
 	Name string `json:"name"`
 }
 
+// Database is a database on the connected server. In MySQL a database is the same
+// thing as a schema; in PostgreSQL each database holds its own schemas and needs
+// its own connection. Owner, Encoding and size are only reported by PostgreSQL.
+type Database struct {
+	Name      string `json:"name"`
+	Current   bool   `json:"current"`
+	Owner     string `json:"owner,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	SizeBytes *int64 `json:"size_bytes,omitempty"`
+	Size      string `json:"size,omitempty"`
+}
+
+// TableSize is a table's total on-disk size, including indexes
+type TableSize struct {
+	Table     string `json:"table"`
+	SizeBytes int64  `json:"size_bytes"`
+	Size      string `json:"size"`
+}
+
+// PartitionInfo describes how a table is partitioned
+type PartitionInfo struct {
+	Table        string      `json:"table"`
+	Partitioned  bool        `json:"partitioned"`
+	PartitionKey string      `json:"partition_key,omitempty"`
+	Partitions   []Partition `json:"partitions"`
+}
+
+// Partition is one partition of a partitioned table
+type Partition struct {
+	Name           string `json:"name"`
+	Bound          string `json:"bound"`
+	SubPartitioned bool   `json:"sub_partitioned,omitempty"`
+	EstimatedRows  *int64 `json:"estimated_rows,omitempty"`
+}
+
+// CollationInfo describes the character set and collation defaults of a schema
+// and the collations of its text columns
+type CollationInfo struct {
+	Schema       string           `json:"schema"`
+	CharacterSet string           `json:"character_set"`
+	Collation    string           `json:"collation"`
+	CType        string           `json:"ctype,omitempty"`
+	Tables       []TableCollation `json:"tables"`
+}
+
+// TableCollation lists the collatable columns of one table
+type TableCollation struct {
+	Table     string            `json:"table"`
+	Collation string            `json:"collation,omitempty"`
+	Columns   []ColumnCollation `json:"columns"`
+}
+
+// ColumnCollation is the character set and collation of one column
+type ColumnCollation struct {
+	Column       string `json:"column"`
+	CharacterSet string `json:"character_set,omitempty"`
+	Collation    string `json:"collation"`
+}
+
+// addColumnCollation appends col to table's entry, adding the entry if this is a
+// new table. Rows must arrive grouped by table.
+func addColumnCollation(tables []TableCollation, table string, col ColumnCollation) []TableCollation {
+	if n := len(tables); n == 0 || tables[n-1].Table != table {
+		tables = append(tables, TableCollation{Table: table, Columns: []ColumnCollation{}})
+	}
+	last := &tables[len(tables)-1]
+	last.Columns = append(last.Columns, col)
+	return tables
+}
+
+// RoutineDefinition is the full CREATE statement of a function or procedure
+type RoutineDefinition struct {
+	Schema     string `json:"schema"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Arguments  string `json:"arguments,omitempty"`
+	Definition string `json:"definition"`
+}
+
+// Setting is a database configuration parameter and its current value. Only
+// PostgreSQL reports the fields after Value.
+type Setting struct {
+	Name            string  `json:"name"`
+	Value           string  `json:"value"`
+	Unit            *string `json:"unit,omitempty"`
+	Category        string  `json:"category,omitempty"`
+	Description     string  `json:"description,omitempty"`
+	Context         string  `json:"context,omitempty"`
+	Source          string  `json:"source,omitempty"`
+	SessionSettable *bool   `json:"session_settable,omitempty"`
+	PendingRestart  bool    `json:"pending_restart,omitempty"`
+}
+
 type QueryResult struct {
 	Columns []string        `json:"columns"`
 	Rows    [][]interface{} `json:"rows"`
+
+	// MoreResultSets holds the second and later result sets of a statement that
+	// returns several, such as a MySQL CALL. Columns and Rows are the first set.
+	MoreResultSets []QueryResult `json:"more_result_sets,omitempty"`
+
+	// Types holds the upper-cased database type name of each column, when the driver reports it
+	Types []string `json:"-"`
 }
 
 type DatabaseAdapter interface {
@@ -29,9 +134,47 @@ type DatabaseAdapter interface {
 	ExecuteSelect(ctx context.Context, query string) (QueryResult, error)
 }
 
+// IdentifierQuoter is implemented by adapters that can quote identifiers in their SQL dialect
+type IdentifierQuoter interface {
+	QuoteIdent(name string) string
+}
+
+// TransactionSettings are the defaults a new connection's transactions run with.
+// IsolationLevel is normalized to upper case with spaces, e.g. READ COMMITTED.
+type TransactionSettings struct {
+	IsolationLevel string `json:"isolation_level"`
+	ReadOnly       bool   `json:"read_only"`
+	TimeZone       string `json:"time_zone"`
+	SystemTimeZone string `json:"system_time_zone,omitempty"`
+}
+
+// TransactionSettingsReader is implemented by adapters that can report their
+// transaction defaults
+type TransactionSettingsReader interface {
+	TransactionSettings(ctx context.Context) (*TransactionSettings, error)
+}
+
+// DatabaseLister is implemented by adapters that can list the databases on their server
+type DatabaseLister interface {
+	ListDatabases(ctx context.Context) ([]Database, error)
+}
+
+// Pinger is implemented by adapters that can check their database is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// normalizeIsolationLevel maps read committed and REPEATABLE-READ style names to
+// one upper-case, space-separated form
+func normalizeIsolationLevel(level string) string {
+	return strings.ToUpper(strings.ReplaceAll(level, "-", " "))
+}
+
 type AdapterRegistry struct {
-	mu       sync.RWMutex
-	adapters map[string]DatabaseAdapter
+	mu          sync.RWMutex
+	adapters    map[string]DatabaseAdapter
+	beforeHooks []BeforeQueryHook
+	afterHooks  []AfterQueryHook
 }
 
 func NewAdapterRegistry() *AdapterRegistry {
@@ -104,9 +247,46 @@ func (r *AdapterRegistry) IsEmpty() bool {
 }
 
 type BaseAdapter struct {
-	db      *sql.DB
-	enabled bool
-	name    string
+	db           *sql.DB
+	enabled      bool
+	name         string
+	policy       ReadOnlyPolicy
+	results      ResultOptions
+	maxOpenConns int
+}
+
+// ErrPoolExhausted reports that a query gave up waiting for a pooled connection
+// because every connection stayed in use, as opposed to a query that ran too long
+var ErrPoolExhausted = errors.New("database connection pool exhausted")
+
+// ErrDatabaseStarting reports that the database refused a new connection because it
+// is starting up or recovering, e.g. during a restart or failover. The same call
+// usually succeeds a few seconds later.
+var ErrDatabaseStarting = errors.New("database is starting up")
+
+// startupRetryDelays are the waits between connection attempts while the database
+// reports that it is starting up
+var startupRetryDelays = []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second}
+
+// retryWhileStarting calls fn again after each of startupRetryDelays while it fails
+// because the database is starting up. It stops early when fn succeeds, fails for
+// another reason, or ctx ends, and returns fn's last error.
+func retryWhileStarting(ctx context.Context, fn func() error) error {
+	err := fn()
+	for _, delay := range startupRetryDelays {
+		if err == nil || !isDatabaseStarting(err) {
+			return err
+		}
+		log.Debug().Err(err).Dur("delay", delay).Msg("Database is starting up, retrying connection")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		err = fn()
+	}
+	return err
 }
 
 func (b *BaseAdapter) Name() string {
@@ -117,6 +297,14 @@ func (b *BaseAdapter) IsEnabled() bool {
 	return b.enabled
 }
 
+// Ping checks the database connection
+func (b *BaseAdapter) Ping(ctx context.Context) error {
+	if b.db == nil {
+		return fmt.Errorf("not connected")
+	}
+	return b.db.PingContext(ctx)
+}
+
 func (b *BaseAdapter) Close() error {
 	if b.db != nil {
 		return b.db.Close()
@@ -124,14 +312,211 @@ func (b *BaseAdapter) Close() error {
 	return nil
 }
 
-func scanQueryResult(rows *sql.Rows) (QueryResult, error) {
+// acquireConn takes a connection from the pool for one query. If the context ends
+// while every pooled connection is in use, the error wraps ErrPoolExhausted so it
+// is not mistaken for a slow query. That needs a finite pool (DB_MAX_OPEN_CONNS);
+// an unlimited pool opens another connection instead of waiting. A database that
+// is starting up is retried briefly, then reported as ErrDatabaseStarting.
+func (b *BaseAdapter) acquireConn(ctx context.Context) (*sql.Conn, error) {
+	var conn *sql.Conn
+	err := retryWhileStarting(ctx, func() (err error) {
+		conn, err = b.db.Conn(ctx)
+		return err
+	})
+	if err == nil {
+		return conn, nil
+	}
+
+	if isDatabaseStarting(err) {
+		return nil, fmt.Errorf("%w: the server is not accepting connections yet, retry the call in a few seconds (%w)", ErrDatabaseStarting, err)
+	}
+
+	if ctx.Err() != nil {
+		if stats := b.db.Stats(); stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+			return nil, fmt.Errorf("%w: all %d connections stayed in use while waiting (%w); raise DB_MAX_OPEN_CONNS or make fewer concurrent calls",
+				ErrPoolExhausted, stats.MaxOpenConnections, err)
+		}
+	}
+	return nil, fmt.Errorf("failed to get database connection: %w", err)
+}
+
+// connectionOption is one key=value pair from POSTGRES_OPTIONS or MYSQL_OPTIONS
+type connectionOption struct {
+	Key   string
+	Value string
+}
+
+// parseConnectionOptions splits an &-separated list of key=value pairs. Keys in
+// protected (compared case-insensitively) are rejected: they control where and how
+// securely the server connects, so they must be set in the connection URL itself.
+func parseConnectionOptions(raw string, protected map[string]bool) ([]connectionOption, error) {
+	var options []connectionOption
+	for _, pair := range strings.Split(raw, "&") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid option %q, expected key=value", pair)
+		}
+		if protected[strings.ToLower(key)] {
+			return nil, fmt.Errorf("option %s cannot be set here, set it in the connection URL", key)
+		}
+		options = append(options, connectionOption{Key: key, Value: strings.TrimSpace(value)})
+	}
+	return options, nil
+}
+
+// scanTableSizes scans (table, size) rows into TableSize values
+func scanTableSizes(rows *sql.Rows) ([]TableSize, error) {
+	sizes := []TableSize{}
+	for rows.Next() {
+		var t TableSize
+		if err := rows.Scan(&t.Table, &t.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan table size: %w", err)
+		}
+		t.Size = formatBytes(t.SizeBytes)
+		sizes = append(sizes, t)
+	}
+	return sizes, rows.Err()
+}
+
+// primaryKeyArgs checks that key names exactly the primary key columns and returns
+// its values in primary key column order
+func primaryKeyArgs(pkColumns []string, key map[string]interface{}) ([]interface{}, error) {
+	if len(pkColumns) == 0 {
+		return nil, fmt.Errorf("table has no primary key")
+	}
+
+	var missing, unknown []string
+	args := make([]interface{}, 0, len(pkColumns))
+	for _, col := range pkColumns {
+		value, ok := key[col]
+		if !ok {
+			missing = append(missing, col)
+			continue
+		}
+		args = append(args, jsonArg(value))
+	}
+	for col := range key {
+		if !containsString(pkColumns, col) {
+			unknown = append(unknown, col)
+		}
+	}
+
+	if len(missing) > 0 || len(unknown) > 0 {
+		sort.Strings(unknown)
+		msg := fmt.Sprintf("key must name exactly the primary key columns (%s)", strings.Join(pkColumns, ", "))
+		if len(missing) > 0 {
+			msg += fmt.Sprintf("; missing: %s", strings.Join(missing, ", "))
+		}
+		if len(unknown) > 0 {
+			msg += fmt.Sprintf("; not in primary key: %s", strings.Join(unknown, ", "))
+		}
+		return nil, errors.New(msg)
+	}
+
+	return args, nil
+}
+
+// buildSelectList quotes the requested columns as a select list after checking each
+// exists in available. No requested columns selects all columns.
+func buildSelectList(requested, available []string, quote func(string) string) (string, error) {
+	if len(requested) == 0 {
+		return "*", nil
+	}
+
+	var unknown []string
+	quoted := make([]string, len(requested))
+	for i, col := range requested {
+		if !containsString(available, col) {
+			unknown = append(unknown, col)
+		}
+		quoted[i] = quote(col)
+	}
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown columns: %s (available: %s)", strings.Join(unknown, ", "), strings.Join(available, ", "))
+	}
+
+	return strings.Join(quoted, ", "), nil
+}
+
+// jsonArg converts a JSON-decoded value into a query argument. JSON numbers decode
+// as float64, so whole numbers are passed as integers.
+func jsonArg(value interface{}) interface{} {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		return int64(f)
+	}
+	return value
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// excludedSchemasCondition builds a WHERE condition leaving the excluded schemas out
+// of column, and its arguments. Placeholders are $n for PostgreSQL and ? for MySQL.
+// With no exclusions the condition is TRUE.
+func excludedSchemasCondition(column string, excluded []string, mysqlPlaceholders bool) (string, []interface{}) {
+	if len(excluded) == 0 {
+		return "TRUE", nil
+	}
+
+	placeholders := make([]string, len(excluded))
+	args := make([]interface{}, len(excluded))
+	for i, name := range excluded {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		if mysqlPlaceholders {
+			placeholders[i] = "?"
+		}
+		args[i] = name
+	}
+	return fmt.Sprintf("%s NOT IN (%s)", column, strings.Join(placeholders, ", ")), args
+}
+
+// scanQueryResult scans every result set of rows. The first set fills Columns and
+// Rows; later non-empty sets are collected in MoreResultSets.
+func scanQueryResult(rows *sql.Rows, opts ResultOptions) (QueryResult, error) {
+	result, err := scanResultSet(rows, opts)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	for rows.NextResultSet() {
+		set, err := scanResultSet(rows, opts)
+		if err != nil {
+			return QueryResult{}, err
+		}
+		// Skip the column-less status result that ends a procedure call
+		if len(set.Columns) > 0 {
+			result.MoreResultSets = append(result.MoreResultSets, set)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return QueryResult{}, err
+	}
+
+	return result, nil
+}
+
+// scanResultSet scans the current result set of rows
+func scanResultSet(rows *sql.Rows, opts ResultOptions) (QueryResult, error) {
 	columns, err := rows.Columns()
 	if err != nil {
 		return QueryResult{}, err
 	}
+	typeNames := columnTypeNames(rows, len(columns))
 
 	var result QueryResult
 	result.Columns = columns
+	result.Types = typeNames
 
 	for rows.Next() {
 		values := make([]interface{}, len(columns))
@@ -146,14 +531,7 @@ func scanQueryResult(rows *sql.Rows) (QueryResult, error) {
 
 		row := make([]interface{}, len(columns))
 		for i, v := range values {
-			switch val := v.(type) {
-			case []byte:
-				row[i] = string(val)
-			case nil:
-				row[i] = nil
-			default:
-				row[i] = val
-			}
+			row[i] = convertValue(v, typeNames[i], opts)
 		}
 		result.Rows = append(result.Rows, row)
 	}
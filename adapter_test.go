@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireConnPoolExhausted(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxOpenConns  int
+		wantExhausted bool
+	}{
+		{name: "pool of one in use", maxOpenConns: 1, wantExhausted: true},
+		{name: "unlimited pool", maxOpenConns: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sql.OpenDB(&migrationTestDB{})
+			defer db.Close()
+			db.SetMaxOpenConns(tt.maxOpenConns)
+			adapter := &BaseAdapter{name: "postgres", enabled: true, db: db}
+
+			held, err := adapter.acquireConn(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer held.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			conn, err := adapter.acquireConn(ctx)
+			if !tt.wantExhausted {
+				if err != nil {
+					t.Fatalf("acquireConn: %v", err)
+				}
+				conn.Close()
+				return
+			}
+			if !errors.Is(err, ErrPoolExhausted) {
+				t.Fatalf("acquireConn error = %v, want ErrPoolExhausted", err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("acquireConn error = %v, want it to wrap the deadline", err)
+			}
+		})
+	}
+}
+
+func TestAcquireConnCanceledIsNotExhaustion(t *testing.T) {
+	db := sql.OpenDB(&migrationTestDB{})
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	adapter := &BaseAdapter{name: "postgres", enabled: true, db: db}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := adapter.acquireConn(ctx); err == nil || errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("acquireConn with a free pool and canceled context = %v, want a plain error", err)
+	}
+}
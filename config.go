@@ -2,6 +2,9 @@ package main
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -12,31 +15,172 @@ type Config struct {
 	Port     string
 	Host     string
 	LogLevel string
+	APIKey   string
+
+	// HMACSecret, when set, requires MCP requests to carry an X-Signature
+	// header with the HMAC-SHA256 of the request body
+	HMACSecret string
+
+	// OAuthMock registers the mock OAuth endpoints Claude Code probes for
+	OAuthMock bool
+
+	// Protocol settings
+	StrictProtocolVersion bool
+
+	// PrettyJSON indents JSON-RPC response bodies for manual testing
+	PrettyJSON bool
+
+	// AllowedClients restricts initialize to these client names; empty allows all
+	AllowedClients []string
+
+	// Health endpoint: response status codes, extra "Name: value" response headers,
+	// and whether every adapter is pinged to decide the status
+	HealthStatusHealthy   int
+	HealthStatusUnhealthy int
+	HealthHeaders         []string
+	HealthCheckDatabases  bool
+
+	// Session settings
+	UseSession         bool
+	SessionMaxLifetime time.Duration
 
 	// Database configurations
 	PostgresURL string
 	MySQLURL    string
+	AppName     string
+
+	// Extra driver connection parameters (&-separated key=value pairs) merged into
+	// POSTGRES_URL and MYSQL_URL at connect time
+	PostgresOptions string
+	MySQLOptions    string
+
+	// System schemas each adapter's ListSchemas leaves out; an empty list exposes them all
+	PostgresExcludedSchemas []string
+	MySQLExcludedSchemas    []string
+
+	// DBMaxOpenConns caps each adapter's connection pool; 0 means no limit, in which
+	// case ErrPoolExhausted is never reported
+	DBMaxOpenConns int
 
 	// Future adapters
 	RedisURL   string
 	MongoDBURL string
+
+	// Result formatting
+	TimestampFormat    string
+	MaxCellBytes       int
+	NullRepresentation string
+
+	// Query policy
+	AllowReadonlyRoutines bool
+	ReadonlyRoutines      []string
+
+	// Tool limits
+	ToolTimeout      time.Duration
+	PatternMaxTables int
+	MaxBatchSize     int
+	MaxTools         int
+	MaxOutputChars   int
+
+	// Upper bound on a request body after Content-Encoding is removed
+	MaxDecompressedBodyBytes int
+
+	// Row cap and overall time limit of one postgres_export_table download (0 disables)
+	TableExportMaxRows int
+	TableExportTimeout time.Duration
+
+	// Catalog views exposed as resources (adapter/view, e.g. postgres/columns) and their row cap
+	MetadataResources       []string
+	MetadataResourceMaxRows int
+
+	// Adapters whose query logs have literals replaced with ? ("true"/"all" for every adapter)
+	LogRedactLiterals []string
+
+	// Tracing: OTLP/HTTP endpoint; tracing is disabled when empty
+	OTLPEndpoint string
+
+	// Feature flags
+	EnableDiagnosticTools bool
+	EnableDebugStats      bool
+
+	// AllowWrites registers tools that modify the database (postgres_run_migration)
+	AllowWrites bool
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
-	// Load .env file if it exists
-	if err := godotenv.Load(); err != nil {
-		log.Debug().Err(err).Msg("No .env file found, using environment variables")
+	// Load .env file if it exists, then set up logging so LOG_LEVEL from .env applies
+	envErr := godotenv.Load()
+	InitLogger(os.Getenv("LOG_LEVEL"))
+	if envErr != nil {
+		log.Debug().Err(envErr).Msg("No .env file found, using environment variables")
 	}
 
 	cfg := &Config{
 		Port:        getEnv("PORT", "5435"),
 		Host:        getEnv("HOST", "0.0.0.0"),
 		LogLevel:    getEnv("LOG_LEVEL", "info"),
+		APIKey:      os.Getenv("API_KEY"),
 		PostgresURL: os.Getenv("POSTGRES_URL"),
 		MySQLURL:    os.Getenv("MYSQL_URL"),
+		AppName:     getEnv("APP_NAME", "mcp-storage"),
 		RedisURL:    os.Getenv("REDIS_URL"),
 		MongoDBURL:  os.Getenv("MONGODB_URL"),
+
+		PostgresOptions: os.Getenv("POSTGRES_OPTIONS"),
+		MySQLOptions:    os.Getenv("MYSQL_OPTIONS"),
+
+		PostgresExcludedSchemas: getEnvListOr("POSTGRES_EXCLUDED_SCHEMAS", []string{"pg_catalog", "information_schema"}),
+		MySQLExcludedSchemas:    getEnvListOr("MYSQL_EXCLUDED_SCHEMAS", []string{"mysql", "information_schema", "performance_schema", "sys"}),
+
+		DBMaxOpenConns: getEnvInt("DB_MAX_OPEN_CONNS", 0),
+
+		HMACSecret: os.Getenv("HMAC_SECRET"),
+		OAuthMock:  getEnvBool("OAUTH_MOCK", true),
+
+		StrictProtocolVersion: getEnvBool("STRICT_PROTOCOL_VERSION", false),
+
+		PrettyJSON: getEnvBool("PRETTY_JSON", false),
+
+		AllowedClients: getEnvList("ALLOWED_CLIENTS"),
+
+		HealthStatusHealthy:   getEnvInt("HEALTH_STATUS_HEALTHY", 200),
+		HealthStatusUnhealthy: getEnvInt("HEALTH_STATUS_UNHEALTHY", 503),
+		HealthHeaders:         getEnvList("HEALTH_HEADERS"),
+		HealthCheckDatabases:  getEnvBool("HEALTH_CHECK_DATABASES", false),
+
+		UseSession:         getEnvBool("MCP_USE_SESSION", false),
+		SessionMaxLifetime: getEnvDuration("SESSION_MAX_LIFETIME", 0),
+
+		TimestampFormat:    getEnv("TIMESTAMP_FORMAT", "rfc3339nano"),
+		MaxCellBytes:       getEnvInt("MAX_CELL_BYTES", 0),
+		NullRepresentation: getEnv("NULL_REPRESENTATION", "null"),
+
+		AllowReadonlyRoutines: getEnvBool("ALLOW_READONLY_ROUTINES", false),
+		ReadonlyRoutines:      getEnvList("READONLY_ROUTINES"),
+
+		ToolTimeout:      getEnvDuration("TOOL_TIMEOUT", 30*time.Second),
+		PatternMaxTables: getEnvInt("PATTERN_MAX_TABLES", 50),
+		MaxBatchSize:     getEnvInt("MAX_BATCH_SIZE", 100),
+		MaxTools:         getEnvInt("MAX_TOOLS", 200),
+		MaxOutputChars:   getEnvInt("MAX_OUTPUT_CHARS", 0),
+
+		MaxDecompressedBodyBytes: getEnvInt("MAX_DECOMPRESSED_BODY_BYTES", 10<<20),
+
+		TableExportMaxRows: getEnvInt("TABLE_EXPORT_MAX_ROWS", 1000000),
+		TableExportTimeout: getEnvDuration("TABLE_EXPORT_TIMEOUT", 30*time.Minute),
+
+		MetadataResources:       getEnvList("METADATA_RESOURCES"),
+		MetadataResourceMaxRows: getEnvInt("METADATA_RESOURCE_MAX_ROWS", defaultMetadataResourceMaxRows),
+
+		LogRedactLiterals: getEnvList("LOG_REDACT_LITERALS"),
+
+		OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+
+		EnableDiagnosticTools: getEnvBool("ENABLE_DIAGNOSTIC_TOOLS", false),
+		EnableDebugStats:      getEnvBool("ENABLE_DEBUG_STATS", false),
+
+		AllowWrites: getEnvBool("ALLOW_WRITES", false),
 	}
 
 	// Log adapter configuration
@@ -49,6 +193,8 @@ func LoadConfig() (*Config, error) {
 		Bool("mysql", cfg.MySQLURL != "").
 		Bool("redis", cfg.RedisURL != "").
 		Bool("mongodb", cfg.MongoDBURL != "").
+		Bool("readonly_routines", cfg.AllowReadonlyRoutines).
+		Bool("allow_writes", cfg.AllowWrites).
 		Msg("Configuration loaded")
 
 	return cfg, nil
@@ -66,3 +212,65 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvBool gets a boolean environment variable with a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Warn().Str("key", key).Str("value", value).Msg("Invalid boolean value, using default")
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvInt gets an integer environment variable with a default value
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warn().Str("key", key).Str("value", value).Msg("Invalid integer value, using default")
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "30s", "2h") with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warn().Str("key", key).Str("value", value).Msg("Invalid duration value, using default")
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvList gets a comma-separated environment variable as a list of trimmed, non-empty values
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getEnvListOr is getEnvList with a default for an unset variable. A variable set
+// to an empty value yields an empty list rather than the default.
+func getEnvListOr(key string, defaultValue []string) []string {
+	if _, ok := os.LookupEnv(key); !ok {
+		return defaultValue
+	}
+	return getEnvList(key)
+}
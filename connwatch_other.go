@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"net"
+)
+
+// watchDisconnect returns a cancellable copy of ctx. Noticing a closed connection
+// needs a socket peek, which is only implemented on Unix.
+func watchDisconnect(ctx context.Context, conn net.Conn) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
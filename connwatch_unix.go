@@ -0,0 +1,64 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+// disconnectPollInterval is how often watchDisconnect checks whether the client is gone
+const disconnectPollInterval = 100 * time.Millisecond
+
+// watchDisconnect returns a context that is cancelled when the client closes conn.
+// fasthttp's request context only ends when the server shuts down, so this is how a
+// handler notices that its client went away. The socket is peeked, never read, every
+// disconnectPollInterval until the returned cancel function is called. Connections
+// that are not sockets, and sockets with data already waiting (a pipelined request),
+// are not watched.
+func watchDisconnect(ctx context.Context, conn net.Conn) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return ctx, cancel
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return ctx, cancel
+	}
+
+	go func() {
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+
+		buf := make([]byte, 1)
+		// Control keeps the descriptor from being closed and reused while it is peeked
+		_ = raw.Control(func(fd uintptr) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				n, _, err := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK)
+				switch {
+				case errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EWOULDBLOCK) || errors.Is(err, syscall.EINTR):
+					// Nothing to read: the client is still waiting
+				case err != nil || n == 0:
+					// Reset, or end of stream: the client closed the connection
+					cancel()
+					return
+				default:
+					return
+				}
+			}
+		})
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,72 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// tcpPair returns both ends of a loopback TCP connection
+func tcpPair(t *testing.T) (server, client net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err = ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return server, client
+}
+
+func TestWatchDisconnectCancelsOnClientClose(t *testing.T) {
+	server, client := tcpPair(t)
+	ctx, cancel := watchDisconnect(context.Background(), server)
+	defer cancel()
+
+	time.Sleep(2 * disconnectPollInterval)
+	if ctx.Err() != nil {
+		t.Fatal("context cancelled while the client is connected")
+	}
+
+	client.Close()
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("context not cancelled after the client closed the connection")
+	}
+}
+
+func TestWatchDisconnectLeavesPendingDataUnread(t *testing.T) {
+	server, client := tcpPair(t)
+	ctx, cancel := watchDisconnect(context.Background(), server)
+	defer cancel()
+
+	if _, err := client.Write([]byte("next")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * disconnectPollInterval)
+	if ctx.Err() != nil {
+		t.Fatal("context cancelled by a pipelined request")
+	}
+
+	buf := make([]byte, 4)
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := server.Read(buf); err != nil || string(buf[:n]) != "next" {
+		t.Fatalf("Read = %q, %v, want the pipelined bytes", buf[:n], err)
+	}
+}
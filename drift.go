@@ -0,0 +1,743 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaDrift is the difference between a live schema and a target DDL script.
+// Missing objects are in the target but not the database; extra objects are in the
+// database but not the target.
+type SchemaDrift struct {
+	Schema            string       `json:"schema"`
+	InSync            bool         `json:"in_sync"`
+	MissingTables     []string     `json:"missing_tables"`
+	ExtraTables       []string     `json:"extra_tables"`
+	ChangedTables     []TableDrift `json:"changed_tables"`
+	MissingIndexes    []string     `json:"missing_indexes"`
+	ExtraIndexes      []string     `json:"extra_indexes"`
+	ChangedIndexes    []IndexDrift `json:"changed_indexes"`
+	IgnoredStatements int          `json:"ignored_statements"`
+}
+
+// TableDrift lists the column differences of a table present on both sides
+type TableDrift struct {
+	Table          string        `json:"table"`
+	MissingColumns []string      `json:"missing_columns"`
+	ExtraColumns   []string      `json:"extra_columns"`
+	ChangedColumns []ColumnDrift `json:"changed_columns"`
+}
+
+// ColumnDrift is a column whose type or nullability differs, described on each
+// side as its normalized type followed by NOT NULL when set
+type ColumnDrift struct {
+	Column string `json:"column"`
+	Live   string `json:"live"`
+	Target string `json:"target"`
+}
+
+// IndexDrift is an index whose definition differs between the two sides
+type IndexDrift struct {
+	Index  string `json:"index"`
+	Live   string `json:"live"`
+	Target string `json:"target"`
+}
+
+// ddlColumn is a column parsed from CREATE TABLE
+type ddlColumn struct {
+	name    string
+	typ     string
+	notNull bool
+}
+
+func (c ddlColumn) String() string {
+	if c.notNull {
+		return c.typ + " NOT NULL"
+	}
+	return c.typ
+}
+
+// ddlTable is a table parsed from CREATE TABLE, with its columns in order
+type ddlTable struct {
+	columns []*ddlColumn
+}
+
+func (t *ddlTable) column(name string) *ddlColumn {
+	for _, c := range t.columns {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// ddlIndex is an index parsed from CREATE INDEX. Definition is the normalized
+// access method and everything after it (columns, INCLUDE, WHERE).
+type ddlIndex struct {
+	name       string
+	table      string
+	unique     bool
+	definition string
+}
+
+func (i ddlIndex) String() string {
+	if i.unique {
+		return "UNIQUE " + i.definition
+	}
+	return i.definition
+}
+
+// ddlSchema is the part of a schema the drift check compares
+type ddlSchema struct {
+	tables  map[string]*ddlTable
+	indexes []ddlIndex
+	ignored int
+}
+
+// parseSchemaDDL reads the CREATE TABLE, CREATE INDEX, and ALTER TABLE ... ADD
+// CONSTRAINT statements of a PostgreSQL script for schemaName. Objects qualified
+// with another schema and all other statements are skipped and counted as ignored.
+// Unquoted identifiers are folded to lower case when foldCase is set, as PostgreSQL
+// does; the live DDL from GetSchemaDDL prints names verbatim and is parsed without it.
+func parseSchemaDDL(script, schemaName string, foldCase bool) *ddlSchema {
+	schema := &ddlSchema{tables: make(map[string]*ddlTable)}
+	constraintIndexes := make(map[string]bool)
+	primaryKeys := make(map[string][]string)
+
+	for _, stmt := range splitPostgresStatements(script) {
+		p := &ddlParser{tokens: tokenizeDDL(stmt), foldCase: foldCase}
+		if !p.accept("CREATE") && !p.accept("ALTER") {
+			schema.ignored++
+			continue
+		}
+
+		switch {
+		case p.tokens[0].is("CREATE") && p.accept("SCHEMA"):
+			// The schema itself is given by the caller
+
+		case p.tokens[0].is("CREATE") && p.peekTable():
+			name, table, pk, ok := p.createTable(schemaName)
+			if !ok {
+				schema.ignored++
+				continue
+			}
+			schema.tables[name] = table
+			primaryKeys[name] = append(primaryKeys[name], pk...)
+
+		case p.tokens[0].is("CREATE") && p.peekIndex():
+			index, ok := p.createIndex(schemaName)
+			if !ok {
+				schema.ignored++
+				continue
+			}
+			schema.indexes = append(schema.indexes, index)
+
+		case p.tokens[0].is("ALTER") && p.accept("TABLE"):
+			table, constraint, pk, ok := p.addConstraint(schemaName)
+			if !ok {
+				schema.ignored++
+				continue
+			}
+			if constraint != "" {
+				constraintIndexes[constraint] = true
+			}
+			primaryKeys[table] = append(primaryKeys[table], pk...)
+
+		default:
+			schema.ignored++
+		}
+	}
+
+	// Primary key columns are implicitly NOT NULL
+	for tableName, columns := range primaryKeys {
+		if table, ok := schema.tables[tableName]; ok {
+			for _, name := range columns {
+				if c := table.column(name); c != nil {
+					c.notNull = true
+				}
+			}
+		}
+	}
+
+	// Indexes backing UNIQUE, PRIMARY KEY, and EXCLUDE constraints are compared
+	// through the constraints, which an inline column constraint does not name
+	indexes := schema.indexes[:0]
+	for _, index := range schema.indexes {
+		if !constraintIndexes[index.name] {
+			indexes = append(indexes, index)
+		}
+	}
+	schema.indexes = indexes
+
+	return schema
+}
+
+// diffSchemas compares the live schema with the target
+func diffSchemas(schemaName string, live, target *ddlSchema) *SchemaDrift {
+	drift := &SchemaDrift{
+		Schema:            schemaName,
+		MissingTables:     []string{},
+		ExtraTables:       []string{},
+		ChangedTables:     []TableDrift{},
+		MissingIndexes:    []string{},
+		ExtraIndexes:      []string{},
+		ChangedIndexes:    []IndexDrift{},
+		IgnoredStatements: target.ignored,
+	}
+
+	for _, name := range sortedTableNames(target.tables) {
+		liveTable, ok := live.tables[name]
+		if !ok {
+			drift.MissingTables = append(drift.MissingTables, name)
+			continue
+		}
+		if d := diffTables(name, liveTable, target.tables[name]); d != nil {
+			drift.ChangedTables = append(drift.ChangedTables, *d)
+		}
+	}
+	for _, name := range sortedTableNames(live.tables) {
+		if _, ok := target.tables[name]; !ok {
+			drift.ExtraTables = append(drift.ExtraTables, name)
+		}
+	}
+
+	matched := make(map[int]bool)
+	for _, want := range target.indexes {
+		found := -1
+		for i, have := range live.indexes {
+			if matched[i] || have.table != want.table {
+				continue
+			}
+			// Unnamed target indexes match any live index with the same definition
+			if want.name == have.name || want.name == "" && want.String() == have.String() {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			drift.MissingIndexes = append(drift.MissingIndexes, indexLabel(want))
+			continue
+		}
+		matched[found] = true
+		if have := live.indexes[found]; have.String() != want.String() {
+			drift.ChangedIndexes = append(drift.ChangedIndexes, IndexDrift{Index: indexLabel(have), Live: have.String(), Target: want.String()})
+		}
+	}
+	for i, have := range live.indexes {
+		if !matched[i] {
+			drift.ExtraIndexes = append(drift.ExtraIndexes, indexLabel(have))
+		}
+	}
+
+	drift.InSync = len(drift.MissingTables) == 0 && len(drift.ExtraTables) == 0 && len(drift.ChangedTables) == 0 &&
+		len(drift.MissingIndexes) == 0 && len(drift.ExtraIndexes) == 0 && len(drift.ChangedIndexes) == 0
+	return drift
+}
+
+// diffTables compares the columns of one table, returning nil when they match
+func diffTables(name string, live, target *ddlTable) *TableDrift {
+	d := &TableDrift{Table: name, MissingColumns: []string{}, ExtraColumns: []string{}, ChangedColumns: []ColumnDrift{}}
+	for _, want := range target.columns {
+		have := live.column(want.name)
+		if have == nil {
+			d.MissingColumns = append(d.MissingColumns, want.name)
+			continue
+		}
+		if have.String() != want.String() {
+			d.ChangedColumns = append(d.ChangedColumns, ColumnDrift{Column: want.name, Live: have.String(), Target: want.String()})
+		}
+	}
+	for _, have := range live.columns {
+		if target.column(have.name) == nil {
+			d.ExtraColumns = append(d.ExtraColumns, have.name)
+		}
+	}
+
+	if len(d.MissingColumns) == 0 && len(d.ExtraColumns) == 0 && len(d.ChangedColumns) == 0 {
+		return nil
+	}
+	return d
+}
+
+func sortedTableNames(tables map[string]*ddlTable) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func indexLabel(index ddlIndex) string {
+	if index.name == "" {
+		return fmt.Sprintf("(unnamed) on %s: %s", index.table, index)
+	}
+	return fmt.Sprintf("%s on %s", index.name, index.table)
+}
+
+// ddlToken is a word, quoted identifier, literal, or punctuation of a DDL statement
+type ddlToken struct {
+	text   string
+	quoted bool
+}
+
+// is reports whether the token is the unquoted keyword (case-insensitive)
+func (t ddlToken) is(keyword string) bool {
+	return !t.quoted && strings.EqualFold(t.text, keyword)
+}
+
+// tokenizeDDL splits a statement into tokens, dropping comments. Double-quoted
+// identifiers are unescaped and marked quoted; string and dollar-quoted literals
+// are kept whole.
+func tokenizeDDL(stmt string) []ddlToken {
+	var tokens []ddlToken
+	n := len(stmt)
+	for i := 0; i < n; {
+		c := stmt[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '-' && i+1 < n && stmt[i+1] == '-':
+			end := strings.IndexByte(stmt[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+
+		case c == '/' && i+1 < n && stmt[i+1] == '*':
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+
+		case c == '"':
+			end := skipQuoted(stmt, i, '"', false)
+			text := stmt[i+1 : end]
+			text = strings.TrimSuffix(text, `"`)
+			tokens = append(tokens, ddlToken{text: strings.ReplaceAll(text, `""`, `"`), quoted: true})
+			i = end
+
+		case c == '\'':
+			end := skipQuoted(stmt, i, '\'', false)
+			tokens = append(tokens, ddlToken{text: stmt[i:end]})
+			i = end
+
+		case c == '$':
+			if end, ok := skipDollarQuoted(stmt, i); ok {
+				tokens = append(tokens, ddlToken{text: stmt[i:end]})
+				i = end
+				continue
+			}
+			tokens = append(tokens, ddlToken{text: "$"})
+			i++
+
+		case isIdentChar(c):
+			end := i + 1
+			for end < n && isIdentChar(stmt[end]) {
+				end++
+			}
+			tokens = append(tokens, ddlToken{text: stmt[i:end]})
+			i = end
+
+		case c == ':' && i+1 < n && stmt[i+1] == ':':
+			tokens = append(tokens, ddlToken{text: "::"})
+			i += 2
+
+		default:
+			tokens = append(tokens, ddlToken{text: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// ddlParser walks the tokens of one statement
+type ddlParser struct {
+	tokens   []ddlToken
+	pos      int
+	foldCase bool
+}
+
+func (p *ddlParser) peek() ddlToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ddlToken{}
+}
+
+// accept consumes the keywords if the next tokens are exactly them
+func (p *ddlParser) accept(keywords ...string) bool {
+	if p.pos+len(keywords) > len(p.tokens) {
+		return false
+	}
+	for i, keyword := range keywords {
+		if !p.tokens[p.pos+i].is(keyword) {
+			return false
+		}
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+// ident consumes one token as an identifier, or returns "" at the end of the tokens
+func (p *ddlParser) ident() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return p.name(t)
+}
+
+func (p *ddlParser) name(t ddlToken) string {
+	if p.foldCase && !t.quoted {
+		return strings.ToLower(t.text)
+	}
+	return t.text
+}
+
+// qualifiedName consumes [schema.]name, reporting ok=false for another schema
+func (p *ddlParser) qualifiedName(schemaName string) (string, bool) {
+	name := p.ident()
+	if p.peek().text == "." && !p.peek().quoted {
+		p.pos++
+		schema := name
+		name = p.ident()
+		if schema != schemaName && !(p.foldCase && strings.EqualFold(schema, schemaName)) {
+			return "", false
+		}
+	}
+	return name, name != ""
+}
+
+// group consumes a parenthesized group and returns the tokens inside it
+func (p *ddlParser) group() ([]ddlToken, bool) {
+	if p.peek().text != "(" || p.peek().quoted {
+		return nil, false
+	}
+	depth := 0
+	for i := p.pos; i < len(p.tokens); i++ {
+		if p.tokens[i].quoted {
+			continue
+		}
+		switch p.tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				inner := p.tokens[p.pos+1 : i]
+				p.pos = i + 1
+				return inner, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// peekTable consumes the modifiers and TABLE keyword of CREATE ... TABLE.
+// Temporary tables are not part of the schema and are not recognized.
+func (p *ddlParser) peekTable() bool {
+	p.accept("UNLOGGED")
+	return p.accept("TABLE")
+}
+
+// peekIndex consumes [UNIQUE] INDEX, leaving UNIQUE recorded in the tokens
+func (p *ddlParser) peekIndex() bool {
+	return p.accept("INDEX") || p.accept("UNIQUE", "INDEX")
+}
+
+// createTable parses the rest of CREATE TABLE, returning the table, and the columns
+// of a table-level PRIMARY KEY
+func (p *ddlParser) createTable(schemaName string) (string, *ddlTable, []string, bool) {
+	p.accept("IF", "NOT", "EXISTS")
+	name, ok := p.qualifiedName(schemaName)
+	if !ok {
+		return "", nil, nil, false
+	}
+	inner, ok := p.group()
+	if !ok {
+		// CREATE TABLE ... AS, PARTITION OF, or OF type
+		return "", nil, nil, false
+	}
+
+	table := &ddlTable{}
+	var primaryKey []string
+	for _, item := range splitDDLList(inner) {
+		if len(item) == 0 {
+			continue
+		}
+		first := item[0]
+		if first.is("CONSTRAINT") || first.is("PRIMARY") || first.is("UNIQUE") || first.is("FOREIGN") ||
+			first.is("CHECK") || first.is("EXCLUDE") || first.is("LIKE") {
+			primaryKey = append(primaryKey, p.primaryKeyColumns(item)...)
+			continue
+		}
+		table.columns = append(table.columns, p.column(item))
+	}
+	return name, table, primaryKey, true
+}
+
+// ddlColumnStop are the words that end a column's type in CREATE TABLE
+var ddlColumnStop = []string{"CONSTRAINT", "NOT", "NULL", "DEFAULT", "PRIMARY", "UNIQUE", "REFERENCES", "CHECK", "COLLATE", "GENERATED"}
+
+// ddlSerialTypes are the serial pseudo-types, which are NOT NULL integer columns
+var ddlSerialTypes = map[string]bool{
+	"serial": true, "serial4": true, "bigserial": true, "serial8": true, "smallserial": true, "serial2": true,
+}
+
+// column parses a column definition
+func (p *ddlParser) column(item []ddlToken) *ddlColumn {
+	col := &ddlColumn{name: p.name(item[0])}
+
+	end := len(item)
+	depth := 0
+	for i := 1; i < len(item); i++ {
+		switch {
+		case item[i].text == "(" && !item[i].quoted:
+			depth++
+		case item[i].text == ")" && !item[i].quoted:
+			depth--
+		case depth == 0 && end == len(item):
+			for _, stop := range ddlColumnStop {
+				if item[i].is(stop) {
+					end = i
+					break
+				}
+			}
+		}
+	}
+
+	typ := joinDDLTokens(item[1:end], true)
+	col.notNull = ddlSerialTypes[typ]
+	col.typ = normalizePostgresType(typ)
+
+	for i := end; i < len(item); i++ {
+		switch {
+		case item[i].is("NOT") && i+1 < len(item) && item[i+1].is("NULL"),
+			item[i].is("PRIMARY") && i+1 < len(item) && item[i+1].is("KEY"),
+			item[i].is("IDENTITY"):
+			col.notNull = true
+		}
+	}
+	return col
+}
+
+// primaryKeyColumns returns the columns of a PRIMARY KEY (...) table constraint
+func (p *ddlParser) primaryKeyColumns(item []ddlToken) []string {
+	for i := 0; i+2 < len(item); i++ {
+		if item[i].is("PRIMARY") && item[i+1].is("KEY") {
+			sub := &ddlParser{tokens: item[i+2:], foldCase: p.foldCase}
+			inner, ok := sub.group()
+			if !ok {
+				return nil
+			}
+			var columns []string
+			for _, col := range splitDDLList(inner) {
+				if len(col) > 0 {
+					columns = append(columns, p.name(col[0]))
+				}
+			}
+			return columns
+		}
+	}
+	return nil
+}
+
+// createIndex parses the rest of CREATE [UNIQUE] INDEX
+func (p *ddlParser) createIndex(schemaName string) (ddlIndex, bool) {
+	index := ddlIndex{unique: p.tokens[1].is("UNIQUE")}
+	p.accept("CONCURRENTLY")
+	p.accept("IF", "NOT", "EXISTS")
+	if !p.peek().is("ON") {
+		index.name = p.ident()
+	}
+	if !p.accept("ON") {
+		return ddlIndex{}, false
+	}
+	p.accept("ONLY")
+	table, ok := p.qualifiedName(schemaName)
+	if !ok {
+		return ddlIndex{}, false
+	}
+	index.table = table
+
+	method := "btree"
+	if p.accept("USING") {
+		if method = strings.ToLower(p.ident()); method == "" {
+			return ddlIndex{}, false
+		}
+	}
+	index.definition = method + " " + joinDDLTokens(p.tokens[p.pos:], true)
+	return index, true
+}
+
+// addConstraint parses ALTER TABLE ... ADD [CONSTRAINT name] ..., returning the
+// table, the constraint name when it is backed by an index, and the columns of a
+// PRIMARY KEY
+func (p *ddlParser) addConstraint(schemaName string) (string, string, []string, bool) {
+	p.accept("IF", "EXISTS")
+	p.accept("ONLY")
+	table, ok := p.qualifiedName(schemaName)
+	if !ok || !p.accept("ADD") {
+		return "", "", nil, false
+	}
+
+	var name string
+	if p.accept("CONSTRAINT") {
+		name = p.ident()
+	}
+	rest := p.tokens[p.pos:]
+	if len(rest) == 0 {
+		return "", "", nil, false
+	}
+	if !rest[0].is("UNIQUE") && !rest[0].is("PRIMARY") && !rest[0].is("EXCLUDE") {
+		// CHECK and FOREIGN KEY constraints have no index
+		return table, "", nil, true
+	}
+	return table, name, p.primaryKeyColumns(rest), true
+}
+
+// splitDDLList splits tokens on top-level commas
+func splitDDLList(tokens []ddlToken) [][]ddlToken {
+	var items [][]ddlToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		if t.quoted {
+			continue
+		}
+		switch t.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				items = append(items, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(items, tokens[start:])
+}
+
+// ddlSpacing removes the spaces joinDDLTokens puts around punctuation
+var ddlSpacing = strings.NewReplacer(" (", "(", "( ", "(", " )", ")", " ,", ",", ", ", ",",
+	" [", "[", "[ ", "[", " ]", "]", " .", ".", ". ", ".", " ::", "::", ":: ", "::")
+
+// joinDDLTokens joins tokens into normalized text. Unquoted words are lower-cased
+// when lower is set; quoted identifiers are re-quoted unless they are plain
+// lower-case names, so "email" and email compare equal.
+func joinDDLTokens(tokens []ddlToken, lower bool) string {
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		switch {
+		case t.quoted && quotePostgresIdentIfNeeded(t.text) == t.text:
+			parts[i] = t.text
+		case t.quoted:
+			parts[i] = quotePostgresIdent(t.text)
+		case lower && !strings.HasPrefix(t.text, "'") && !strings.HasPrefix(t.text, "$"):
+			parts[i] = strings.ToLower(t.text)
+		default:
+			parts[i] = t.text
+		}
+	}
+	return ddlSpacing.Replace(ddlSpacing.Replace(strings.Join(parts, " ")))
+}
+
+// quotePostgresIdentIfNeeded quotes an identifier unless it is a plain lower-case name
+func quotePostgresIdentIfNeeded(name string) string {
+	if name == "" || !isIdentStart(name[0]) || name[0] >= 0x80 {
+		return quotePostgresIdent(name)
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || isDigit(c)) {
+			return quotePostgresIdent(name)
+		}
+	}
+	return name
+}
+
+// postgresTypeAliases maps type names to the spelling format_type() uses
+var postgresTypeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"int8":        "bigint",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"int2":        "smallint",
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"float8":      "double precision",
+	"float":       "double precision",
+	"float4":      "real",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"decimal":     "numeric",
+	"varbit":      "bit varying",
+	"timestamptz": "timestamp with time zone",
+	"timetz":      "time with time zone",
+}
+
+// normalizePostgresType rewrites a type as format_type() would print it, e.g.
+// varchar(20) as character varying(20) and timestamptz as timestamp with time zone
+func normalizePostgresType(typ string) string {
+	typ = strings.ToLower(ddlSpacing.Replace(strings.Join(strings.Fields(typ), " ")))
+
+	var array string
+	for strings.HasSuffix(typ, "]") {
+		if i := strings.LastIndex(typ, "["); i >= 0 {
+			typ = strings.TrimSpace(typ[:i])
+			array += "[]"
+		} else {
+			break
+		}
+	}
+	if strings.HasSuffix(typ, " array") {
+		typ = strings.TrimSuffix(typ, " array")
+		array += "[]"
+	}
+
+	name, modifier, rest := typ, "", ""
+	if i := strings.Index(typ, "("); i >= 0 {
+		if j := strings.Index(typ[i:], ")"); j >= 0 {
+			name, modifier, rest = strings.TrimSpace(typ[:i]), typ[i:i+j+1], strings.TrimSpace(typ[i+j+1:])
+		}
+	}
+	if alias, ok := postgresTypeAliases[name]; ok {
+		name = alias
+	}
+
+	// Time zone words follow the precision: timestamp(3) with time zone
+	for _, base := range []string{"timestamp", "time"} {
+		if name != base && !strings.HasPrefix(name, base+" with") {
+			continue
+		}
+		if suffix := strings.TrimSpace(strings.TrimPrefix(name, base)); suffix != "" {
+			rest = suffix
+		}
+		if rest == "" {
+			rest = "without time zone"
+		}
+		return base + modifier + " " + rest + array
+	}
+
+	if name == "character" && modifier == "" {
+		modifier = "(1)"
+	}
+	if name == "bit" && modifier == "" {
+		modifier = "(1)"
+	}
+	if rest != "" {
+		rest = " " + rest
+	}
+	return name + modifier + rest + array
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSchemaDDLTruncatedStatements(t *testing.T) {
+	for _, script := range []string{
+		"ALTER TABLE foo ADD CONSTRAINT",
+		"ALTER TABLE foo ADD",
+		"ALTER TABLE",
+		"CREATE INDEX ON t USING",
+		"CREATE INDEX",
+		"CREATE UNIQUE INDEX idx ON",
+		"CREATE TABLE",
+		"CREATE TABLE public.",
+		"CREATE TABLE t (",
+	} {
+		t.Run(script, func(t *testing.T) {
+			schema := parseSchemaDDL(script, "public", true)
+			if len(schema.tables) != 0 || len(schema.indexes) != 0 || schema.ignored != 1 {
+				t.Errorf("parsed %v and %v with %d ignored, want only the statement ignored", schema.tables, schema.indexes, schema.ignored)
+			}
+		})
+	}
+}
+
+func TestParseSchemaDDLTables(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		table   string
+		columns []string
+		ignored int
+	}{
+		{
+			name:    "aliases and not null",
+			script:  "CREATE TABLE users (id serial PRIMARY KEY, email varchar(20) NOT NULL, created timestamptz)",
+			table:   "users",
+			columns: []string{"id integer NOT NULL", "email character varying(20) NOT NULL", "created timestamp with time zone"},
+		},
+		{
+			name:    "table-level primary key",
+			script:  "CREATE TABLE IF NOT EXISTS public.t (a int, b text, PRIMARY KEY (a, b))",
+			table:   "t",
+			columns: []string{"a integer NOT NULL", "b text NOT NULL"},
+		},
+		{
+			name:    "primary key added later",
+			script:  "CREATE TABLE t (a int); ALTER TABLE t ADD CONSTRAINT t_pkey PRIMARY KEY (a)",
+			table:   "t",
+			columns: []string{"a integer NOT NULL"},
+		},
+		{
+			name:    "folded and quoted names",
+			script:  `CREATE TABLE Orders ("Total" numeric(10,2), Note char)`,
+			table:   "orders",
+			columns: []string{"Total numeric(10,2)", "note character(1)"},
+		},
+		{
+			name:    "array types",
+			script:  "CREATE TABLE t (tags text[], ids int ARRAY)",
+			table:   "t",
+			columns: []string{"tags text[]", "ids integer[]"},
+		},
+		{
+			name:    "other schema and other statements are ignored",
+			script:  "CREATE TABLE other.t (a int); CREATE VIEW v AS SELECT 1; GRANT SELECT ON t TO x; CREATE TABLE t (a int)",
+			table:   "t",
+			columns: []string{"a integer"},
+			ignored: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := parseSchemaDDL(tt.script, "public", true)
+			table, ok := schema.tables[tt.table]
+			if !ok {
+				t.Fatalf("table %s not parsed, got %v", tt.table, schema.tables)
+			}
+			var columns []string
+			for _, c := range table.columns {
+				columns = append(columns, c.name+" "+c.String())
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %q, want %q", columns, tt.columns)
+			}
+			if schema.ignored != tt.ignored {
+				t.Errorf("ignored = %d, want %d", schema.ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestParseSchemaDDLIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		indexes []ddlIndex
+	}{
+		{
+			name:    "named btree",
+			script:  "CREATE INDEX users_email_idx ON users (Email)",
+			indexes: []ddlIndex{{name: "users_email_idx", table: "users", definition: "btree (email)"}},
+		},
+		{
+			name:    "unique with method and predicate",
+			script:  "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS u ON ONLY public.t USING HASH (a) WHERE a > 0",
+			indexes: []ddlIndex{{name: "u", table: "t", unique: true, definition: "hash (a) where a > 0"}},
+		},
+		{
+			name:    "unnamed",
+			script:  "CREATE INDEX ON t (lower(b))",
+			indexes: []ddlIndex{{table: "t", definition: "btree (lower(b))"}},
+		},
+		{
+			name:    "constraint indexes are dropped",
+			script:  "CREATE UNIQUE INDEX t_a_key ON t (a); ALTER TABLE t ADD CONSTRAINT t_a_key UNIQUE USING INDEX t_a_key",
+			indexes: nil,
+		},
+		{
+			name:    "check constraint keeps the index",
+			script:  "CREATE INDEX t_a ON t (a); ALTER TABLE t ADD CONSTRAINT t_a CHECK (a > 0)",
+			indexes: []ddlIndex{{name: "t_a", table: "t", definition: "btree (a)"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := parseSchemaDDL(tt.script, "public", true)
+			if len(schema.indexes) == 0 && len(tt.indexes) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(schema.indexes, tt.indexes) {
+				t.Errorf("indexes = %+v, want %+v", schema.indexes, tt.indexes)
+			}
+		})
+	}
+}
+
+func TestTokenizeDDL(t *testing.T) {
+	tokens := tokenizeDDL(`SELECT "A""b", 'x;y' -- comment
+		/* block */ $$body$$ a::int`)
+	want := []ddlToken{
+		{text: "SELECT"}, {text: `A"b`, quoted: true}, {text: ","}, {text: "'x;y'"},
+		{text: "$$body$$"}, {text: "a"}, {text: "::"}, {text: "int"},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("tokens = %+v, want %+v", tokens, want)
+	}
+}
+
+func TestNormalizePostgresType(t *testing.T) {
+	tests := map[string]string{
+		"int":                 "integer",
+		"VARCHAR(20)":         "character varying(20)",
+		"timestamp(3)":        "timestamp(3) without time zone",
+		"timestamptz":         "timestamp with time zone",
+		"time with time zone": "time with time zone",
+		"char":                "character(1)",
+		"decimal(10, 2)":      "numeric(10,2)",
+		"float8[]":            "double precision[]",
+		"bit":                 "bit(1)",
+		"text":                "text",
+	}
+	for in, want := range tests {
+		if got := normalizePostgresType(in); got != want {
+			t.Errorf("normalizePostgresType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDiffSchemas(t *testing.T) {
+	live := parseSchemaDDL(`
+		CREATE TABLE users (id integer NOT NULL, email text, legacy text);
+		CREATE TABLE old (a integer);
+		CREATE INDEX users_email ON users USING btree (email);
+		CREATE INDEX users_legacy ON users USING btree (legacy);
+	`, "public", false)
+	target := parseSchemaDDL(`
+		CREATE TABLE users (id serial PRIMARY KEY, email varchar(100), created timestamptz);
+		CREATE TABLE new (a int);
+		CREATE INDEX users_email ON users (lower(email));
+		CREATE INDEX ON users (created);
+		CREATE VIEW v AS SELECT 1;
+	`, "public", true)
+
+	drift := diffSchemas("public", live, target)
+	if drift.InSync {
+		t.Fatal("InSync = true, want false")
+	}
+	if !reflect.DeepEqual(drift.MissingTables, []string{"new"}) || !reflect.DeepEqual(drift.ExtraTables, []string{"old"}) {
+		t.Errorf("missing = %v, extra = %v", drift.MissingTables, drift.ExtraTables)
+	}
+	wantTable := []TableDrift{{
+		Table:          "users",
+		MissingColumns: []string{"created"},
+		ExtraColumns:   []string{"legacy"},
+		ChangedColumns: []ColumnDrift{{Column: "email", Live: "text", Target: "character varying(100)"}},
+	}}
+	if !reflect.DeepEqual(drift.ChangedTables, wantTable) {
+		t.Errorf("changed tables = %+v, want %+v", drift.ChangedTables, wantTable)
+	}
+	if len(drift.MissingIndexes) != 1 || len(drift.ExtraIndexes) != 1 || len(drift.ChangedIndexes) != 1 {
+		t.Errorf("indexes: missing %v, extra %v, changed %v", drift.MissingIndexes, drift.ExtraIndexes, drift.ChangedIndexes)
+	}
+	if drift.IgnoredStatements != 1 {
+		t.Errorf("ignored = %d, want 1", drift.IgnoredStatements)
+	}
+
+	if d := diffSchemas("public", live, live); !d.InSync {
+		t.Errorf("schema compared with itself is not in sync: %+v", d)
+	}
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PlanNode is a node of a PostgreSQL EXPLAIN (FORMAT JSON) plan
+type PlanNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name,omitempty"`
+	Schema       string     `json:"Schema,omitempty"`
+	Alias        string     `json:"Alias,omitempty"`
+	IndexName    string     `json:"Index Name,omitempty"`
+	JoinType     string     `json:"Join Type,omitempty"`
+	StartupCost  float64    `json:"Startup Cost"`
+	TotalCost    float64    `json:"Total Cost"`
+	PlanRows     float64    `json:"Plan Rows"`
+	PlanWidth    int        `json:"Plan Width"`
+	Filter       string     `json:"Filter,omitempty"`
+	IndexCond    string     `json:"Index Cond,omitempty"`
+	HashCond     string     `json:"Hash Cond,omitempty"`
+	MergeCond    string     `json:"Merge Cond,omitempty"`
+	JoinFilter   string     `json:"Join Filter,omitempty"`
+	Plans        []PlanNode `json:"Plans,omitempty"`
+}
+
+// explainOutput is the top-level EXPLAIN (FORMAT JSON) document
+type explainOutput []explainEntry
+
+// explainEntry is one statement's plan. QueryIdentifier is only present with
+// VERBOSE on PostgreSQL 14+ when query identifiers are computed.
+type explainEntry struct {
+	Plan            PlanNode `json:"Plan"`
+	QueryIdentifier *int64   `json:"Query Identifier,omitempty"`
+}
+
+// parseExplainJSON extracts the first statement's plan from EXPLAIN (FORMAT JSON) output
+func parseExplainJSON(data []byte) (*explainEntry, error) {
+	var out explainOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse EXPLAIN output: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("EXPLAIN returned no plan")
+	}
+	return &out[0], nil
+}
+
+// Explain returns the planner's plan for a read-only query without executing it
+func (p *PostgresAdapter) Explain(ctx context.Context, query string) (*PlanNode, error) {
+	entry, err := p.explain(ctx, query, false)
+	if err != nil {
+		return nil, err
+	}
+	return &entry.Plan, nil
+}
+
+// explain runs EXPLAIN (FORMAT JSON), optionally VERBOSE, on a read-only query.
+// EXPLAIN runs in a read-only transaction so nothing can be written even if the
+// query text contains more than one statement.
+func (p *PostgresAdapter) explain(ctx context.Context, query string, verbose bool) (*explainEntry, error) {
+	query, err := validateReadOnlyQuery(query, p.policy)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	options := "FORMAT JSON"
+	if verbose {
+		options += ", VERBOSE"
+	}
+
+	var raw []byte
+	if err := tx.QueryRowContext(ctx, "EXPLAIN ("+options+") "+query).Scan(&raw); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	return parseExplainJSON(raw)
+}
+
+// Planner cost thresholds for the rough cost bands reported when no timing history exists
+const (
+	lowCostThreshold    = 1e3
+	mediumCostThreshold = 1e5
+)
+
+// TimeEstimate is a rough execution time estimate for a query. Method is
+// "pg_stat_statements" when timings of earlier runs of the same normalized query
+// were found, otherwise "planner_cost" and only the cost band is given.
+type TimeEstimate struct {
+	Method           string   `json:"method"`
+	EstimatedMs      *float64 `json:"estimated_ms,omitempty"`
+	StddevMs         *float64 `json:"stddev_ms,omitempty"`
+	MaxMs            *float64 `json:"max_ms,omitempty"`
+	PreviousCalls    *int64   `json:"previous_calls,omitempty"`
+	TotalCost        float64  `json:"total_cost"`
+	CostBand         string   `json:"cost_band"`
+	EstimatedRows    int64    `json:"estimated_rows"`
+	StatsUnavailable string   `json:"stats_unavailable,omitempty"`
+	Note             string   `json:"note"`
+}
+
+// EstimateExecutionTime explains a query without running it and looks up the mean
+// execution time of earlier runs of the same normalized query in pg_stat_statements,
+// matched by query identifier. Without history, only the planner cost is reported.
+func (p *PostgresAdapter) EstimateExecutionTime(ctx context.Context, query string) (*TimeEstimate, error) {
+	entry, err := p.explain(ctx, query, true)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &TimeEstimate{
+		Method:        "planner_cost",
+		TotalCost:     entry.Plan.TotalCost,
+		CostBand:      costBand(entry.Plan.TotalCost),
+		EstimatedRows: int64(entry.Plan.PlanRows),
+		Note:          "Planner cost is in arbitrary units, not time; the band only compares queries on this server. Estimates rely on table statistics and may be far off if they are stale",
+	}
+
+	if entry.QueryIdentifier == nil {
+		estimate.StatsUnavailable = "no query identifier (needs PostgreSQL 14+ with compute_query_id enabled)"
+		return estimate, nil
+	}
+
+	installed, err := p.HasExtension(ctx, "pg_stat_statements")
+	if err != nil {
+		return nil, err
+	}
+	if !installed {
+		estimate.StatsUnavailable = "pg_stat_statements is not installed"
+		return estimate, nil
+	}
+
+	statsQuery := `
+		SELECT SUM(calls)::bigint,
+			SUM(mean_exec_time * calls) / NULLIF(SUM(calls), 0),
+			MAX(stddev_exec_time),
+			MAX(max_exec_time)
+		FROM pg_stat_statements
+		WHERE queryid = $1
+	`
+
+	var calls sql.NullInt64
+	var mean, stddev, maxTime sql.NullFloat64
+	if err := p.db.QueryRowContext(ctx, statsQuery, *entry.QueryIdentifier).Scan(&calls, &mean, &stddev, &maxTime); err != nil {
+		estimate.StatsUnavailable = fmt.Sprintf("failed to read pg_stat_statements: %v", err)
+		return estimate, nil
+	}
+	if !calls.Valid || calls.Int64 == 0 || !mean.Valid {
+		estimate.StatsUnavailable = "no earlier runs of this query in pg_stat_statements"
+		return estimate, nil
+	}
+
+	estimate.Method = "pg_stat_statements"
+	estimate.EstimatedMs = &mean.Float64
+	estimate.PreviousCalls = &calls.Int64
+	if stddev.Valid {
+		estimate.StddevMs = &stddev.Float64
+	}
+	if maxTime.Valid {
+		estimate.MaxMs = &maxTime.Float64
+	}
+	estimate.Note = "Mean execution time of earlier runs of the same normalized query (any parameter values). Actual time varies with parameters, caching, and load"
+
+	return estimate, nil
+}
+
+// costBand classifies a planner total cost as low, medium, or high
+func costBand(cost float64) string {
+	switch {
+	case cost < lowCostThreshold:
+		return "low"
+	case cost < mediumCostThreshold:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// planShape renders the structure of a plan: node types, join types, relations, and
+// indexes, nested by child. Costs, row estimates, aliases, and conditions (which
+// carry literal values) are left out, so re-planning with new statistics or
+// parameter values gives the same shape unless the plan itself changes.
+func planShape(node *PlanNode) string {
+	var b strings.Builder
+	writePlanShape(&b, node)
+	return b.String()
+}
+
+// writePlanShape appends the shape of node and its children to b
+func writePlanShape(b *strings.Builder, node *PlanNode) {
+	b.WriteString(node.NodeType)
+	if node.JoinType != "" {
+		fmt.Fprintf(b, " (%s)", node.JoinType)
+	}
+	if node.IndexName != "" {
+		fmt.Fprintf(b, " using %s", node.IndexName)
+	}
+	if node.RelationName != "" {
+		b.WriteString(" on ")
+		if node.Schema != "" {
+			b.WriteString(node.Schema + ".")
+		}
+		b.WriteString(node.RelationName)
+	}
+
+	if len(node.Plans) == 0 {
+		return
+	}
+	b.WriteString(" [")
+	for i := range node.Plans {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writePlanShape(b, &node.Plans[i])
+	}
+	b.WriteString("]")
+}
+
+// planFingerprint returns the hex SHA-256 of the plan's shape
+func planFingerprint(node *PlanNode) string {
+	sum := sha256.Sum256([]byte(planShape(node)))
+	return hex.EncodeToString(sum[:])
+}
+
+// maxSuggestedIndexColumns caps the columns of one suggested index
+const maxSuggestedIndexColumns = 3
+
+// IndexSuggestion is a CREATE INDEX statement proposed for a sequentially scanned
+// relation. It is never executed.
+type IndexSuggestion struct {
+	Schema        string   `json:"schema"`
+	Table         string   `json:"table"`
+	Columns       []string `json:"columns"`
+	Reason        string   `json:"reason"`
+	EstimatedRows float64  `json:"estimated_rows"`
+	DDL           string   `json:"ddl"`
+}
+
+// planColumnRef is an alias-qualified column reference in a plan condition.
+// Equality is set when the column is compared with =.
+type planColumnRef struct {
+	Alias    string
+	Column   string
+	Equality bool
+}
+
+// SuggestIndexes explains a read-only query with VERBOSE, so relations carry their
+// schema and conditions qualify columns by alias, and proposes indexes for its
+// sequential scans
+func (p *PostgresAdapter) SuggestIndexes(ctx context.Context, query string) (*PlanNode, []IndexSuggestion, error) {
+	entry, err := p.explain(ctx, query, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Drop suggestions an existing index already covers, such as a primary key on
+	// the small side of a hash join
+	existing := make(map[string][][]string)
+	suggestions := []IndexSuggestion{}
+	for _, s := range suggestIndexes(&entry.Plan) {
+		key := s.Schema + "." + s.Table
+		indexes, ok := existing[key]
+		if !ok {
+			if indexes, err = p.indexColumnLists(ctx, s.Schema, s.Table); err != nil {
+				return nil, nil, err
+			}
+			existing[key] = indexes
+		}
+		if !indexCovers(indexes, s.Columns) {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	return &entry.Plan, suggestions, nil
+}
+
+// indexColumnLists returns the key columns of each plain (non-expression,
+// non-partial) index on a table
+func (p *PostgresAdapter) indexColumnLists(ctx context.Context, schemaName, tableName string) ([][]string, error) {
+	query := `
+		SELECT ARRAY(SELECT a.attname::text FROM unnest(i.indkey::int2[]) WITH ORDINALITY k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum ORDER BY k.ord)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2 AND i.indexprs IS NULL AND i.indpred IS NULL
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes [][]string
+	for rows.Next() {
+		var columns []string
+		if err := rows.Scan((*pq.StringArray)(&columns)); err != nil {
+			return nil, fmt.Errorf("failed to scan index columns: %w", err)
+		}
+		indexes = append(indexes, columns)
+	}
+	return indexes, rows.Err()
+}
+
+// indexCovers reports whether any index starts with exactly the given columns
+func indexCovers(indexes [][]string, columns []string) bool {
+	for _, index := range indexes {
+		if len(index) < len(columns) {
+			continue
+		}
+		covered := true
+		for i, col := range columns {
+			if index[i] != col {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestIndexes proposes an index on the filter columns of each sequential scan
+// with a Filter, and one on the join columns of each sequentially scanned relation
+// used in a hash, merge, or nested loop join condition. Equality columns come first.
+func suggestIndexes(plan *PlanNode) []IndexSuggestion {
+	scans := make(map[string]*PlanNode)
+	collectSeqScans(plan, scans)
+
+	var suggestions []IndexSuggestion
+	seen := make(map[string]bool)
+	add := func(scan *PlanNode, refs []planColumnRef, reason string) {
+		columns := indexColumns(refs)
+		if len(columns) == 0 {
+			return
+		}
+
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = quotePostgresIdent(col)
+		}
+		ddl := fmt.Sprintf("CREATE INDEX CONCURRENTLY ON %s.%s (%s);",
+			quotePostgresIdent(scan.Schema), quotePostgresIdent(scan.RelationName), strings.Join(quoted, ", "))
+		if seen[ddl] {
+			return
+		}
+		seen[ddl] = true
+
+		suggestions = append(suggestions, IndexSuggestion{
+			Schema:        scan.Schema,
+			Table:         scan.RelationName,
+			Columns:       columns,
+			Reason:        reason,
+			EstimatedRows: scan.PlanRows,
+			DDL:           ddl,
+		})
+	}
+
+	var walk func(node *PlanNode)
+	walk = func(node *PlanNode) {
+		if node.NodeType == "Seq Scan" && node.RelationName != "" && node.Filter != "" {
+			add(node, refsForAlias(planColumnRefs(node.Filter), node.Alias), "sequential scan filter: "+node.Filter)
+		}
+
+		for _, cond := range []string{node.HashCond, node.MergeCond, node.JoinFilter} {
+			if cond == "" {
+				continue
+			}
+			byAlias := make(map[string][]planColumnRef)
+			var aliases []string
+			for _, ref := range planColumnRefs(cond) {
+				if scans[ref.Alias] == nil {
+					continue
+				}
+				if _, ok := byAlias[ref.Alias]; !ok {
+					aliases = append(aliases, ref.Alias)
+				}
+				byAlias[ref.Alias] = append(byAlias[ref.Alias], ref)
+			}
+			for _, alias := range aliases {
+				add(scans[alias], byAlias[alias], fmt.Sprintf("%s join condition on a sequentially scanned relation: %s", node.NodeType, cond))
+			}
+		}
+
+		for i := range node.Plans {
+			walk(&node.Plans[i])
+		}
+	}
+	walk(plan)
+
+	return suggestions
+}
+
+// collectSeqScans maps the alias of every sequentially scanned relation to its node.
+// The first scan wins when an alias repeats.
+func collectSeqScans(node *PlanNode, scans map[string]*PlanNode) {
+	if node.NodeType == "Seq Scan" && node.RelationName != "" && node.Schema != "" {
+		if _, ok := scans[node.Alias]; !ok {
+			scans[node.Alias] = node
+		}
+	}
+	for i := range node.Plans {
+		collectSeqScans(&node.Plans[i], scans)
+	}
+}
+
+// refsForAlias keeps the references to one alias
+func refsForAlias(refs []planColumnRef, alias string) []planColumnRef {
+	var kept []planColumnRef
+	for _, ref := range refs {
+		if ref.Alias == alias {
+			kept = append(kept, ref)
+		}
+	}
+	return kept
+}
+
+// indexColumns orders referenced columns for an index: equality columns first, then
+// the rest, each in order of appearance, without duplicates, and capped at
+// maxSuggestedIndexColumns
+func indexColumns(refs []planColumnRef) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, equality := range []bool{true, false} {
+		for _, ref := range refs {
+			if ref.Equality != equality || seen[ref.Column] {
+				continue
+			}
+			seen[ref.Column] = true
+			columns = append(columns, ref.Column)
+		}
+	}
+	if len(columns) > maxSuggestedIndexColumns {
+		columns = columns[:maxSuggestedIndexColumns]
+	}
+	return columns
+}
+
+// planColumnRefs extracts alias.column references from an EXPLAIN VERBOSE condition.
+// String literals, type casts (::type), and qualified function names are skipped.
+func planColumnRefs(expr string) []planColumnRef {
+	var refs []planColumnRef
+	n := len(expr)
+	for i := 0; i < n; {
+		c := expr[i]
+		switch {
+		case c == '\'':
+			i = skipQuoted(expr, i, c, false)
+
+		case c == ':' && i+1 < n && expr[i+1] == ':':
+			i += 2
+			for i < n && (isIdentChar(expr[i]) || expr[i] == '.' || expr[i] == '"' || expr[i] == '[' || expr[i] == ']') {
+				i++
+			}
+
+		case c == '"' || isIdentStart(c):
+			start := i
+			alias, end := readPlanIdent(expr, i)
+			if end >= n || expr[end] != '.' || end+1 >= n || !(expr[end+1] == '"' || isIdentStart(expr[end+1])) {
+				i = end
+				break
+			}
+			column, after := readPlanIdent(expr, end+1)
+			i = after
+			if after < n && expr[after] == '(' || isFunctionArgument(expr, start) {
+				// A plain index on the column would not serve a function of it
+				break
+			}
+			refs = append(refs, planColumnRef{
+				Alias:    alias,
+				Column:   column,
+				Equality: comparedWithEquals(expr, start, after),
+			})
+
+		default:
+			i++
+		}
+	}
+	return refs
+}
+
+// readPlanIdent reads a bare or double-quoted identifier starting at i and returns it
+// unquoted with the index just past it
+func readPlanIdent(expr string, i int) (string, int) {
+	if expr[i] == '"' {
+		end := skipQuoted(expr, i, '"', false)
+		return strings.ReplaceAll(expr[i+1:end-1], `""`, `"`), end
+	}
+	end := i
+	for end < len(expr) && isIdentChar(expr[end]) {
+		end++
+	}
+	return expr[i:end], end
+}
+
+// isFunctionArgument reports whether the operand starting at expr[start] is inside
+// the parentheses of a function call, such as lower((u.email)::text)
+func isFunctionArgument(expr string, start int) bool {
+	i := start - 1
+	for i >= 0 && expr[i] == '(' {
+		if i > 0 && isIdentChar(expr[i-1]) {
+			return true
+		}
+		i--
+	}
+	return false
+}
+
+// comparedWithEquals reports whether the operand spanning expr[start:end] is an
+// operand of =, looking past parentheses, whitespace, and casts around it
+func comparedWithEquals(expr string, start, end int) bool {
+	i := end
+	for i < len(expr) {
+		if expr[i] == ')' || expr[i] == ' ' {
+			i++
+		} else if strings.HasPrefix(expr[i:], "::") {
+			i += 2
+			for i < len(expr) && (isIdentChar(expr[i]) || expr[i] == '.' || expr[i] == '"') {
+				i++
+			}
+		} else {
+			break
+		}
+	}
+	if i+1 < len(expr) && expr[i] == '=' && expr[i+1] == ' ' {
+		return true
+	}
+
+	i = start - 1
+	for i >= 0 && (expr[i] == '(' || expr[i] == ' ') {
+		i--
+	}
+	return i >= 1 && expr[i] == '=' && expr[i-1] == ' '
+}
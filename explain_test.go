@@ -0,0 +1,218 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanFingerprint(t *testing.T) {
+	// plan builds a hash join of an index scan on orders and a sequential scan on
+	// customers; vary adjusts the copy before it is fingerprinted
+	plan := func(vary func(*PlanNode)) *PlanNode {
+		node := &PlanNode{
+			NodeType: "Hash Join", JoinType: "Inner", StartupCost: 1.5, TotalCost: 120.25, PlanRows: 40, PlanWidth: 16,
+			HashCond: "(o.customer_id = c.id)",
+			Plans: []PlanNode{
+				{NodeType: "Index Scan", RelationName: "orders", Schema: "public", Alias: "o", IndexName: "orders_created_idx",
+					TotalCost: 80, PlanRows: 400, IndexCond: "(o.created_at > '2024-01-01'::date)"},
+				{NodeType: "Hash", TotalCost: 20, Plans: []PlanNode{
+					{NodeType: "Seq Scan", RelationName: "customers", Schema: "public", Alias: "c",
+						TotalCost: 18, PlanRows: 10, Filter: "(c.region = 'eu'::text)"},
+				}},
+			},
+		}
+		if vary != nil {
+			vary(node)
+		}
+		return node
+	}
+	base := planFingerprint(plan(nil))
+
+	same := []struct {
+		name string
+		vary func(*PlanNode)
+	}{
+		{name: "costs and estimates", vary: func(n *PlanNode) {
+			n.StartupCost, n.TotalCost, n.PlanRows, n.PlanWidth = 9, 9000, 12345, 64
+			n.Plans[1].Plans[0].TotalCost, n.Plans[1].Plans[0].PlanRows = 500, 9999
+		}},
+		{name: "literals in conditions", vary: func(n *PlanNode) {
+			n.Plans[0].IndexCond = "(o.created_at > '2025-06-30'::date)"
+			n.Plans[1].Plans[0].Filter = "(c.region = 'us'::text)"
+		}},
+		{name: "aliases", vary: func(n *PlanNode) {
+			n.Plans[0].Alias, n.Plans[1].Plans[0].Alias = "orders_1", "cust"
+			n.HashCond = "(orders_1.customer_id = cust.id)"
+		}},
+	}
+	for _, tt := range same {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planFingerprint(plan(tt.vary)); got != base {
+				t.Errorf("fingerprint changed: %s, want %s", got, base)
+			}
+		})
+	}
+
+	different := []struct {
+		name string
+		vary func(*PlanNode)
+	}{
+		{name: "node type", vary: func(n *PlanNode) { n.NodeType = "Merge Join" }},
+		{name: "join type", vary: func(n *PlanNode) { n.JoinType = "Left" }},
+		{name: "index", vary: func(n *PlanNode) { n.Plans[0].IndexName = "orders_customer_idx" }},
+		{name: "relation", vary: func(n *PlanNode) { n.Plans[1].Plans[0].RelationName = "accounts" }},
+		{name: "schema", vary: func(n *PlanNode) { n.Plans[1].Plans[0].Schema = "sales" }},
+		{name: "seq scan instead of index scan", vary: func(n *PlanNode) {
+			n.Plans[0].NodeType, n.Plans[0].IndexName = "Seq Scan", ""
+		}},
+		{name: "children swapped", vary: func(n *PlanNode) { n.Plans[0], n.Plans[1] = n.Plans[1], n.Plans[0] }},
+		{name: "extra node", vary: func(n *PlanNode) {
+			*n = PlanNode{NodeType: "Limit", Plans: []PlanNode{*n}}
+		}},
+	}
+	for _, tt := range different {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planFingerprint(plan(tt.vary)); got == base {
+				t.Errorf("fingerprint unchanged after changing the %s", tt.name)
+			}
+		})
+	}
+
+	if got := planShape(plan(nil)); got != "Hash Join (Inner) [Index Scan using orders_created_idx on public.orders, Hash [Seq Scan on public.customers]]" {
+		t.Errorf("planShape = %q", got)
+	}
+}
+
+func TestPlanColumnRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []planColumnRef
+	}{
+		{name: "equality", expr: "(u.email = 'a@b.c'::text)", want: []planColumnRef{{Alias: "u", Column: "email", Equality: true}}},
+		{name: "range", expr: "(o.total > '10'::numeric)", want: []planColumnRef{{Alias: "o", Column: "total"}}},
+		{name: "cast operand", expr: "((u.status)::text = 'active'::text)", want: []planColumnRef{{Alias: "u", Column: "status", Equality: true}}},
+		{name: "join", expr: "(o.customer_id = c.id)", want: []planColumnRef{
+			{Alias: "o", Column: "customer_id", Equality: true},
+			{Alias: "c", Column: "id", Equality: true},
+		}},
+		{name: "quoted identifiers", expr: `("Order"."Customer" = 5)`, want: []planColumnRef{{Alias: "Order", Column: "Customer", Equality: true}}},
+		{name: "function argument is skipped", expr: "(lower((u.email)::text) = 'x'::text)"},
+		{name: "qualified function is skipped", expr: "(pg_catalog.upper(u.name) = 'X'::text)"},
+		{name: "literal is skipped", expr: "((u.note)::text = 'a.b'::text)", want: []planColumnRef{{Alias: "u", Column: "note", Equality: true}}},
+		{name: "qualified cast is skipped", expr: "(u.kind = 'x'::public.kind)", want: []planColumnRef{{Alias: "u", Column: "kind", Equality: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planColumnRefs(tt.expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planColumnRefs(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestIndexes(t *testing.T) {
+	seqScan := func(table, alias, filter string) PlanNode {
+		return PlanNode{NodeType: "Seq Scan", RelationName: table, Schema: "public", Alias: alias, PlanRows: 1000, Filter: filter}
+	}
+
+	tests := []struct {
+		name string
+		plan PlanNode
+		want []IndexSuggestion
+	}{
+		{
+			name: "filtered seq scan, equality columns first",
+			plan: seqScan("orders", "o", "((o.created_at > '2024-01-01'::date) AND (o.status = 'paid'::text))"),
+			want: []IndexSuggestion{{
+				Schema: "public", Table: "orders", Columns: []string{"status", "created_at"},
+				Reason:        "sequential scan filter: ((o.created_at > '2024-01-01'::date) AND (o.status = 'paid'::text))",
+				EstimatedRows: 1000,
+				DDL:           `CREATE INDEX CONCURRENTLY ON "public"."orders" ("status", "created_at");`,
+			}},
+		},
+		{
+			name: "columns capped",
+			plan: seqScan("t", "t", "((t.a = 1) AND (t.b = 2) AND (t.c = 3) AND (t.d = 4))"),
+			want: []IndexSuggestion{{
+				Schema: "public", Table: "t", Columns: []string{"a", "b", "c"},
+				Reason:        "sequential scan filter: ((t.a = 1) AND (t.b = 2) AND (t.c = 3) AND (t.d = 4))",
+				EstimatedRows: 1000,
+				DDL:           `CREATE INDEX CONCURRENTLY ON "public"."t" ("a", "b", "c");`,
+			}},
+		},
+		{
+			name: "seq scan without a filter",
+			plan: seqScan("orders", "o", ""),
+		},
+		{
+			name: "index scan",
+			plan: PlanNode{NodeType: "Index Scan", RelationName: "orders", Schema: "public", Alias: "o", IndexName: "orders_pkey", Filter: "(o.status = 'paid'::text)"},
+		},
+		{
+			name: "filter only on a function of the column",
+			plan: seqScan("users", "u", "(lower((u.email)::text) = 'x'::text)"),
+		},
+		{
+			name: "hash join on a seq scanned relation",
+			plan: PlanNode{NodeType: "Hash Join", HashCond: "(o.customer_id = c.id)", Plans: []PlanNode{
+				{NodeType: "Index Scan", RelationName: "customers", Schema: "public", Alias: "c", IndexName: "customers_pkey"},
+				{NodeType: "Hash", Plans: []PlanNode{seqScan("orders", "o", "")}},
+			}},
+			want: []IndexSuggestion{{
+				Schema: "public", Table: "orders", Columns: []string{"customer_id"},
+				Reason:        "Hash Join join condition on a sequentially scanned relation: (o.customer_id = c.id)",
+				EstimatedRows: 1000,
+				DDL:           `CREATE INDEX CONCURRENTLY ON "public"."orders" ("customer_id");`,
+			}},
+		},
+		{
+			name: "duplicate suggestions are merged",
+			plan: PlanNode{NodeType: "Hash Join", HashCond: "(o.customer_id = c.id)", Plans: []PlanNode{
+				seqScan("customers", "c", "(c.id = 1)"),
+				{NodeType: "Hash", Plans: []PlanNode{seqScan("orders", "o", "")}},
+			}},
+			want: []IndexSuggestion{
+				{
+					Schema: "public", Table: "orders", Columns: []string{"customer_id"},
+					Reason:        "Hash Join join condition on a sequentially scanned relation: (o.customer_id = c.id)",
+					EstimatedRows: 1000,
+					DDL:           `CREATE INDEX CONCURRENTLY ON "public"."orders" ("customer_id");`,
+				},
+				{
+					Schema: "public", Table: "customers", Columns: []string{"id"},
+					Reason:        "Hash Join join condition on a sequentially scanned relation: (o.customer_id = c.id)",
+					EstimatedRows: 1000,
+					DDL:           `CREATE INDEX CONCURRENTLY ON "public"."customers" ("id");`,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestIndexes(&tt.plan); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suggestIndexes = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexCovers(t *testing.T) {
+	indexes := [][]string{{"id"}, {"status", "created_at", "total"}}
+	tests := []struct {
+		columns []string
+		want    bool
+	}{
+		{columns: []string{"id"}, want: true},
+		{columns: []string{"status"}, want: true},
+		{columns: []string{"status", "created_at"}, want: true},
+		{columns: []string{"created_at"}, want: false},
+		{columns: []string{"created_at", "status"}, want: false},
+		{columns: []string{"id", "status"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := indexCovers(indexes, tt.columns); got != tt.want {
+			t.Errorf("indexCovers(%v) = %v, want %v", tt.columns, got, tt.want)
+		}
+	}
+}
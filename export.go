@@ -0,0 +1,418 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	exportPath      = "/admin/export/schemas.zip"
+	tableExportPath = "/admin/export/table"
+	exportTokenTTL  = 10 * time.Minute
+	exportTimeout   = 5 * time.Minute
+
+	// exportErrorEntry is the zip entry holding the error that ended a schema export early
+	exportErrorEntry = "EXPORT_ERROR.txt"
+)
+
+// exportToken is a single-use download grant. Table is set for table exports, whose
+// tokens only download that table.
+type exportToken struct {
+	expiresAt time.Time
+	table     *tableExport
+}
+
+// tableExport names the table a table export token downloads
+type tableExport struct {
+	schema string
+	table  string
+}
+
+// SchemaExporter streams the DDL of every schema across all adapters as a zip
+// archive, and PostgreSQL tables as newline-delimited JSON
+type SchemaExporter struct {
+	adapters     *AdapterRegistry
+	apiKey       string
+	maxRows      int
+	batchTimeout time.Duration
+	timeout      time.Duration
+	tokens       map[string]exportToken
+	mu           sync.Mutex
+}
+
+// NewSchemaExporter creates a new schema exporter gated by the configured API key
+func NewSchemaExporter(adapters *AdapterRegistry, cfg *Config) *SchemaExporter {
+	return &SchemaExporter{
+		adapters:     adapters,
+		apiKey:       cfg.APIKey,
+		maxRows:      cfg.TableExportMaxRows,
+		batchTimeout: cfg.ToolTimeout,
+		timeout:      cfg.TableExportTimeout,
+		tokens:       make(map[string]exportToken),
+	}
+}
+
+// SetupRoutes registers the export download endpoints
+func (e *SchemaExporter) SetupRoutes(app *fiber.App) {
+	app.Get(exportPath, e.handleExport)
+	app.Get(tableExportPath, e.handleTableExport)
+}
+
+// RegisterTools registers the export_schemas tool, which issues temporary download URLs
+func (e *SchemaExporter) RegisterTools(registry *ToolRegistry) {
+	registry.RegisterTool(
+		Tool{
+			Name:        "export_schemas",
+			Description: "Create a temporary, single-use download path for a zip of DDL files for all schemas across all databases",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			token, expiresAt := e.issueToken(nil)
+			return jsonResult(map[string]interface{}{
+				"download_path": fmt.Sprintf("%s?token=%s", exportPath, token),
+				"expires_at":    expiresAt.UTC().Format(time.RFC3339),
+			})
+		},
+	)
+
+	adapter, ok := e.adapters.Get("postgres")
+	if !ok {
+		return
+	}
+	postgresAdapter := adapter.(*PostgresAdapter)
+
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_export_table",
+			Description: "Create a temporary, single-use download path that streams every row of a PostgreSQL table as newline-delimited JSON. Rows are read through a server-side cursor in batches, so tables of any size can be exported without loading them into memory",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the table (default: public)",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table to export",
+					},
+				},
+				Required: []string{"table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.TableName == "" {
+				return nil, fmt.Errorf("table_name is required")
+			}
+			if params.SchemaName == "" {
+				params.SchemaName = "public"
+			}
+
+			// Fail now rather than in the middle of a download
+			if _, err := postgresAdapter.TableColumns(ctx, params.SchemaName, params.TableName); err != nil {
+				return nil, err
+			}
+
+			token, expiresAt := e.issueToken(&tableExport{schema: params.SchemaName, table: params.TableName})
+			return jsonResult(map[string]interface{}{
+				"download_path": fmt.Sprintf("%s?token=%s", tableExportPath, token),
+				"expires_at":    expiresAt.UTC().Format(time.RFC3339),
+				"max_rows":      e.maxRows,
+			})
+		},
+	)
+}
+
+// issueToken creates a single-use download token, for a table export when table is set
+func (e *SchemaExporter) issueToken(table *tableExport) (string, time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	for token, t := range e.tokens {
+		if now.After(t.expiresAt) {
+			delete(e.tokens, token)
+		}
+	}
+
+	token := uuid.New().String()
+	expiresAt := now.Add(exportTokenTTL)
+	e.tokens[token] = exportToken{expiresAt: expiresAt, table: table}
+	return token, expiresAt
+}
+
+// consumeToken validates and invalidates a download token
+func (e *SchemaExporter) consumeToken(token string) (exportToken, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	t, exists := e.tokens[token]
+	if !exists {
+		return exportToken{}, false
+	}
+	delete(e.tokens, token)
+	return t, time.Now().Before(t.expiresAt)
+}
+
+// hasAPIKey checks the request for the API key as a bearer token
+func (e *SchemaExporter) hasAPIKey(c *fiber.Ctx) bool {
+	auth := c.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	key := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(key), []byte(e.apiKey)) == 1
+}
+
+// authorized checks for a valid API key or a single-use schema download token
+func (e *SchemaExporter) authorized(c *fiber.Ctx) bool {
+	if e.hasAPIKey(c) {
+		return true
+	}
+	if token := c.Query("token"); token != "" {
+		t, ok := e.consumeToken(token)
+		return ok && t.table == nil
+	}
+	return false
+}
+
+// handleExport streams the schema export zip
+func (e *SchemaExporter) handleExport(c *fiber.Ctx) error {
+	l := log.With().Str("scope", "handleExport").Logger()
+
+	if !e.authorized(c) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	filename := fmt.Sprintf("schemas-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	c.Set("Content-Type", "application/zip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+
+		if err := e.writeZip(ctx, w); err != nil {
+			l.Error().Err(err).Msg("Schema export failed")
+			return
+		}
+		l.Info().Str("file", filename).Msg("Schema export completed")
+	})
+
+	return nil
+}
+
+// writeZip writes one .sql file per adapter schema into a zip stream. The 200 status
+// and earlier entries are already sent when a schema fails, so the error is written
+// to an EXPORT_ERROR.txt entry and the archive is still closed, rather than ending
+// the download with a truncated zip that looks like a network failure.
+func (e *SchemaExporter) writeZip(ctx context.Context, w *bufio.Writer) error {
+	zw := zip.NewWriter(w)
+
+	err := e.writeSchemaEntries(ctx, zw, w)
+	if err != nil {
+		if f, createErr := zw.Create(exportErrorEntry); createErr == nil {
+			f.Write([]byte(err.Error() + "\n"))
+		}
+	}
+
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// writeSchemaEntries adds an <adapter>/<schema>.sql entry per schema, flushing each
+// to the client as it is written. A schema's DDL is read in full before its entry is
+// created, so a failed schema never leaves a partial entry.
+func (e *SchemaExporter) writeSchemaEntries(ctx context.Context, zw *zip.Writer, w *bufio.Writer) error {
+	used := make(map[string]bool)
+	for _, name := range e.adapters.List() {
+		adapter, ok := e.adapters.Get(name)
+		if !ok {
+			continue
+		}
+
+		schemas, err := adapter.ListSchemas(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list schemas for %s: %w", name, err)
+		}
+
+		for _, schema := range schemas {
+			ddl, err := adapter.GetSchemaDDL(ctx, schema.Name)
+			if err != nil {
+				return fmt.Errorf("failed to get DDL for %s.%s: %w", name, schema.Name, err)
+			}
+
+			f, err := zw.Create(zipEntryName(name, schema.Name, used))
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write([]byte(ddl + "\n")); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// zipEntryName returns the <adapter>/<schema>.sql entry for a schema. Schema names
+// are arbitrary quoted identifiers, so path separators, control characters, and a
+// leading dot are replaced with "_" to keep the entry inside its adapter directory
+// when extracted; a name already in used gets a numeric suffix.
+func zipEntryName(adapter, schema string, used map[string]bool) string {
+	clean := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, schema)
+	if clean == "" || strings.HasPrefix(clean, ".") {
+		clean = "_" + clean
+	}
+
+	entry := fmt.Sprintf("%s/%s.sql", adapter, clean)
+	for i := 2; used[entry]; i++ {
+		entry = fmt.Sprintf("%s/%s_%d.sql", adapter, clean, i)
+	}
+	used[entry] = true
+	return entry
+}
+
+// handleTableExport streams a PostgreSQL table as newline-delimited JSON: a
+// {"columns": [...]} line, one JSON array per row, and a closing
+// {"rows": n, "truncated": bool} line. It accepts a table export token, or the API
+// key with schema and table query parameters.
+func (e *SchemaExporter) handleTableExport(c *fiber.Ctx) error {
+	l := log.With().Str("scope", "handleTableExport").Logger()
+
+	var target *tableExport
+	if e.hasAPIKey(c) {
+		target = &tableExport{schema: c.Query("schema", "public"), table: c.Query("table")}
+	} else if token := c.Query("token"); token != "" {
+		if t, ok := e.consumeToken(token); ok {
+			target = t.table
+		}
+	}
+	if target == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	if target.table == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "table query parameter is required",
+		})
+	}
+
+	adapter, ok := e.adapters.Get("postgres")
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "PostgreSQL adapter is not configured",
+		})
+	}
+	postgresAdapter := adapter.(*PostgresAdapter)
+
+	// Check the table before the 200 status is sent with the first streamed bytes
+	if _, err := postgresAdapter.TableColumns(c.UserContext(), target.schema, target.table); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// The before-hooks may rewrite or reject the export like any other query
+	query, err := e.adapters.PrepareQuery(c.UserContext(), postgresAdapter, tableExportQuery(target.schema, target.table))
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filename := fmt.Sprintf("%s.%s-%s.ndjson", target.schema, target.table, time.Now().UTC().Format("20060102-150405"))
+	c.Set("Content-Type", "application/x-ndjson")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// The stream outlives the handler, so it is bounded by its own deadline
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if e.timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, e.timeout)
+			defer cancelTimeout()
+		}
+
+		rows, truncated, err := e.writeTable(ctx, postgresAdapter, query, w)
+		if err != nil {
+			l.Error().Err(err).Str("table", target.schema+"."+target.table).Int("rows", rows).Msg("Table export failed")
+			return
+		}
+		l.Info().Str("file", filename).Int("rows", rows).Bool("truncated", truncated).Msg("Table export completed")
+	})
+
+	return nil
+}
+
+// writeTable writes the table export stream, flushing after every batch. A failed
+// flush means the client went away, which ends the export and closes its cursor.
+func (e *SchemaExporter) writeTable(ctx context.Context, adapter *PostgresAdapter, query string, w *bufio.Writer) (int, bool, error) {
+	enc := json.NewEncoder(w)
+	header := false
+
+	rows, truncated, err := adapter.ExportTable(ctx, query, e.maxRows, e.batchTimeout, func(batch QueryResult) error {
+		if !header {
+			if err := enc.Encode(map[string]interface{}{"columns": batch.Columns}); err != nil {
+				return err
+			}
+			header = true
+		}
+		for _, row := range batch.Rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}, func(ctx context.Context, statement string, result QueryResult, err error) {
+		e.adapters.ObserveQuery(ctx, adapter, statement, result, err)
+	})
+	if err != nil {
+		return rows, truncated, err
+	}
+
+	if err := enc.Encode(map[string]interface{}{"rows": rows, "truncated": truncated}); err != nil {
+		return rows, truncated, err
+	}
+	return rows, truncated, w.Flush()
+}
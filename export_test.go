@@ -0,0 +1,219 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTableExportTest serves table exports from a fake PostgreSQL adapter whose
+// orders table has one column and whose cursor returns two rows
+func newTableExportTest(t *testing.T, cfg *Config, fake *catalogTestDB, hook BeforeQueryHook) *fiber.App {
+	t.Helper()
+	fake.results = append(fake.results,
+		catalogTestResult{match: "information_schema.columns", rows: []string{"id"}},
+		catalogTestResult{match: "FETCH", rows: []string{"a", "b"}})
+
+	adapters := NewAdapterRegistry()
+	adapters.adapters["postgres"] = &PostgresAdapter{BaseAdapter: BaseAdapter{name: "postgres", enabled: true, db: sql.OpenDB(fake)}}
+	if hook != nil {
+		adapters.AddBeforeQueryHook(hook)
+	}
+
+	cfg.APIKey = "secret"
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	NewSchemaExporter(adapters, cfg).SetupRoutes(app)
+	return app
+}
+
+func getTableExport(t *testing.T, app *fiber.App) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, tableExportPath+"?schema=public&table=orders", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := app.Test(req, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestTableExportRunsBeforeHooks(t *testing.T) {
+	fake := &catalogTestDB{}
+	app := newTableExportTest(t, &Config{}, fake, func(ctx context.Context, adapter DatabaseAdapter, query string) (string, error) {
+		return query + " WHERE tenant_id = 7", nil
+	})
+
+	status, body := getTableExport(t, app)
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", status, body)
+	}
+	want := `{"columns":["column_name"]}` + "\n" + `["a"]` + "\n" + `["b"]` + "\n" + `{"rows":2,"truncated":false}` + "\n"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	declare := `DECLARE mcp_export NO SCROLL CURSOR FOR SELECT * FROM "public"."orders" WHERE tenant_id = 7`
+	found := false
+	for _, statement := range fake.statements {
+		found = found || statement == declare
+	}
+	if !found {
+		t.Errorf("statements = %q, want the rewritten %q", fake.statements, declare)
+	}
+}
+
+func TestTableExportRejectedByBeforeHook(t *testing.T) {
+	fake := &catalogTestDB{}
+	app := newTableExportTest(t, &Config{}, fake, func(ctx context.Context, adapter DatabaseAdapter, query string) (string, error) {
+		return "", errors.New("exports are disabled")
+	})
+
+	status, body := getTableExport(t, app)
+	if status != fiber.StatusForbidden || !strings.Contains(body, "exports are disabled") {
+		t.Errorf("export = %d %s, want 403 with the hook's reason", status, body)
+	}
+	for _, statement := range fake.statements {
+		if strings.HasPrefix(statement, "DECLARE") {
+			t.Errorf("rejected export declared a cursor: %q", statement)
+		}
+	}
+}
+
+func TestTableExportDeadline(t *testing.T) {
+	fake := &catalogTestDB{block: "FETCH"}
+	app := newTableExportTest(t, &Config{TableExportTimeout: 50 * time.Millisecond}, fake, nil)
+
+	start := time.Now()
+	status, body := getTableExport(t, app)
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", status, body)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("export took %v, want it cut off by the 50ms deadline", elapsed)
+	}
+	if strings.Contains(body, `"rows"`) {
+		t.Errorf("body = %q, want no closing line after the deadline", body)
+	}
+}
+
+// schemaExportAdapter lists fixed schemas and fails GetSchemaDDL for failSchema
+type schemaExportAdapter struct {
+	recordingAdapter
+	schemas    []string
+	failSchema string
+}
+
+func (a *schemaExportAdapter) ListSchemas(context.Context) ([]Schema, error) {
+	schemas := make([]Schema, len(a.schemas))
+	for i, name := range a.schemas {
+		schemas[i] = Schema{Name: name}
+	}
+	return schemas, nil
+}
+
+func (a *schemaExportAdapter) GetSchemaDDL(_ context.Context, schema string) (string, error) {
+	if schema == a.failSchema {
+		return "", errors.New("permission denied for schema " + schema)
+	}
+	return "-- " + schema, nil
+}
+
+// readZip writes a schema export for adapter and returns its entries and contents
+func readZip(t *testing.T, adapter DatabaseAdapter) (map[string]string, []string, error) {
+	t.Helper()
+	adapters := NewAdapterRegistry()
+	adapters.adapters[adapter.Name()] = adapter
+
+	var buf bytes.Buffer
+	exportErr := NewSchemaExporter(adapters, &Config{}).writeZip(context.Background(), bufio.NewWriter(&buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("export is not a valid zip: %v", err)
+	}
+	contents := make(map[string]string)
+	var names []string
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		contents[f.Name] = string(data)
+	}
+	return contents, names, exportErr
+}
+
+func TestSchemaExportEntryNames(t *testing.T) {
+	adapter := &schemaExportAdapter{
+		recordingAdapter: recordingAdapter{name: "postgres"},
+		schemas:          []string{"public", "../../etc/cron.d/x", `..\evil`, ".hidden", "a/b", "a_b", "c:d", "tab\tname"},
+	}
+	contents, names, err := readZip(t, adapter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"postgres/public.sql",
+		"postgres/_.._.._etc_cron.d_x.sql",
+		"postgres/_.._evil.sql",
+		"postgres/_.hidden.sql",
+		"postgres/a_b.sql",
+		"postgres/a_b_2.sql",
+		"postgres/c_d.sql",
+		"postgres/tab_name.sql",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("entries = %q, want %q", names, want)
+	}
+	if contents["postgres/_.._.._etc_cron.d_x.sql"] != "-- ../../etc/cron.d/x\n" {
+		t.Errorf("sanitized entry holds %q, want the original schema's DDL", contents["postgres/_.._.._etc_cron.d_x.sql"])
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, "postgres/") || strings.Contains(name[len("postgres/"):], "/") || strings.Contains(name, `\`) {
+			t.Errorf("entry %q escapes its adapter directory", name)
+		}
+	}
+}
+
+func TestSchemaExportMidStreamError(t *testing.T) {
+	adapter := &schemaExportAdapter{
+		recordingAdapter: recordingAdapter{name: "postgres"},
+		schemas:          []string{"public", "locked", "sales"},
+		failSchema:       "locked",
+	}
+	contents, names, err := readZip(t, adapter)
+	if err == nil || !strings.Contains(err.Error(), "failed to get DDL for postgres.locked") {
+		t.Errorf("writeZip error = %v, want the DDL failure", err)
+	}
+
+	// The archive is complete: the schemas before the failure and an error entry
+	if want := []string{"postgres/public.sql", exportErrorEntry}; !reflect.DeepEqual(names, want) {
+		t.Errorf("entries = %q, want %q", names, want)
+	}
+	if got := contents[exportErrorEntry]; got != "failed to get DDL for postgres.locked: permission denied for schema locked\n" {
+		t.Errorf("%s = %q", exportErrorEntry, got)
+	}
+}
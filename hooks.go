@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BeforeQueryHook runs before a query is executed. It may return a rewritten
+// query (e.g. with an injected tenant filter) or an error to reject it.
+type BeforeQueryHook func(ctx context.Context, adapter DatabaseAdapter, query string) (string, error)
+
+// AfterQueryHook observes a query once it has executed
+type AfterQueryHook func(ctx context.Context, adapter DatabaseAdapter, query string, result QueryResult, err error)
+
+// AddBeforeQueryHook registers a hook that runs before every ExecuteSelect
+func (r *AdapterRegistry) AddBeforeQueryHook(hook BeforeQueryHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.beforeHooks = append(r.beforeHooks, hook)
+}
+
+// AddAfterQueryHook registers a hook that runs after every ExecuteSelect
+func (r *AdapterRegistry) AddAfterQueryHook(hook AfterQueryHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.afterHooks = append(r.afterHooks, hook)
+}
+
+// ExecuteSelect runs a query on the adapter, wrapped by the registered hooks.
+// Before-hooks run in registration order, each receiving the previous hook's query.
+func (r *AdapterRegistry) ExecuteSelect(ctx context.Context, adapter DatabaseAdapter, query string) (QueryResult, error) {
+	r.mu.RLock()
+	beforeHooks := r.beforeHooks
+	afterHooks := r.afterHooks
+	r.mu.RUnlock()
+
+	ctx, span := tracer.Start(ctx, "db.query", trace.WithAttributes(attribute.String("db.system", adapter.Name())))
+	defer span.End()
+
+	query, err := runBeforeHooks(ctx, beforeHooks, adapter, query)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	result, err := adapter.ExecuteSelect(ctx, query)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int("db.rows_returned", len(result.Rows)))
+	}
+
+	for _, hook := range afterHooks {
+		hook(ctx, adapter, query, result, err)
+	}
+
+	return result, err
+}
+
+// PrepareQuery runs the before-hooks for a query executed outside ExecuteSelect, such
+// as the SELECT behind a table export's cursor, and returns the query to run
+func (r *AdapterRegistry) PrepareQuery(ctx context.Context, adapter DatabaseAdapter, query string) (string, error) {
+	r.mu.RLock()
+	beforeHooks := r.beforeHooks
+	r.mu.RUnlock()
+
+	return runBeforeHooks(ctx, beforeHooks, adapter, query)
+}
+
+// runBeforeHooks passes query through hooks in order, each receiving the previous
+// hook's query, and stops at the first rejection
+func runBeforeHooks(ctx context.Context, hooks []BeforeQueryHook, adapter DatabaseAdapter, query string) (string, error) {
+	for _, hook := range hooks {
+		rewritten, err := hook(ctx, adapter, query)
+		if err != nil {
+			return "", fmt.Errorf("query rejected: %w", err)
+		}
+		query = rewritten
+	}
+	return query, nil
+}
+
+// ObserveQuery runs the after-hooks for a statement executed outside ExecuteSelect,
+// such as a migration statement, so it is logged and counted like any other query
+func (r *AdapterRegistry) ObserveQuery(ctx context.Context, adapter DatabaseAdapter, query string, result QueryResult, err error) {
+	r.mu.RLock()
+	afterHooks := r.afterHooks
+	r.mu.RUnlock()
+
+	for _, hook := range afterHooks {
+		hook(ctx, adapter, query, result, err)
+	}
+}
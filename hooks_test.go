@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// failingAdapter records the query it was asked to run and fails it
+type failingAdapter struct {
+	recordingAdapter
+	err error
+}
+
+func (a *failingAdapter) ExecuteSelect(ctx context.Context, query string) (QueryResult, error) {
+	a.recordingAdapter.ExecuteSelect(ctx, query)
+	return QueryResult{}, a.err
+}
+
+func TestExecuteSelectHookOrder(t *testing.T) {
+	adapter := &recordingAdapter{name: "postgres"}
+	registry := NewAdapterRegistry()
+
+	var calls []string
+	registry.AddBeforeQueryHook(func(_ context.Context, _ DatabaseAdapter, query string) (string, error) {
+		calls = append(calls, "before 1: "+query)
+		return query + " WHERE tenant_id = 1", nil
+	})
+	registry.AddBeforeQueryHook(func(_ context.Context, _ DatabaseAdapter, query string) (string, error) {
+		calls = append(calls, "before 2: "+query)
+		return query + " LIMIT 10", nil
+	})
+	registry.AddAfterQueryHook(func(_ context.Context, _ DatabaseAdapter, query string, result QueryResult, err error) {
+		calls = append(calls, "after: "+query)
+		if err != nil || !reflect.DeepEqual(result.Columns, []string{"schema"}) {
+			t.Errorf("after-hook got result %v, err %v", result, err)
+		}
+	})
+
+	if _, err := registry.ExecuteSelect(context.Background(), adapter, "SELECT * FROM t"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"before 1: SELECT * FROM t",
+		"before 2: SELECT * FROM t WHERE tenant_id = 1",
+		"after: SELECT * FROM t WHERE tenant_id = 1 LIMIT 10",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls = %q, want %q", calls, want)
+	}
+	if adapter.query != "SELECT * FROM t WHERE tenant_id = 1 LIMIT 10" {
+		t.Errorf("adapter ran %q, want the rewritten query", adapter.query)
+	}
+}
+
+func TestExecuteSelectBeforeHookRejects(t *testing.T) {
+	adapter := &recordingAdapter{name: "postgres"}
+	registry := NewAdapterRegistry()
+
+	denied := errors.New("denied")
+	laterHookRan, afterHookRan := false, false
+	registry.AddBeforeQueryHook(func(context.Context, DatabaseAdapter, string) (string, error) {
+		return "", denied
+	})
+	registry.AddBeforeQueryHook(func(_ context.Context, _ DatabaseAdapter, query string) (string, error) {
+		laterHookRan = true
+		return query, nil
+	})
+	registry.AddAfterQueryHook(func(context.Context, DatabaseAdapter, string, QueryResult, error) {
+		afterHookRan = true
+	})
+
+	_, err := registry.ExecuteSelect(context.Background(), adapter, "SELECT 1")
+	if !errors.Is(err, denied) {
+		t.Fatalf("ExecuteSelect error = %v, want it to wrap %v", err, denied)
+	}
+	if laterHookRan || afterHookRan {
+		t.Errorf("hooks ran after a rejection: before=%v after=%v", laterHookRan, afterHookRan)
+	}
+	if adapter.query != "" {
+		t.Errorf("adapter ran %q after a rejection", adapter.query)
+	}
+}
+
+func TestExecuteSelectAfterHookReceivesError(t *testing.T) {
+	queryErr := errors.New("relation does not exist")
+	adapter := &failingAdapter{recordingAdapter: recordingAdapter{name: "postgres"}, err: queryErr}
+	registry := NewAdapterRegistry()
+
+	var observed error
+	registry.AddAfterQueryHook(func(_ context.Context, _ DatabaseAdapter, _ string, _ QueryResult, err error) {
+		observed = err
+	})
+
+	if _, err := registry.ExecuteSelect(context.Background(), adapter, "SELECT * FROM missing"); err != queryErr {
+		t.Fatalf("ExecuteSelect error = %v, want %v", err, queryErr)
+	}
+	if observed != queryErr {
+		t.Errorf("after-hook got error %v, want %v", observed, queryErr)
+	}
+}
+
+func TestPrepareQueryRunsOnlyBeforeHooks(t *testing.T) {
+	adapter := &recordingAdapter{name: "postgres"}
+	registry := NewAdapterRegistry()
+
+	afterHookRan := false
+	registry.AddBeforeQueryHook(func(_ context.Context, _ DatabaseAdapter, query string) (string, error) {
+		return "/* tenant */ " + query, nil
+	})
+	registry.AddAfterQueryHook(func(context.Context, DatabaseAdapter, string, QueryResult, error) {
+		afterHookRan = true
+	})
+
+	query, err := registry.PrepareQuery(context.Background(), adapter, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "/* tenant */ SELECT 1" {
+		t.Errorf("PrepareQuery = %q, want the rewritten query", query)
+	}
+	if afterHookRan || adapter.query != "" {
+		t.Errorf("PrepareQuery executed the query: after-hook=%v adapter=%q", afterHookRan, adapter.query)
+	}
+}
@@ -1,26 +1,34 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // JSONRPCHandler handles JSON-RPC requests
 type JSONRPCHandler struct {
 	methods map[string]MethodHandler
 	mu      sync.RWMutex
+
+	// maxBatchSize caps the number of messages in a batch; 0 means no limit
+	maxBatchSize int
 }
 
 // MethodHandler is a function that handles a JSON-RPC method
-type MethodHandler func(params json.RawMessage) (interface{}, error)
+type MethodHandler func(ctx context.Context, params json.RawMessage) (interface{}, error)
 
-// NewJSONRPCHandler creates a new JSON-RPC handler
-func NewJSONRPCHandler() *JSONRPCHandler {
+// NewJSONRPCHandler creates a new JSON-RPC handler that accepts batches of up to
+// maxBatchSize messages (0 for no limit)
+func NewJSONRPCHandler(maxBatchSize int) *JSONRPCHandler {
 	return &JSONRPCHandler{
-		methods: make(map[string]MethodHandler),
+		methods:      make(map[string]MethodHandler),
+		maxBatchSize: maxBatchSize,
 	}
 }
 
@@ -32,8 +40,8 @@ func (h *JSONRPCHandler) RegisterMethod(method string, handler MethodHandler) {
 }
 
 // HandleRequest processes a JSON-RPC request and returns a response
-func (h *JSONRPCHandler) HandleRequest(data []byte) []byte {
-	l := log.With().Str("scope", "HandleRequest").Logger()
+func (h *JSONRPCHandler) HandleRequest(ctx context.Context, data []byte) []byte {
+	l := loggerFrom(ctx).With().Str("scope", "HandleRequest").Logger()
 
 	// Log raw request in debug mode
 	if debugMode {
@@ -63,7 +71,7 @@ func (h *JSONRPCHandler) HandleRequest(data []byte) []byte {
 				Str("params", prettyParams).
 				Msg("=== PARSED JSON-RPC REQUEST ===")
 		}
-		return h.handleSingleRequest(&req)
+		return h.handleSingleRequest(ctx, &req)
 	}
 
 	// Try to parse as batch request
@@ -72,7 +80,7 @@ func (h *JSONRPCHandler) HandleRequest(data []byte) []byte {
 		if debugMode {
 			l.Debug().RawJSON("request", data).Msg("Handling batch request")
 		}
-		return h.handleBatchRequest(batch)
+		return h.handleBatchRequest(ctx, batch)
 	}
 
 	// Invalid JSON
@@ -80,14 +88,26 @@ func (h *JSONRPCHandler) HandleRequest(data []byte) []byte {
 }
 
 // handleSingleRequest processes a single JSON-RPC request
-func (h *JSONRPCHandler) handleSingleRequest(req *JSONRPCRequest) []byte {
-	l := log.With().Str("scope", "handleSingleRequest").Str("method", req.Method).Logger()
+func (h *JSONRPCHandler) handleSingleRequest(ctx context.Context, req *JSONRPCRequest) []byte {
+	l := loggerFrom(ctx).With().Str("scope", "handleSingleRequest").Str("method", req.Method).Logger()
+
+	ctx, span := tracer.Start(ctx, "jsonrpc.request")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.method", req.Method),
+	)
 
 	// Validate JSON-RPC version
 	if req.JSONRPC != "2.0" {
 		return h.createErrorResponse(req.ID, InvalidRequest, "Invalid Request", "JSON-RPC version must be 2.0")
 	}
 
+	// A missing method makes the message malformed, not a call to an unknown method
+	if req.Method == "" {
+		return h.createErrorResponse(req.ID, InvalidRequest, "Invalid Request", "method is required")
+	}
+
 	// Check if it's a notification (no ID)
 	isNotification := req.ID == nil
 
@@ -105,8 +125,11 @@ func (h *JSONRPCHandler) handleSingleRequest(req *JSONRPCRequest) []byte {
 	}
 
 	// Execute method
-	result, err := handler(req.Params)
+	result, err := handler(ctx, req.Params)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
 		if isNotification {
 			l.Error().Err(err).Msg("Error in notification handler")
 			return nil
@@ -143,15 +166,30 @@ func (h *JSONRPCHandler) handleSingleRequest(req *JSONRPCRequest) []byte {
 	return respData
 }
 
-// handleBatchRequest processes a batch of JSON-RPC requests
-func (h *JSONRPCHandler) handleBatchRequest(batch []JSONRPCRequest) []byte {
+// handleBatchRequest processes a batch of JSON-RPC requests in order. Once ctx ends,
+// because the client disconnected (see watchDisconnect) or the server is shutting
+// down, the remaining items are skipped and only the responses completed so far are
+// returned. The item running at that moment sees the same cancellation, so its
+// database query is aborted as well.
+func (h *JSONRPCHandler) handleBatchRequest(ctx context.Context, batch []JSONRPCRequest) []byte {
 	if len(batch) == 0 {
 		return h.createErrorResponse(nil, InvalidRequest, "Invalid Request", "Batch cannot be empty")
 	}
+	if h.maxBatchSize > 0 && len(batch) > h.maxBatchSize {
+		return h.createErrorResponse(nil, InvalidRequest, "Invalid Request",
+			fmt.Sprintf("Batch of %d messages exceeds the maximum of %d", len(batch), h.maxBatchSize))
+	}
 
 	var responses []json.RawMessage
-	for _, req := range batch {
-		if resp := h.handleSingleRequest(&req); resp != nil {
+	for i, req := range batch {
+		if err := ctx.Err(); err != nil {
+			loggerFrom(ctx).Warn().Err(err).
+				Int("completed", i).
+				Int("skipped", len(batch)-i).
+				Msg("Batch cancelled, skipping remaining requests")
+			break
+		}
+		if resp := h.handleSingleRequest(ctx, &req); resp != nil {
 			responses = append(responses, resp)
 		}
 	}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHandleBatchRequestStopsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls []string
+	h := NewJSONRPCHandler(0)
+	h.RegisterMethod("work", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		calls = append(calls, "work")
+		return "done", nil
+	})
+	h.RegisterMethod("disconnect", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		calls = append(calls, "disconnect")
+		cancel()
+		return "done", nil
+	})
+
+	resp := h.HandleRequest(ctx, []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"work"},
+		{"jsonrpc":"2.0","id":2,"method":"disconnect"},
+		{"jsonrpc":"2.0","id":3,"method":"work"},
+		{"jsonrpc":"2.0","id":4,"method":"work"}
+	]`))
+
+	var responses []JSONRPCResponse
+	if err := json.Unmarshal(resp, &responses); err != nil {
+		t.Fatalf("response %s: %v", resp, err)
+	}
+	if len(responses) != 2 || string(responses[0].ID) != "1" || string(responses[1].ID) != "2" {
+		t.Errorf("responses = %s, want only ids 1 and 2", resp)
+	}
+	if len(calls) != 2 {
+		t.Errorf("calls = %v, want the items after the cancellation skipped", calls)
+	}
+}
+
+func TestHandleBatchRequestSizeLimit(t *testing.T) {
+	batchOf := func(n int) []byte {
+		items := make([]string, n)
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"ping"}`, i+1)
+		}
+		return []byte("[" + strings.Join(items, ",") + "]")
+	}
+
+	tests := []struct {
+		name      string
+		maxBatch  int
+		body      []byte
+		responses int
+		wantError string
+	}{
+		{name: "at the limit", maxBatch: 3, body: batchOf(3), responses: 3},
+		{name: "under the limit", maxBatch: 3, body: batchOf(1), responses: 1},
+		{name: "over the limit", maxBatch: 3, body: batchOf(4), wantError: "Batch of 4 messages exceeds the maximum of 3"},
+		{name: "no limit", maxBatch: 0, body: batchOf(50), responses: 50},
+		{name: "empty batch", maxBatch: 3, body: []byte(`[]`), wantError: "Batch cannot be empty"},
+		{name: "empty batch without a limit", maxBatch: 0, body: []byte(` [ ] `), wantError: "Batch cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewJSONRPCHandler(tt.maxBatch)
+			calls := 0
+			h.RegisterMethod("ping", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+				calls++
+				return struct{}{}, nil
+			})
+
+			resp := h.HandleRequest(context.Background(), tt.body)
+			if tt.wantError != "" {
+				var errResp JSONRPCResponse
+				if err := json.Unmarshal(resp, &errResp); err != nil {
+					t.Fatalf("response %s: %v", resp, err)
+				}
+				if errResp.Error == nil || errResp.Error.Code != InvalidRequest || errResp.Error.Data != tt.wantError {
+					t.Errorf("response = %s, want Invalid Request %q", resp, tt.wantError)
+				}
+				if calls != 0 {
+					t.Errorf("%d methods ran, want the batch rejected before any", calls)
+				}
+				return
+			}
+
+			var responses []JSONRPCResponse
+			if err := json.Unmarshal(resp, &responses); err != nil {
+				t.Fatalf("response %s: %v", resp, err)
+			}
+			if len(responses) != tt.responses || calls != tt.responses {
+				t.Errorf("got %d responses from %d calls, want %d", len(responses), calls, tt.responses)
+			}
+		})
+	}
+}
+
+func TestHandleRequestEmptyMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		wantID string
+	}{
+		{name: "empty method", body: `{"jsonrpc":"2.0","id":7,"method":""}`, wantID: "7"},
+		{name: "missing method", body: `{"jsonrpc":"2.0","id":"a"}`, wantID: `"a"`},
+		{name: "empty method without id", body: `{"jsonrpc":"2.0","method":""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewJSONRPCHandler(0)
+			// A handler registered under "" must not be reachable
+			h.RegisterMethod("", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+				t.Error("handler for the empty method ran")
+				return nil, nil
+			})
+
+			resp := h.HandleRequest(context.Background(), []byte(tt.body))
+			var got JSONRPCResponse
+			if err := json.Unmarshal(resp, &got); err != nil {
+				t.Fatalf("response %s: %v", resp, err)
+			}
+			if got.Error == nil || got.Error.Code != InvalidRequest || got.Error.Data != "method is required" {
+				t.Errorf("response = %s, want Invalid Request: method is required", resp)
+			}
+			if string(got.ID) != tt.wantID {
+				t.Errorf("id = %s, want %q", got.ID, tt.wantID)
+			}
+		})
+	}
+
+	// In a batch the malformed item gets its own error and the others still run
+	h := NewJSONRPCHandler(0)
+	h.RegisterMethod("ping", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return struct{}{}, nil
+	})
+	resp := h.HandleRequest(context.Background(), []byte(`[{"jsonrpc":"2.0","id":1,"method":""},{"jsonrpc":"2.0","id":2,"method":"ping"}]`))
+	var responses []JSONRPCResponse
+	if err := json.Unmarshal(resp, &responses); err != nil {
+		t.Fatalf("response %s: %v", resp, err)
+	}
+	if len(responses) != 2 || responses[0].Error == nil || responses[0].Error.Code != InvalidRequest || responses[1].Error != nil {
+		t.Errorf("batch responses = %s, want Invalid Request for id 1 and a result for id 2", resp)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// lintSuggestedLimit is the LIMIT added to the suggested rewrite of an unbounded query
+	lintSuggestedLimit = 100
+
+	// lintWideTableColumns is the column count above which SELECT * is reported
+	lintWideTableColumns = 20
+)
+
+// LintWarning is one finding of a static query check
+type LintWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// QueryLint is the result of linting a query. SuggestedQuery is set when adding a
+// LIMIT would address a warning.
+type QueryLint struct {
+	Warnings       []LintWarning `json:"warnings"`
+	SuggestedQuery string        `json:"suggested_query,omitempty"`
+}
+
+// lintAggregates are functions that collapse a result to one row without GROUP BY
+var lintAggregates = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+	"bool_and": true, "bool_or": true, "every": true, "array_agg": true,
+	"string_agg": true, "json_agg": true, "jsonb_agg": true,
+}
+
+// lintClauseEnds are the keywords that end a FROM clause at the top level
+var lintClauseEnds = map[string]bool{
+	"where": true, "group": true, "having": true, "window": true, "order": true,
+	"limit": true, "offset": true, "fetch": true, "for": true,
+	"union": true, "intersect": true, "except": true,
+}
+
+// lintTableRef is a table named in the top-level FROM clause
+type lintTableRef struct {
+	schema string
+	table  string
+}
+
+// lintQuery statically checks a SELECT for missing or unpaired LIMIT and ORDER BY,
+// SELECT * on wide tables, and joins that may produce a cartesian product. Only the
+// outermost query is checked; subqueries and CTE bodies are skipped. columnCount
+// returns the number of columns of a table, or false when it is unknown.
+func lintQuery(query string, columnCount func(schema, table string) (int, bool)) *QueryLint {
+	tokens := tokenizeDDL(query)
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	var (
+		hasLimit, hasOrder, hasGroup, hasWhere, hasAggregate bool
+		selectStar, crossJoin                                bool
+		inSelectList, inFrom                                 bool
+		fromItems                                            int
+		tables                                               []lintTableRef
+		depth                                                int
+	)
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.text {
+		case "(":
+			depth++
+			continue
+		case ")":
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+
+		next := func(keyword string) bool {
+			return i+1 < len(tokens) && tokens[i+1].is(keyword)
+		}
+
+		switch {
+		case t.is("select"):
+			inSelectList, inFrom = true, false
+		case t.is("from") && inSelectList:
+			inSelectList, inFrom = false, true
+			fromItems = 1
+			if ref, ok := lintTableAt(tokens, i+1); ok {
+				tables = append(tables, ref)
+			}
+		case t.is("limit") || (t.is("fetch") && (next("first") || next("next"))):
+			hasLimit = true
+			inFrom = false
+		case t.is("order") && next("by"):
+			hasOrder = true
+			inFrom = false
+		case t.is("group") && next("by"):
+			hasGroup = true
+			inFrom = false
+		case t.is("where"):
+			hasWhere = true
+			inFrom = false
+		case !t.quoted && lintClauseEnds[strings.ToLower(t.text)]:
+			inSelectList, inFrom = false, false
+
+		case inSelectList:
+			if t.text == "*" && i > 0 && (tokens[i-1].is("select") || tokens[i-1].text == "," || tokens[i-1].text == "." || tokens[i-1].is("distinct")) {
+				selectStar = true
+			}
+			if !t.quoted && lintAggregates[strings.ToLower(t.text)] && i+1 < len(tokens) && tokens[i+1].text == "(" {
+				hasAggregate = true
+			}
+
+		case inFrom:
+			switch {
+			case t.text == ",":
+				fromItems++
+				if ref, ok := lintTableAt(tokens, i+1); ok {
+					tables = append(tables, ref)
+				}
+			case t.is("cross") && next("join"):
+				crossJoin = true
+			case t.is("join"):
+				if ref, ok := lintTableAt(tokens, i+1); ok {
+					tables = append(tables, ref)
+				}
+			}
+		}
+	}
+
+	lint := &QueryLint{Warnings: []LintWarning{}}
+	warn := func(code, format string, args ...interface{}) {
+		lint.Warnings = append(lint.Warnings, LintWarning{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	singleRow := hasAggregate && !hasGroup
+	switch {
+	case !hasLimit && hasOrder && !singleRow:
+		warn("order_by_without_limit", "ORDER BY without LIMIT sorts and returns the whole result; add a LIMIT if only the first rows are needed")
+	case !hasLimit && !singleRow:
+		warn("missing_limit", "The query has no LIMIT and may return every row of the table(s); add a LIMIT while exploring")
+	case hasLimit && !hasOrder && !singleRow:
+		warn("limit_without_order_by", "LIMIT without ORDER BY returns an arbitrary subset of rows that can differ between runs; add an ORDER BY for stable results")
+	}
+	if !hasLimit && !singleRow {
+		lint.SuggestedQuery = fmt.Sprintf("%s\nLIMIT %d", strings.TrimRight(strings.TrimSpace(query), ";"), lintSuggestedLimit)
+	}
+
+	if selectStar {
+		for _, ref := range tables {
+			if n, ok := columnCount(ref.schema, ref.table); ok && n > lintWideTableColumns {
+				warn("select_star_wide_table", "SELECT * on %s, which has %d columns; list only the columns you need", ref.table, n)
+			}
+		}
+	}
+
+	if crossJoin {
+		warn("cartesian_join", "CROSS JOIN pairs every row of one table with every row of the other; make sure that is intended")
+	}
+	if fromItems > 1 && !hasWhere {
+		warn("cartesian_join", "Tables listed with commas in FROM but no WHERE clause produce a cartesian product; add join conditions or use JOIN ... ON")
+	}
+
+	return lint
+}
+
+// lintTableAt reads a [schema.]table name starting at tokens[i]. Subqueries,
+// function calls, LATERAL items, and ONLY are not table names.
+func lintTableAt(tokens []ddlToken, i int) (lintTableRef, bool) {
+	if i < len(tokens) && tokens[i].is("only") {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].text == "(" || tokens[i].is("lateral") {
+		return lintTableRef{}, false
+	}
+
+	name := lintIdent(tokens[i])
+	ref := lintTableRef{schema: "public", table: name}
+	if i+2 < len(tokens) && tokens[i+1].text == "." {
+		ref = lintTableRef{schema: name, table: lintIdent(tokens[i+2])}
+		i += 2
+	}
+	if i+1 < len(tokens) && tokens[i+1].text == "(" {
+		return lintTableRef{}, false
+	}
+	return ref, true
+}
+
+// lintIdent returns a token as an identifier, folding unquoted names to lower case
+func lintIdent(t ddlToken) string {
+	if t.quoted {
+		return t.text
+	}
+	return strings.ToLower(t.text)
+}
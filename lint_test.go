@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintQuery(t *testing.T) {
+	// wide has more than lintWideTableColumns columns, narrow fewer; other tables are unknown
+	columnCount := func(schema, table string) (int, bool) {
+		switch schema + "." + table {
+		case "public.wide", "sales.Wide":
+			return lintWideTableColumns + 1, true
+		case "public.narrow":
+			return 3, true
+		}
+		return 0, false
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		want          []string
+		wantSuggested string
+	}{
+		// missing_limit
+		{name: "missing limit", query: "SELECT id FROM narrow;", want: []string{"missing_limit"}, wantSuggested: "SELECT id FROM narrow\nLIMIT 100"},
+		{name: "ordered and limited", query: "SELECT id FROM narrow ORDER BY id LIMIT 10", want: []string{}},
+		{name: "aggregate returns one row", query: "SELECT count(*) FROM narrow", want: []string{}},
+		{name: "limit only in a subquery", query: "SELECT id FROM (SELECT id FROM narrow ORDER BY id LIMIT 5) s", want: []string{"missing_limit"},
+			wantSuggested: "SELECT id FROM (SELECT id FROM narrow ORDER BY id LIMIT 5) s\nLIMIT 100"},
+
+		// order_by_without_limit
+		{name: "order by without limit", query: "SELECT id FROM narrow ORDER BY id", want: []string{"order_by_without_limit"}, wantSuggested: "SELECT id FROM narrow ORDER BY id\nLIMIT 100"},
+		{name: "order by with fetch first", query: "SELECT id FROM narrow ORDER BY id FETCH FIRST 5 ROWS ONLY", want: []string{}},
+
+		// limit_without_order_by
+		{name: "limit without order by", query: "SELECT id FROM narrow LIMIT 10", want: []string{"limit_without_order_by"}},
+		{name: "grouped aggregate with limit and order", query: "SELECT kind, count(*) FROM narrow GROUP BY kind ORDER BY kind LIMIT 10", want: []string{}},
+
+		// select_star_wide_table
+		{name: "select star on a wide table", query: "SELECT * FROM wide ORDER BY id LIMIT 10", want: []string{"select_star_wide_table"}},
+		{name: "select star on a quoted schema-qualified wide table", query: `SELECT w.* FROM "sales"."Wide" w ORDER BY id LIMIT 10`, want: []string{"select_star_wide_table"}},
+		{name: "select star on a narrow table", query: "SELECT * FROM narrow ORDER BY id LIMIT 10", want: []string{}},
+		{name: "columns listed on a wide table", query: "SELECT id, name FROM wide ORDER BY id LIMIT 10", want: []string{}},
+		{name: "count star on a wide table", query: "SELECT count(*) FROM wide", want: []string{}},
+
+		// cartesian_join
+		{name: "cross join", query: "SELECT a.id FROM narrow a CROSS JOIN other b ORDER BY 1 LIMIT 10", want: []string{"cartesian_join"}},
+		{name: "comma join without where", query: "SELECT a.id FROM narrow a, other b ORDER BY 1 LIMIT 10", want: []string{"cartesian_join"}},
+		{name: "comma join with where", query: "SELECT a.id FROM narrow a, other b WHERE a.id = b.id ORDER BY 1 LIMIT 10", want: []string{}},
+		{name: "join on", query: "SELECT a.id FROM narrow a JOIN other b ON a.id = b.id ORDER BY 1 LIMIT 10", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lint := lintQuery(tt.query, columnCount)
+			codes := []string{}
+			for _, w := range lint.Warnings {
+				codes = append(codes, w.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("warnings = %v, want %v", codes, tt.want)
+			}
+			if lint.SuggestedQuery != tt.wantSuggested {
+				t.Errorf("suggested query = %q, want %q", lint.SuggestedQuery, tt.wantSuggested)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -12,39 +13,18 @@ import (
 
 var debugMode bool
 
-// InitLogger initializes the global logger based on environment variables
-func InitLogger() {
-	// Set log level based on LOG_LEVEL env var
-	level := zerolog.InfoLevel
-	levelStr := os.Getenv("LOG_LEVEL")
-	
-	switch strings.ToLower(levelStr) {
-	case "trace":
-		level = zerolog.TraceLevel
-		debugMode = true
-	case "debug":
-		level = zerolog.DebugLevel
-		debugMode = true
-	case "info":
-		level = zerolog.InfoLevel
-		debugMode = false
-	case "warn", "warning":
-		level = zerolog.WarnLevel
-		debugMode = false
-	case "error":
-		level = zerolog.ErrorLevel
-		debugMode = false
-	case "fatal":
-		level = zerolog.FatalLevel
-		debugMode = false
-	case "panic":
-		level = zerolog.PanicLevel
-		debugMode = false
-	default:
-		// Default to info level
-		level = zerolog.InfoLevel
-		debugMode = false
-	}
+// RequestIDHeader carries the correlation ID of an MCP request, in both directions
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// InitLogger initializes the global logger at the given level. It is called
+// once, from LoadConfig, so LOG_LEVEL from a .env file is honored. Unknown
+// levels fall back to info with a warning.
+func InitLogger(levelStr string) {
+	level, ok := parseLogLevel(levelStr)
+	debugMode = level <= zerolog.DebugLevel
 
 	zerolog.SetGlobalLevel(level)
 
@@ -53,7 +33,7 @@ func InitLogger() {
 		Out:        os.Stderr,
 		TimeFormat: "2006-01-02T15:04:05.000Z07:00",
 	}
-	
+
 	// Enable all log levels in console writer
 	output.FormatLevel = func(i interface{}) string {
 		var levelStr string
@@ -73,15 +53,70 @@ func InitLogger() {
 		}
 		return fmt.Sprintf("\x1b[%dm%s\x1b[0m", 90, levelStr)
 	}
-	
+
 	log.Logger = log.Output(output).With().Caller().Logger()
 
+	if !ok {
+		log.Warn().Str("value", levelStr).Msg("Unknown LOG_LEVEL, defaulting to info")
+	}
+
 	log.Info().
 		Str("level", level.String()).
 		Bool("debug_mode", debugMode).
 		Msg("Logger initialized")
 }
 
+// withRequestID attaches a logger carrying the request ID to the context, so that
+// logs written through loggerFrom for this request can be correlated
+func withRequestID(ctx context.Context, requestID string) context.Context {
+	return log.With().Str("request_id", requestID).Logger().WithContext(ctx)
+}
+
+// loggerFrom returns the request-scoped logger attached to the context, or the
+// global logger outside a request
+func loggerFrom(ctx context.Context) *zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		return l
+	}
+	return &log.Logger
+}
+
+// validRequestID reports whether a client-supplied request ID is short and printable
+// enough to be echoed and logged
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLogLevel maps a LOG_LEVEL value to a zerolog level, reporting whether it was recognized
+func parseLogLevel(s string) (zerolog.Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace":
+		return zerolog.TraceLevel, true
+	case "debug":
+		return zerolog.DebugLevel, true
+	case "", "info":
+		return zerolog.InfoLevel, true
+	case "warn", "warning":
+		return zerolog.WarnLevel, true
+	case "error":
+		return zerolog.ErrorLevel, true
+	case "fatal":
+		return zerolog.FatalLevel, true
+	case "panic":
+		return zerolog.PanicLevel, true
+	default:
+		return zerolog.InfoLevel, false
+	}
+}
+
 // IsDebugMode returns whether debug mode is enabled
 func IsDebugMode() bool {
 	return debugMode
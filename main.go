@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,53 +17,75 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// startTime records when the server process started
+var startTime = time.Now()
+
 func main() {
-	// Initialize logger first
-	InitLogger()
-	
-	// Test debug logging
-	log.Debug().Msg("=== DEBUG LOGGING TEST - This should appear if debug is enabled ===")
+	// Load configuration (also initializes the logger)
+	cfg, err := LoadConfig()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
 
 	l := log.With().Str("scope", "main").Logger()
 
-	// Load configuration
-	cfg, err := LoadConfig()
+	// Initialize tracing (no-op unless OTEL_EXPORTER_OTLP_ENDPOINT is set)
+	shutdownTracing, err := InitTracing(context.Background(), cfg)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+		l.Error().Err(err).Msg("Failed to initialize tracing")
+		shutdownTracing = func(context.Context) error { return nil }
 	}
 
 	// Initialize adapter registry
 	adapterRegistry := NewAdapterRegistry()
 
 	// Register database adapters
-	postgresAdapter := NewPostgresAdapter(cfg.PostgresURL)
+	postgresAdapter := NewPostgresAdapter(cfg)
 	if err := adapterRegistry.Register(postgresAdapter); err != nil {
 		l.Error().Err(err).Msg("Failed to register PostgreSQL adapter")
 	}
 
-	mysqlAdapter := NewMySQLAdapter(cfg.MySQLURL)
+	mysqlAdapter := NewMySQLAdapter(cfg)
 	if err := adapterRegistry.Register(mysqlAdapter); err != nil {
 		l.Error().Err(err).Msg("Failed to register MySQL adapter")
 	}
 
+	// Debug-level query logging, with optional literal redaction
+	adapterRegistry.AddAfterQueryHook(NewQueryLogHook(cfg))
+
+	// Per-adapter query counters, reported by /debug/stats
+	queryMetrics := NewQueryMetrics()
+	adapterRegistry.AddAfterQueryHook(queryMetrics.Hook)
+
+	// The allowlist is enforced per session, so without sessions only initialize can succeed
+	if len(cfg.AllowedClients) > 0 && !cfg.UseSession {
+		l.Warn().Msg("ALLOWED_CLIENTS is set without MCP_USE_SESSION; every request other than initialize will be rejected")
+	}
+
 	// Check if at least one adapter is registered
 	if adapterRegistry.IsEmpty() {
 		l.Warn().Msg("No database adapters configured. Only built-in tools will be available.")
 	}
 
 	// Create tool registry and register tools
-	toolRegistry := NewToolRegistry()
-	RegisterTools(toolRegistry, adapterRegistry)
+	toolRegistry := NewToolRegistry(cfg)
+	RegisterTools(toolRegistry, adapterRegistry, cfg)
+
+	// Admin schema export, only available when an API key is configured
+	var exporter *SchemaExporter
+	if cfg.APIKey != "" {
+		exporter = NewSchemaExporter(adapterRegistry, cfg)
+		exporter.RegisterTools(toolRegistry)
+	}
 
 	// Create JSON-RPC handler
-	rpcHandler := NewJSONRPCHandler()
+	rpcHandler := NewJSONRPCHandler(cfg.MaxBatchSize)
 
 	// Register MCP methods
-	registerMCPMethods(rpcHandler, toolRegistry)
+	registerMCPMethods(rpcHandler, toolRegistry, NewMetadataResources(cfg, adapterRegistry), cfg)
 
 	// Create MCP transport
-	useSession := os.Getenv("MCP_USE_SESSION") == "true"
-	transport := NewMCPTransport(rpcHandler, useSession)
+	transport := NewMCPTransport(rpcHandler, cfg, queryMetrics, adapterRegistry)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -74,14 +97,16 @@ func main() {
 		IdleTimeout:           120 * time.Second,
 		JSONEncoder:           json.Marshal,
 		JSONDecoder:           json.Unmarshal,
+		ErrorHandler:          ErrorHandler,
 	})
 
 	// Middleware
 	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization, Mcp-Session-Id",
-		AllowMethods: "GET, POST, OPTIONS",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Mcp-Session-Id, X-Signature, X-Request-Id",
+		AllowMethods:  "GET, POST, OPTIONS",
+		ExposeHeaders: "Mcp-Session-Id, X-Request-Id",
 	}))
 
 	// Conditional request logging
@@ -93,6 +118,9 @@ func main() {
 
 	// Setup routes
 	transport.SetupRoutes(app)
+	if exporter != nil {
+		exporter.SetupRoutes(app)
+	}
 
 	// Graceful shutdown
 	c := make(chan os.Signal, 1)
@@ -102,18 +130,9 @@ func main() {
 		<-c
 		l.Info().Msg("Gracefully shutting down...")
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
-
-		// Close database connections
-		if err := adapterRegistry.Close(); err != nil {
-			l.Error().Err(err).Msg("Error closing database connections")
-		}
-
-		// Shutdown Fiber
-		if err := app.ShutdownWithContext(ctx); err != nil {
-			l.Error().Err(err).Msg("Error shutting down server")
-		}
+		shutdown(ctx, app, adapterRegistry, shutdownTracing)
 	}()
 
 	// Start server
@@ -122,7 +141,9 @@ func main() {
 		Str("address", addr).
 		Strs("adapters", adapterRegistry.List()).
 		Int("tools", len(toolRegistry.ListTools())).
-		Bool("session_management", useSession).
+		Int("tools_skipped", toolRegistry.SkippedTools()).
+		Int("max_tools", cfg.MaxTools).
+		Bool("session_management", cfg.UseSession).
 		Msg("Starting MCP Storage Server")
 
 	if err := app.Listen(addr); err != nil {
@@ -130,12 +151,38 @@ func main() {
 	}
 }
 
+// shutdownTimeout bounds how long shutdown waits for in-flight requests to drain
+const shutdownTimeout = 10 * time.Second
+
+// shutdown stops the server. Fiber goes first so in-flight requests drain, bounded
+// by ctx, while their database connections are still open; the adapters are closed
+// next, and tracing last so the spans of the drained requests are flushed. There are
+// no long-lived push streams (SSE/WebSocket) to notify; every response is a single
+// HTTP reply, and session notifications are pulled from GET /notifications.
+func shutdown(ctx context.Context, app *fiber.App, adapters *AdapterRegistry, shutdownTracing func(context.Context) error) {
+	l := log.With().Str("scope", "shutdown").Logger()
+
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		l.Error().Err(err).Msg("Error shutting down server")
+	}
+
+	// Close database connections
+	if err := adapters.Close(); err != nil {
+		l.Error().Err(err).Msg("Error closing database connections")
+	}
+
+	// Flush pending spans
+	if err := shutdownTracing(ctx); err != nil {
+		l.Error().Err(err).Msg("Error shutting down tracing")
+	}
+}
+
 // registerMCPMethods registers all MCP protocol methods
-func registerMCPMethods(handler *JSONRPCHandler, toolRegistry *ToolRegistry) {
+func registerMCPMethods(handler *JSONRPCHandler, toolRegistry *ToolRegistry, resources *MetadataResources, cfg *Config) {
 	l := log.With().Str("scope", "registerMCPMethods").Logger()
 
 	// Initialize method
-	handler.RegisterMethod("initialize", func(params json.RawMessage) (interface{}, error) {
+	handler.RegisterMethod("initialize", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
 		var req InitializeParams
 		if err := json.Unmarshal(params, &req); err != nil {
 			l.Error().Err(err).Str("params", string(params)).Msg("Failed to parse initialize params")
@@ -151,14 +198,29 @@ func registerMCPMethods(handler *JSONRPCHandler, toolRegistry *ToolRegistry) {
 			Interface("capabilities", req.Capabilities).
 			Msg("=== INITIALIZE REQUEST DETAILS ===")
 
-		// Validate protocol version
+		// Reject clients not in ALLOWED_CLIENTS. Sessions are only created for a
+		// successful initialize, so a rejected client never gets one.
+		if !clientAllowed(cfg.AllowedClients, req.ClientInfo.Name) {
+			l.Warn().
+				Str("client_name", req.ClientInfo.Name).
+				Str("client_version", req.ClientInfo.Version).
+				Msg("Rejected client not in ALLOWED_CLIENTS")
+			return nil, NewRPCError(InvalidRequest, "Client not allowed",
+				fmt.Sprintf("Client %q is not allowed to connect to this server", req.ClientInfo.Name))
+		}
+
+		// Validate protocol version. In non-strict mode a mismatch is only logged and
+		// the server's version is returned for the client to decide.
 		if req.ProtocolVersion != ProtocolVersion {
 			l.Warn().
 				Str("client_protocol_version", req.ProtocolVersion).
 				Str("server_protocol_version", ProtocolVersion).
+				Bool("strict", cfg.StrictProtocolVersion).
 				Msg("Protocol version mismatch")
-			return nil, NewRPCError(InvalidParams, "Unsupported protocol version",
-				fmt.Sprintf("Server supports %s, client requested %s", ProtocolVersion, req.ProtocolVersion))
+			if cfg.StrictProtocolVersion {
+				return nil, NewRPCError(InvalidParams, "Unsupported protocol version",
+					fmt.Sprintf("Server supports %s, client requested %s", ProtocolVersion, req.ProtocolVersion))
+			}
 		}
 
 		// Build server capabilities
@@ -167,13 +229,19 @@ func registerMCPMethods(handler *JSONRPCHandler, toolRegistry *ToolRegistry) {
 				ListChanged: false,
 			},
 		}
+		if !resources.IsEmpty() {
+			capabilities.Resources = &ResourcesCapability{}
+		}
+		if cfg.UseSession {
+			capabilities.Logging = &LoggingCapability{}
+		}
 
 		result := InitializeResult{
 			ProtocolVersion: ProtocolVersion,
 			Capabilities:    capabilities,
 			ServerInfo: ServerInfo{
-				Name:    "MCP Storage Server",
-				Version: "1.0.0",
+				Name:    ServerName,
+				Version: ServerVersion,
 			},
 		}
 
@@ -186,29 +254,70 @@ func registerMCPMethods(handler *JSONRPCHandler, toolRegistry *ToolRegistry) {
 	})
 
 	// Initialized notification
-	handler.RegisterMethod("notifications/initialized", func(params json.RawMessage) (interface{}, error) {
+	handler.RegisterMethod("notifications/initialized", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
 		l.Debug().Msg("Client initialized notification received")
 		return nil, nil
 	})
 
+	// Roots changed notification, accepted and ignored. Roots are not supported:
+	// fetching them requires sending a roots/list request to the client, which the
+	// HTTP-only transport cannot do, so roots never affect which schemas are visible.
+	handler.RegisterMethod("notifications/roots/list_changed", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		l.Debug().Msg("Roots list changed notification received; roots filtering is not supported over HTTP transport")
+		return nil, nil
+	})
+
+	// Ping. The HTTP-only transport has no push stream to warn clients before an
+	// idle session expires, and a warning queued for GET /notifications is only
+	// seen on the client's next request, which already refreshes the session.
+	// Clients keep sessions alive by pinging instead.
+	handler.RegisterMethod("ping", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return struct{}{}, nil
+	})
+
+	// Logging level, stored per session so one client's level never changes
+	// another's or the server's own stderr logging
+	handler.RegisterMethod("logging/setLevel", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req SetLevelParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, NewRPCError(InvalidParams, "Invalid parameters", err.Error())
+		}
+		if _, ok := logLevelSeverity[req.Level]; !ok {
+			return nil, NewRPCError(InvalidParams, "Invalid log level", string(req.Level))
+		}
+
+		session := sessionFrom(ctx)
+		if session == nil {
+			return nil, NewRPCError(InvalidRequest, "logging/setLevel requires a session", "enable MCP_USE_SESSION and send Mcp-Session-Id")
+		}
+		session.SetLogLevel(req.Level)
+
+		l.Debug().Str("session_id", session.ID).Str("level", string(req.Level)).Msg("Session log level set")
+		return struct{}{}, nil
+	})
+
 	// Tools list method
-	handler.RegisterMethod("tools/list", func(params json.RawMessage) (interface{}, error) {
+	handler.RegisterMethod("tools/list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
 		tools := toolRegistry.ListTools()
 		return ListToolsResult{Tools: tools}, nil
 	})
 
 	// Tools call method
-	handler.RegisterMethod("tools/call", func(params json.RawMessage) (interface{}, error) {
+	handler.RegisterMethod("tools/call", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
 		var req CallToolParams
 		if err := json.Unmarshal(params, &req); err != nil {
 			return nil, NewRPCError(InvalidParams, "Invalid parameters", err.Error())
 		}
 
-		ctx := context.Background()
+		// Report every schema problem at once so the client can fix them in one retry
+		if errs := toolRegistry.ArgumentErrors(req.Name, req.Arguments); len(errs) > 0 {
+			return nil, NewRPCError(InvalidParams, "Invalid tool arguments", errs)
+		}
+
 		result, err := toolRegistry.CallTool(ctx, req.Name, req.Arguments)
 		if err != nil {
 			// Return error as tool result
-			return &CallToolResult{
+			result = &CallToolResult{
 				Content: []Content{
 					TextContent{
 						Type: "text",
@@ -216,11 +325,82 @@ func registerMCPMethods(handler *JSONRPCHandler, toolRegistry *ToolRegistry) {
 					},
 				},
 				IsError: true,
-			}, nil
+			}
+		}
+
+		// A tool that returns no result still answers with the CallToolResult shape,
+		// since clients reject a null result or null content
+		if result == nil {
+			result = &CallToolResult{}
+		}
+		if result.Content == nil {
+			result.Content = []Content{}
+		}
+
+		// Echo the request's _meta (e.g. a correlation or progress token) so the
+		// client can match the result; keys set by the tool take precedence
+		if len(req.Meta) > 0 {
+			if result.Meta == nil {
+				result.Meta = make(map[string]interface{}, len(req.Meta))
+			}
+			for k, v := range req.Meta {
+				if _, ok := result.Meta[k]; !ok {
+					result.Meta[k] = v
+				}
+			}
 		}
 
 		return result, nil
 	})
 
+	// Tools validate method (non-standard): checks arguments without executing the tool
+	handler.RegisterMethod("tools/validate", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var req CallToolParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, NewRPCError(InvalidParams, "Invalid parameters", err.Error())
+		}
+
+		result, err := toolRegistry.ValidateTool(req.Name, req.Arguments)
+		if err != nil {
+			return nil, NewRPCError(InvalidParams, "Unknown tool", err.Error())
+		}
+
+		return result, nil
+	})
+
+	// Metadata resources, only advertised when METADATA_RESOURCES exposes some
+	if !resources.IsEmpty() {
+		handler.RegisterMethod("resources/list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return ListResourcesResult{Resources: resources.List()}, nil
+		})
+
+		handler.RegisterMethod("resources/read", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var req ReadResourceParams
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, NewRPCError(InvalidParams, "Invalid parameters", err.Error())
+			}
+
+			if cfg.ToolTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, cfg.ToolTimeout)
+				defer cancel()
+			}
+			return resources.Read(ctx, req.URI)
+		})
+	}
+
 	l.Info().Msg("MCP methods registered")
 }
+
+// clientAllowed reports whether a client name is in the allowlist; an empty allowlist allows every client
+func clientAllowed(allowed []string, name string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
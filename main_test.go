@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestToolsCallEmptyResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *CallToolResult
+		params string
+		want   string
+	}{
+		{name: "nil result", result: nil, params: `{"name":"quiet"}`, want: `{"content":[]}`},
+		{name: "nil content", result: &CallToolResult{}, params: `{"name":"quiet"}`, want: `{"content":[]}`},
+		{name: "nil result with meta", result: nil, params: `{"name":"quiet","_meta":{"progressToken":"p1"}}`, want: `{"content":[],"_meta":{"progressToken":"p1"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			tools := NewToolRegistry(cfg)
+			tools.RegisterTool(Tool{Name: "quiet", InputSchema: InputSchema{Type: "object"}},
+				func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+					return tt.result, nil
+				})
+			handler := NewJSONRPCHandler(0)
+			registerMCPMethods(handler, tools, NewMetadataResources(cfg, NewAdapterRegistry()), cfg)
+
+			body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":` + tt.params + `}`
+			resp := handler.HandleRequest(context.Background(), []byte(body))
+
+			var got struct {
+				Result json.RawMessage `json:"result"`
+				Error  *JSONRPCError   `json:"error"`
+			}
+			if err := json.Unmarshal(resp, &got); err != nil {
+				t.Fatalf("response %s: %v", resp, err)
+			}
+			if got.Error != nil || string(got.Result) != tt.want {
+				t.Errorf("response = %s, want result %s", resp, tt.want)
+			}
+		})
+	}
+}
+
+// closeRecordingAdapter calls onClose when it is closed
+type closeRecordingAdapter struct {
+	recordingAdapter
+	onClose func()
+}
+
+func (a *closeRecordingAdapter) Close() error {
+	a.onClose()
+	return nil
+}
+
+func TestShutdownOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		release bool
+		want    []string
+	}{
+		{name: "in-flight request drains first", timeout: 5 * time.Second, release: true, want: []string{"request done", "adapters closed", "tracing flushed"}},
+		{name: "stuck request is bounded by the timeout", timeout: 100 * time.Millisecond, want: []string{"adapters closed", "tracing flushed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				events []string
+			)
+			record := func(event string) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			}
+			recorded := func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string(nil), events...)
+			}
+
+			adapters := NewAdapterRegistry()
+			adapters.Register(&closeRecordingAdapter{recordingAdapter: recordingAdapter{name: "postgres"}, onClose: func() { record("adapters closed") }})
+
+			entered, release := make(chan struct{}), make(chan struct{})
+			app := fiber.New(fiber.Config{DisableStartupMessage: true})
+			app.Get("/slow", func(c *fiber.Ctx) error {
+				close(entered)
+				<-release
+				record("request done")
+				return c.SendString("ok")
+			})
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go app.Listener(ln)
+
+			status := make(chan int, 1)
+			go func() {
+				resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+				if err != nil {
+					status <- 0
+					return
+				}
+				resp.Body.Close()
+				status <- resp.StatusCode
+			}()
+			<-entered
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			done := make(chan struct{})
+			go func() {
+				shutdown(ctx, app, adapters, func(context.Context) error {
+					record("tracing flushed")
+					return nil
+				})
+				close(done)
+			}()
+
+			if tt.release {
+				time.Sleep(50 * time.Millisecond)
+				if got := recorded(); len(got) != 0 {
+					t.Errorf("%v before the in-flight request finished", got)
+				}
+				close(release)
+				if got := <-status; got != http.StatusOK {
+					t.Errorf("in-flight request got status %d, want 200", got)
+				}
+			}
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("shutdown did not return")
+			}
+			if got := recorded(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shutdown events = %v, want %v", got, tt.want)
+			}
+			if !tt.release {
+				close(release)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// metricKeywords are the statement keywords counted individually; anything else
+// is counted as OTHER so user-written queries cannot grow the label set
+var metricKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"CALL":     true,
+	"EXPLAIN":  true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"DESCRIBE": true,
+}
+
+// rowBuckets are the upper bounds of the rows-returned buckets; larger results
+// fall into the last, open-ended bucket
+var rowBuckets = []struct {
+	max   int
+	label string
+}{
+	{0, "0"},
+	{1, "1"},
+	{10, "2-10"},
+	{100, "11-100"},
+	{1000, "101-1000"},
+	{10000, "1001-10000"},
+}
+
+// AdapterQueryStats counts the queries run on one adapter
+type AdapterQueryStats struct {
+	Queries      map[string]int64 `json:"queries"`
+	Errors       map[string]int64 `json:"errors"`
+	RowsReturned map[string]int64 `json:"rows_returned"`
+}
+
+// QueryMetrics counts queries per adapter by statement keyword, errors by
+// SQLSTATE or MySQL error number, and the distribution of rows returned
+type QueryMetrics struct {
+	mu       sync.Mutex
+	adapters map[string]*AdapterQueryStats
+}
+
+// NewQueryMetrics creates an empty query metrics collector
+func NewQueryMetrics() *QueryMetrics {
+	return &QueryMetrics{
+		adapters: make(map[string]*AdapterQueryStats),
+	}
+}
+
+// Hook records a query; register it with AdapterRegistry.AddAfterQueryHook
+func (m *QueryMetrics) Hook(ctx context.Context, adapter DatabaseAdapter, query string, result QueryResult, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.adapters[adapter.Name()]
+	if !ok {
+		stats = &AdapterQueryStats{
+			Queries:      make(map[string]int64),
+			Errors:       make(map[string]int64),
+			RowsReturned: make(map[string]int64),
+		}
+		m.adapters[adapter.Name()] = stats
+	}
+
+	stats.Queries[queryKeyword(query)]++
+	if err != nil {
+		stats.Errors[errorCode(err)]++
+		return
+	}
+	stats.RowsReturned[rowBucket(len(result.Rows))]++
+}
+
+// Snapshot returns a copy of the current counters keyed by adapter name
+func (m *QueryMetrics) Snapshot() map[string]AdapterQueryStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]AdapterQueryStats, len(m.adapters))
+	for name, stats := range m.adapters {
+		snapshot[name] = AdapterQueryStats{
+			Queries:      copyCounts(stats.Queries),
+			Errors:       copyCounts(stats.Errors),
+			RowsReturned: copyCounts(stats.RowsReturned),
+		}
+	}
+	return snapshot
+}
+
+// queryKeyword returns the upper-cased first keyword of query, or OTHER
+func queryKeyword(query string) string {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	end := strings.IndexFunc(query, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end >= 0 {
+		query = query[:end]
+	}
+
+	keyword := strings.ToUpper(query)
+	if !metricKeywords[keyword] {
+		return "OTHER"
+	}
+	return keyword
+}
+
+// errorCode classifies a query error by SQLSTATE (PostgreSQL), error number
+// (MySQL), or context cancellation
+func errorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return strconv.Itoa(int(mysqlErr.Number))
+	}
+
+	switch {
+	case errors.Is(err, ErrPoolExhausted):
+		return "pool_exhausted"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "other"
+}
+
+// rowBucket returns the label of the rows-returned bucket holding n
+func rowBucket(n int) string {
+	for _, b := range rowBuckets {
+		if n <= b.max {
+			return b.label
+		}
+	}
+	return "10001+"
+}
+
+// copyCounts returns a copy of counts
+func copyCounts(counts map[string]int64) map[string]int64 {
+	c := make(map[string]int64, len(counts))
+	for k, v := range counts {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// rowsAdapter returns n rows for every query
+type rowsAdapter struct {
+	recordingAdapter
+	n int
+}
+
+func (a *rowsAdapter) ExecuteSelect(ctx context.Context, query string) (QueryResult, error) {
+	a.recordingAdapter.ExecuteSelect(ctx, query)
+	return QueryResult{Columns: []string{"n"}, Rows: make([][]interface{}, a.n)}, nil
+}
+
+func TestQueryMetricsHook(t *testing.T) {
+	metrics := NewQueryMetrics()
+	registry := NewAdapterRegistry()
+	registry.AddAfterQueryHook(metrics.Hook)
+
+	postgres := &recordingAdapter{name: "postgres"}
+	denied := &failingAdapter{recordingAdapter: recordingAdapter{name: "postgres"}, err: &pq.Error{Code: "42501"}}
+	missing := &failingAdapter{recordingAdapter: recordingAdapter{name: "postgres"}, err: fmt.Errorf("query failed: %w", &pq.Error{Code: "42P01"})}
+	mysqlDenied := &failingAdapter{recordingAdapter: recordingAdapter{name: "mysql"}, err: &mysql.MySQLError{Number: 1142}}
+	canceled := &failingAdapter{recordingAdapter: recordingAdapter{name: "mysql"}, err: context.Canceled}
+
+	runs := []struct {
+		adapter DatabaseAdapter
+		query   string
+	}{
+		{postgres, "SELECT 1"},
+		{postgres, "  (select 2)"},
+		{postgres, "WITH x AS (SELECT 1) SELECT * FROM x"},
+		{postgres, "VACUUM"},
+		{&rowsAdapter{recordingAdapter: recordingAdapter{name: "postgres"}, n: 50}, "SELECT * FROM t"},
+		{denied, "SELECT * FROM secret"},
+		{denied, "SELECT * FROM secret2"},
+		{missing, "SELECT * FROM nope"},
+		{mysqlDenied, "SHOW CREATE TABLE t"},
+		{canceled, "SELECT SLEEP(10)"},
+	}
+	for _, run := range runs {
+		registry.ExecuteSelect(context.Background(), run.adapter, run.query)
+	}
+
+	want := map[string]AdapterQueryStats{
+		"postgres": {
+			Queries:      map[string]int64{"SELECT": 6, "WITH": 1, "OTHER": 1},
+			Errors:       map[string]int64{"42501": 2, "42P01": 1},
+			RowsReturned: map[string]int64{"0": 4, "11-100": 1},
+		},
+		"mysql": {
+			Queries:      map[string]int64{"SHOW": 1, "SELECT": 1},
+			Errors:       map[string]int64{"1142": 1, "canceled": 1},
+			RowsReturned: map[string]int64{},
+		},
+	}
+	if got := metrics.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+
+	// Snapshot is a copy
+	metrics.Snapshot()["postgres"].Queries["SELECT"] = 100
+	if got := metrics.Snapshot()["postgres"].Queries["SELECT"]; got != 6 {
+		t.Errorf("modifying a snapshot changed the counters: SELECT = %d", got)
+	}
+}
+
+func TestQueryMetricsClassification(t *testing.T) {
+	keywords := map[string]string{
+		"select 1":            "SELECT",
+		"\n\t((SELECT 1))":    "SELECT",
+		"Explain SELECT 1":    "EXPLAIN",
+		"DESCRIBE t":          "DESCRIBE",
+		"DELETE FROM t":       "OTHER",
+		"SELECTED":            "OTHER",
+		"":                    "OTHER",
+		"/* hint */ SELECT 1": "OTHER",
+	}
+	for query, want := range keywords {
+		if got := queryKeyword(query); got != want {
+			t.Errorf("queryKeyword(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	codes := []struct {
+		err  error
+		want string
+	}{
+		{err: &pq.Error{Code: "57014"}, want: "57014"},
+		{err: &mysql.MySQLError{Number: 1064}, want: "1064"},
+		{err: fmt.Errorf("acquire: %w", ErrPoolExhausted), want: "pool_exhausted"},
+		{err: context.DeadlineExceeded, want: "deadline_exceeded"},
+		{err: context.Canceled, want: "canceled"},
+		{err: errors.New("boom"), want: "other"},
+	}
+	for _, tt := range codes {
+		if got := errorCode(tt.err); got != tt.want {
+			t.Errorf("errorCode(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+
+	buckets := map[int]string{0: "0", 1: "1", 2: "2-10", 10: "2-10", 11: "11-100", 1000: "101-1000", 10000: "1001-10000", 10001: "10001+"}
+	for n, want := range buckets {
+		if got := rowBucket(n); got != want {
+			t.Errorf("rowBucket(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
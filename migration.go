@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// transactionKeywords start statements that would end or nest the migration's transaction
+var transactionKeywords = []string{"begin", "start", "commit", "end", "rollback", "abort", "savepoint", "release"}
+
+// MigrationResult reports how each statement of a migration script ran. Either
+// every statement is committed or none is.
+type MigrationResult struct {
+	Committed  bool                 `json:"committed"`
+	Statements []MigrationStatement `json:"statements"`
+}
+
+// MigrationStatement is the outcome of one statement: "ok", "failed", "skipped"
+// (not run after an earlier failure), or "rolled_back" (ran, then undone)
+type MigrationStatement struct {
+	Index        int    `json:"index"`
+	Statement    string `json:"statement"`
+	Status       string `json:"status"`
+	RowsAffected *int64 `json:"rows_affected,omitempty"`
+	DurationMs   int64  `json:"duration_ms"`
+	Error        string `json:"error,omitempty"`
+}
+
+// splitPostgresStatements splits a script on top-level semicolons. Semicolons inside
+// string literals, quoted identifiers, dollar-quoted bodies ($$ ... $$, $fn$ ... $fn$),
+// and comments do not end a statement. Statements holding only whitespace and
+// comments are dropped.
+func splitPostgresStatements(script string) []string {
+	var statements []string
+	start := 0
+	hasCode := false
+
+	flush := func(end int) {
+		if hasCode {
+			statements = append(statements, strings.TrimSpace(script[start:end]))
+		}
+		start = end + 1
+		hasCode = false
+	}
+
+	n := len(script)
+	for i := 0; i < n; {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"':
+			escapes := c == '\'' && i > 0 && (script[i-1] == 'e' || script[i-1] == 'E') && (i < 2 || !isIdentChar(script[i-2]))
+			i = skipQuoted(script, i, c, escapes)
+			hasCode = true
+
+		case c == '$':
+			if end, ok := skipDollarQuoted(script, i); ok {
+				i = end
+			} else {
+				i++
+			}
+			hasCode = true
+
+		case c == '-' && i+1 < n && script[i+1] == '-':
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			i += end
+
+		case c == '/' && i+1 < n && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = n
+			} else {
+				i += end + 4
+			}
+
+		case c == ';':
+			flush(i)
+			i++
+
+		default:
+			if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				hasCode = true
+			}
+			i++
+		}
+	}
+	if start < n {
+		flush(n)
+	}
+
+	return statements
+}
+
+// validateMigrationStatements rejects empty scripts and transaction control
+// statements, since the whole script already runs in one transaction
+func validateMigrationStatements(statements []string) error {
+	if len(statements) == 0 {
+		return fmt.Errorf("script contains no statements")
+	}
+
+	for i, stmt := range statements {
+		lower := strings.ToLower(stripLeadingComments(stmt))
+		for _, kw := range transactionKeywords {
+			if hasKeywordPrefix(lower, kw) {
+				return fmt.Errorf("statement %d: transaction control (%s) is not allowed; the script already runs in a single transaction", i+1, strings.ToUpper(kw))
+			}
+		}
+	}
+	return nil
+}
+
+// migrationResetStatements undo session state a committed migration may leave on
+// its connection, such as SET search_path or SET ROLE, before it returns to the pool
+var migrationResetStatements = []string{"RESET ALL", "RESET ROLE", "RESET SESSION AUTHORIZATION"}
+
+// migrationResetTimeout bounds the reset, which runs even after the call's context ended
+const migrationResetTimeout = 5 * time.Second
+
+// RunMigration runs statements in order inside one transaction on a dedicated
+// connection. On the first failure the transaction is rolled back and the remaining
+// statements are skipped. observe, if set, is called after each executed statement.
+// Session settings are reset before the connection is released; if that fails the
+// connection is discarded instead.
+func (p *PostgresAdapter) RunMigration(ctx context.Context, statements []string, observe func(ctx context.Context, statement string, err error)) (*MigrationResult, error) {
+	l := loggerFrom(ctx).With().Str("scope", "RunMigration").Logger()
+
+	conn, err := p.acquireConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		resetCtx, cancel := context.WithTimeout(context.Background(), migrationResetTimeout)
+		defer cancel()
+		for _, stmt := range migrationResetStatements {
+			if _, err := conn.ExecContext(resetCtx, stmt); err != nil {
+				l.Warn().Err(err).Msg("Failed to reset session state, discarding connection")
+				conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+				break
+			}
+		}
+		conn.Close()
+	}()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &MigrationResult{Statements: make([]MigrationStatement, len(statements))}
+	failed := -1
+	for i, stmt := range statements {
+		s := &result.Statements[i]
+		s.Index = i + 1
+		s.Statement = stmt
+
+		if failed >= 0 {
+			s.Status = "skipped"
+			continue
+		}
+
+		started := time.Now()
+		res, err := tx.ExecContext(ctx, stmt)
+		s.DurationMs = time.Since(started).Milliseconds()
+		if observe != nil {
+			observe(ctx, stmt, err)
+		}
+		if err != nil {
+			s.Status = "failed"
+			s.Error = err.Error()
+			failed = i
+			continue
+		}
+
+		s.Status = "ok"
+		if n, err := res.RowsAffected(); err == nil {
+			s.RowsAffected = &n
+		}
+	}
+
+	if failed >= 0 {
+		if err := tx.Rollback(); err != nil {
+			return nil, fmt.Errorf("failed to roll back migration: %w", err)
+		}
+		for i := 0; i < failed; i++ {
+			result.Statements[i].Status = "rolled_back"
+		}
+		l.Warn().Int("statements", len(statements)).Int("failed_statement", failed+1).Msg("Migration rolled back")
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit migration: %w", err)
+	}
+	result.Committed = true
+	l.Info().Int("statements", len(statements)).Msg("Migration committed")
+
+	return result, nil
+}
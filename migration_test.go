@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// migrationTestDB records the statements and transaction calls of a fake connection.
+// Executing the statement named in failOn returns an error.
+type migrationTestDB struct {
+	mu     sync.Mutex
+	events []string
+	failOn string
+}
+
+func (d *migrationTestDB) add(event string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+}
+
+func (d *migrationTestDB) Connect(context.Context) (driver.Conn, error) { return &migrationTestConn{d}, nil }
+func (d *migrationTestDB) Driver() driver.Driver                        { return nil }
+
+type migrationTestConn struct{ db *migrationTestDB }
+
+func (c *migrationTestConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *migrationTestConn) Close() error { c.db.add("close"); return nil }
+func (c *migrationTestConn) Begin() (driver.Tx, error) {
+	c.db.add("begin")
+	return migrationTestTx{c.db}, nil
+}
+func (c *migrationTestConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.add(query)
+	if query == c.db.failOn {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type migrationTestTx struct{ db *migrationTestDB }
+
+func (t migrationTestTx) Commit() error   { t.db.add("commit"); return nil }
+func (t migrationTestTx) Rollback() error { t.db.add("rollback"); return nil }
+
+func runTestMigration(t *testing.T, failOn string, statements []string) (*MigrationResult, []string, []string) {
+	t.Helper()
+	fake := &migrationTestDB{failOn: failOn}
+	adapter := &PostgresAdapter{BaseAdapter: BaseAdapter{name: "postgres", enabled: true, db: sql.OpenDB(fake)}}
+
+	var observed []string
+	result, err := adapter.RunMigration(context.Background(), statements, func(ctx context.Context, statement string, err error) {
+		if err != nil {
+			statement += " (failed)"
+		}
+		observed = append(observed, statement)
+	})
+	if err != nil {
+		t.Fatalf("RunMigration: %v", err)
+	}
+	return result, fake.events, observed
+}
+
+func TestRunMigrationRollsBackOnFailure(t *testing.T) {
+	statements := []string{"CREATE TABLE a (id int)", "CREATE TABLE broken", "CREATE TABLE c (id int)"}
+	result, events, observed := runTestMigration(t, "CREATE TABLE broken", statements)
+
+	if result.Committed {
+		t.Error("Committed = true, want false")
+	}
+	var statuses []string
+	for _, s := range result.Statements {
+		statuses = append(statuses, s.Status)
+	}
+	if want := []string{"rolled_back", "failed", "skipped"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if result.Statements[1].Error == "" {
+		t.Error("failed statement has no error")
+	}
+
+	want := []string{"begin", "CREATE TABLE a (id int)", "CREATE TABLE broken", "rollback",
+		"RESET ALL", "RESET ROLE", "RESET SESSION AUTHORIZATION"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+	if want := []string{"CREATE TABLE a (id int)", "CREATE TABLE broken (failed)"}; !reflect.DeepEqual(observed, want) {
+		t.Errorf("observed = %q, want %q", observed, want)
+	}
+}
+
+func TestRunMigrationCommitsAndResetsSession(t *testing.T) {
+	statements := []string{"SET search_path TO app", "CREATE TABLE a (id int)"}
+	result, events, observed := runTestMigration(t, "", statements)
+
+	if !result.Committed {
+		t.Error("Committed = false, want true")
+	}
+	want := []string{"begin", "SET search_path TO app", "CREATE TABLE a (id int)", "commit",
+		"RESET ALL", "RESET ROLE", "RESET SESSION AUTHORIZATION"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+	if !reflect.DeepEqual(observed, statements) {
+		t.Errorf("observed = %q, want %q", observed, statements)
+	}
+}
+
+func TestRunMigrationDiscardsConnectionWhenResetFails(t *testing.T) {
+	_, events, _ := runTestMigration(t, "RESET ALL", []string{"SET ROLE admin"})
+
+	want := []string{"begin", "SET ROLE admin", "commit", "RESET ALL", "close"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestSplitPostgresStatements(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{script: "SELECT 1; SELECT 2;", want: []string{"SELECT 1", "SELECT 2"}},
+		{script: "INSERT INTO t VALUES ('a;b'); -- c;d\nSELECT 1", want: []string{"INSERT INTO t VALUES ('a;b')", "-- c;d\nSELECT 1"}},
+		{script: `SELECT "x;y" FROM t; /* ; */`, want: []string{`SELECT "x;y" FROM t`}},
+		{script: "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql", want: []string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql"}},
+		{script: `SELECT E'it\'s;'; SELECT 2`, want: []string{`SELECT E'it\'s;'`, "SELECT 2"}},
+		{script: " ; -- only a comment\n", want: nil},
+	}
+	for _, tt := range tests {
+		if got := splitPostgresStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPostgresStatements(%q) = %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMigrationStatements(t *testing.T) {
+	if err := validateMigrationStatements(nil); err == nil {
+		t.Error("empty script accepted")
+	}
+	for _, stmt := range []string{"BEGIN", "commit", "/* x */ ROLLBACK", "SAVEPOINT s", "START TRANSACTION"} {
+		if err := validateMigrationStatements([]string{"CREATE TABLE a (id int)", stmt}); err == nil {
+			t.Errorf("%q accepted", stmt)
+		}
+	}
+	if err := validateMigrationStatements([]string{"CREATE TABLE beginnings (id int)"}); err != nil {
+		t.Errorf("CREATE TABLE beginnings rejected: %v", err)
+	}
+}
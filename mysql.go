@@ -4,24 +4,60 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/rs/zerolog/log"
 )
 
+// mysqlQueryOptions are the variables that may be applied with a SET_VAR optimizer hint for a single query
+var mysqlQueryOptions = map[string]bool{
+	"max_execution_time":     true,
+	"sort_buffer_size":       true,
+	"join_buffer_size":       true,
+	"read_rnd_buffer_size":   true,
+	"tmp_table_size":         true,
+	"optimizer_search_depth": true,
+}
+
+// mysqlProtectedOptions are DSN parameters MYSQL_OPTIONS may not set, because they
+// control TLS, password exchange, local file access, or multi-statement queries
+var mysqlProtectedOptions = map[string]bool{
+	"tls":                      true,
+	"serverpubkey":             true,
+	"allowcleartextpasswords":  true,
+	"allownativepasswords":     true,
+	"allowoldpasswords":        true,
+	"allowfallbacktoplaintext": true,
+	"allowallfiles":            true,
+	"multistatements":          true,
+}
+
 type MySQLAdapter struct {
 	BaseAdapter
-	url string
+	url     string
+	appName string
+	options string
+
+	// excludedSchemas are left out of ListSchemas
+	excludedSchemas []string
 }
 
-func NewMySQLAdapter(url string) *MySQLAdapter {
+func NewMySQLAdapter(cfg *Config) *MySQLAdapter {
 	return &MySQLAdapter{
 		BaseAdapter: BaseAdapter{
-			name:    "mysql",
-			enabled: url != "",
+			name:         "mysql",
+			enabled:      cfg.MySQLURL != "",
+			policy:       NewMySQLReadOnlyPolicy(cfg),
+			results:      NewResultOptions(cfg),
+			maxOpenConns: cfg.DBMaxOpenConns,
 		},
-		url: url,
+		url:     cfg.MySQLURL,
+		appName: cfg.AppName,
+		options: cfg.MySQLOptions,
+
+		excludedSchemas: cfg.MySQLExcludedSchemas,
 	}
 }
 
@@ -30,30 +66,91 @@ func (m *MySQLAdapter) Connect() error {
 		return nil
 	}
 
-	db, err := sql.Open("mysql", m.url)
+	dsn, err := m.connectionConfig()
+	if err != nil {
+		return fmt.Errorf("failed to open mysql connection: %w", err)
+	}
+
+	connector, err := mysql.NewConnector(dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open mysql connection: %w", err)
 	}
+	db := sql.OpenDB(connector)
 
 	if err := db.Ping(); err != nil {
 		db.Close()
 		return fmt.Errorf("failed to ping mysql: %w", err)
 	}
+	db.SetMaxOpenConns(m.maxOpenConns)
 
 	m.db = db
 	log.Info().Msg("MySQL adapter connected")
 	return nil
 }
 
+// connectionConfig parses the DSN and adds a program_name connection attribute so the
+// server's connections are identifiable in performance_schema.session_connect_attrs.
+// A program_name already present in the DSN takes precedence.
+func (m *MySQLAdapter) connectionConfig() (*mysql.Config, error) {
+	options, err := parseConnectionOptions(m.options, mysqlProtectedOptions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MYSQL_OPTIONS: %w", err)
+	}
+
+	dsn, err := mysql.ParseDSN(mysqlDSNWithOptions(m.url, options))
+	if err != nil {
+		return nil, err
+	}
+
+	if m.appName != "" && !strings.Contains(dsn.ConnectionAttributes, "program_name:") {
+		// Attributes are comma-separated key:value pairs
+		attr := "program_name:" + strings.NewReplacer(",", "", ":", "").Replace(m.appName)
+		if dsn.ConnectionAttributes != "" {
+			attr = dsn.ConnectionAttributes + "," + attr
+		}
+		dsn.ConnectionAttributes = attr
+	}
+
+	return dsn, nil
+}
+
+// mysqlDSNWithOptions appends MYSQL_OPTIONS parameters to a DSN. A parameter already
+// present in the DSN is kept, and the option is skipped with a warning.
+func mysqlDSNWithOptions(dsn string, options []connectionOption) string {
+	if len(options) == 0 {
+		return dsn
+	}
+
+	// Parameters follow the first ? after the last / (the password may contain either)
+	existing := url.Values{}
+	sep := "?"
+	if slash := strings.LastIndex(dsn, "/"); slash >= 0 {
+		if q := strings.Index(dsn[slash:], "?"); q >= 0 {
+			existing, _ = url.ParseQuery(dsn[slash+q+1:])
+			sep = "&"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(dsn)
+	for _, opt := range options {
+		if existing.Has(opt.Key) {
+			log.Warn().Str("option", opt.Key).Msg("MYSQL_OPTIONS entry ignored, already set in MYSQL_URL")
+			continue
+		}
+		fmt.Fprintf(&b, "%s%s=%s", sep, opt.Key, url.QueryEscape(opt.Value))
+		sep = "&"
+	}
+	return b.String()
+}
+
+// ListSchemas returns the databases on the server, leaving out the configured
+// system schemas
 func (m *MySQLAdapter) ListSchemas(ctx context.Context) ([]Schema, error) {
-	query := `
-		SELECT SCHEMA_NAME 
-		FROM INFORMATION_SCHEMA.SCHEMATA 
-		WHERE SCHEMA_NAME NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')
-		ORDER BY SCHEMA_NAME
-	`
+	condition, args := excludedSchemasCondition("SCHEMA_NAME", m.excludedSchemas, true)
+	query := fmt.Sprintf("SELECT SCHEMA_NAME FROM INFORMATION_SCHEMA.SCHEMATA WHERE %s ORDER BY SCHEMA_NAME", condition)
 
-	rows, err := m.db.QueryContext(ctx, query)
+	rows, err := m.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list schemas: %w", err)
 	}
@@ -71,6 +168,32 @@ func (m *MySQLAdapter) ListSchemas(ctx context.Context) ([]Schema, error) {
 	return schemas, rows.Err()
 }
 
+// ListDatabases returns the databases (schemas) visible to the user, including the
+// system ones that ListSchemas leaves out
+func (m *MySQLAdapter) ListDatabases(ctx context.Context) ([]Database, error) {
+	var current sql.NullString
+	if err := m.db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&current); err != nil {
+		return nil, fmt.Errorf("failed to get current database: %w", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SHOW DATABASES")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	defer rows.Close()
+
+	databases := []Database{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan database: %w", err)
+		}
+		databases = append(databases, Database{Name: name, Current: current.Valid && name == current.String})
+	}
+
+	return databases, rows.Err()
+}
+
 func (m *MySQLAdapter) GetSchemaDDL(ctx context.Context, schemaName string) (string, error) {
 	var ddls []string
 
@@ -190,18 +313,609 @@ func (m *MySQLAdapter) GetSchemaDDL(ctx context.Context, schemaName string) (str
 }
 
 func (m *MySQLAdapter) ExecuteSelect(ctx context.Context, query string) (QueryResult, error) {
-	query = strings.TrimSpace(query)
-	queryLower := strings.ToLower(query)
+	query, err := validateReadOnlyQuery(query, m.policy)
+	if err != nil {
+		return QueryResult{}, err
+	}
 
-	if !strings.HasPrefix(queryLower, "select") && !strings.HasPrefix(queryLower, "with") {
-		return QueryResult{}, fmt.Errorf("only SELECT queries are allowed")
+	if opts := queryOptionsFrom(ctx); len(opts) > 0 {
+		if query, err = applyMySQLHints(query, opts); err != nil {
+			return QueryResult{}, err
+		}
 	}
 
-	rows, err := m.db.QueryContext(ctx, query)
+	conn, err := m.acquireConn(ctx)
+	if err != nil {
+		return QueryResult{}, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, query, queryArgsFrom(ctx)...)
 	if err != nil {
 		return QueryResult{}, fmt.Errorf("query execution failed: %w", err)
 	}
 	defer rows.Close()
 
-	return scanQueryResult(rows)
+	return scanQueryResult(rows, resultOptionsFrom(ctx, m.results))
+}
+
+// applyMySQLHints injects SET_VAR optimizer hints after the leading SELECT keyword.
+// SET_VAR only lasts for the statement, so the settings do not leak into the session.
+func applyMySQLHints(query string, opts QueryOptions) (string, error) {
+	if err := opts.validate(mysqlQueryOptions); err != nil {
+		return "", err
+	}
+	if !hasKeywordPrefix(strings.ToLower(query), "select") {
+		return "", fmt.Errorf("query options are only supported for queries starting with SELECT")
+	}
+
+	hints := make([]string, 0, len(opts))
+	for _, name := range opts.names() {
+		hints = append(hints, fmt.Sprintf("SET_VAR(%s = %s)", name, opts[name]))
+	}
+
+	return fmt.Sprintf("SELECT /*+ %s */%s", strings.Join(hints, " "), query[len("select"):]), nil
+}
+
+// LargestTables returns the largest tables in a schema by data plus index length, descending
+func (m *MySQLAdapter) LargestTables(ctx context.Context, schemaName string, limit int) ([]TableSize, error) {
+	query := `
+		SELECT TABLE_NAME, COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0) AS size
+		FROM INFORMATION_SCHEMA.TABLES
+		WHERE TABLE_SCHEMA = ?
+			AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY size DESC, TABLE_NAME
+		LIMIT ?
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table sizes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTableSizes(rows)
+}
+
+// ListPartitions returns the partitioning method and partitions of a table from
+// INFORMATION_SCHEMA.PARTITIONS. Non-partitioned tables are reported with
+// Partitioned set to false.
+func (m *MySQLAdapter) ListPartitions(ctx context.Context, schemaName, tableName string) (*PartitionInfo, error) {
+	query := `
+		SELECT PARTITION_NAME, SUBPARTITION_NAME, PARTITION_METHOD, PARTITION_EXPRESSION,
+			PARTITION_DESCRIPTION, TABLE_ROWS
+		FROM INFORMATION_SCHEMA.PARTITIONS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY PARTITION_ORDINAL_POSITION, SUBPARTITION_ORDINAL_POSITION
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	info := &PartitionInfo{Table: tableName, Partitions: []Partition{}}
+	found := false
+	for rows.Next() {
+		found = true
+		var name, subName, method, expression, description sql.NullString
+		var tableRows sql.NullInt64
+		if err := rows.Scan(&name, &subName, &method, &expression, &description, &tableRows); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+
+		// A non-partitioned table has a single row with no partition name
+		if !name.Valid {
+			continue
+		}
+		info.Partitioned = true
+		info.PartitionKey = fmt.Sprintf("%s (%s)", method.String, expression.String)
+
+		part := Partition{Name: name.String, SubPartitioned: subName.Valid}
+		if subName.Valid {
+			part.Name = name.String + "." + subName.String
+		}
+		if description.Valid {
+			part.Bound = description.String
+		}
+		if tableRows.Valid {
+			n := tableRows.Int64
+			part.EstimatedRows = &n
+		}
+		info.Partitions = append(info.Partitions, part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("table %s.%s not found", schemaName, tableName)
+	}
+	return info, nil
+}
+
+// quoteMySQLIdent quotes an identifier for safe inclusion in a query
+func quoteMySQLIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// QuoteIdent quotes an identifier for MySQL
+func (m *MySQLAdapter) QuoteIdent(name string) string {
+	return quoteMySQLIdent(name)
+}
+
+// PrimaryKeyColumns returns the primary key columns of a table in key order
+func (m *MySQLAdapter) PrimaryKeyColumns(ctx context.Context, schemaName, tableName string) ([]string, error) {
+	query := `
+		SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+			AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY'
+			AND tc.table_schema = ? AND tc.table_name = ?
+		ORDER BY kcu.ordinal_position
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up primary key: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan primary key column: %w", err)
+		}
+		columns = append(columns, name)
+	}
+
+	return columns, rows.Err()
+}
+
+// ForeignKeys returns the foreign keys of a table, ordered by constraint name, with
+// columns in key order
+func (m *MySQLAdapter) ForeignKeys(ctx context.Context, schemaName, tableName string) ([]ForeignKey, error) {
+	query := `
+		SELECT constraint_name, column_name, referenced_table_schema, referenced_table_name, referenced_column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = ? AND table_name = ? AND referenced_table_name IS NOT NULL
+		ORDER BY constraint_name, ordinal_position
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var name, column, parentSchema, parentTable, parentColumn string
+		if err := rows.Scan(&name, &column, &parentSchema, &parentTable, &parentColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		if len(fks) == 0 || fks[len(fks)-1].Name != name {
+			fks = append(fks, ForeignKey{Name: name, ParentSchema: parentSchema, ParentTable: parentTable})
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ParentColumns = append(fk.ParentColumns, parentColumn)
+	}
+
+	return fks, rows.Err()
+}
+
+// GetRowQuery builds a lookup of at most one row by primary key, optionally only the
+// given columns, and its bound arguments. key must name exactly the primary key
+// columns. It reads the catalog to validate columns and find the key, but does not
+// run the lookup.
+func (m *MySQLAdapter) GetRowQuery(ctx context.Context, schemaName, tableName string, key map[string]interface{}, columns []string) (string, []interface{}, error) {
+	projection, err := m.selectList(ctx, schemaName, tableName, columns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pkColumns, err := m.PrimaryKeyColumns(ctx, schemaName, tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := primaryKeyArgs(pkColumns, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conditions := make([]string, len(pkColumns))
+	for i, col := range pkColumns {
+		conditions[i] = fmt.Sprintf("%s = ?", quoteMySQLIdent(col))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s LIMIT 1", projection,
+		quoteMySQLIdent(schemaName), quoteMySQLIdent(tableName), strings.Join(conditions, " AND "))
+
+	return query, args, nil
+}
+
+// CollationInfo returns the default character set and collation of a schema, the
+// collation of each table, and the character set and collation of text columns
+func (m *MySQLAdapter) CollationInfo(ctx context.Context, schemaName, tableName string) (*CollationInfo, error) {
+	info := &CollationInfo{Schema: schemaName, Tables: []TableCollation{}}
+
+	schemaQuery := `
+		SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME
+		FROM INFORMATION_SCHEMA.SCHEMATA
+		WHERE SCHEMA_NAME = ?
+	`
+	err := m.db.QueryRowContext(ctx, schemaQuery, schemaName).Scan(&info.CharacterSet, &info.Collation)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("schema %s not found", schemaName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema collation: %w", err)
+	}
+
+	columnsQuery := `
+		SELECT t.TABLE_NAME, COALESCE(t.TABLE_COLLATION, ''), c.COLUMN_NAME, c.CHARACTER_SET_NAME, c.COLLATION_NAME
+		FROM INFORMATION_SCHEMA.TABLES t
+		JOIN INFORMATION_SCHEMA.COLUMNS c
+			ON c.TABLE_SCHEMA = t.TABLE_SCHEMA AND c.TABLE_NAME = t.TABLE_NAME
+		WHERE t.TABLE_SCHEMA = ?
+			AND (? = '' OR t.TABLE_NAME = ?)
+			AND c.COLLATION_NAME IS NOT NULL
+		ORDER BY t.TABLE_NAME, c.ORDINAL_POSITION
+	`
+
+	rows, err := m.db.QueryContext(ctx, columnsQuery, schemaName, tableName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column collations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, tableCollation string
+		var col ColumnCollation
+		if err := rows.Scan(&table, &tableCollation, &col.Column, &col.CharacterSet, &col.Collation); err != nil {
+			return nil, fmt.Errorf("failed to scan column collation: %w", err)
+		}
+		info.Tables = addColumnCollation(info.Tables, table, col)
+		info.Tables[len(info.Tables)-1].Collation = tableCollation
+	}
+
+	return info, rows.Err()
+}
+
+// TableColumns returns the column names of a table or view in ordinal order
+func (m *MySQLAdapter) TableColumns(ctx context.Context, schemaName, tableName string) ([]string, error) {
+	query := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+		ORDER BY ordinal_position
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s.%s not found", schemaName, tableName)
+	}
+	return columns, nil
+}
+
+// selectList builds a validated select list for the requested columns of a table
+func (m *MySQLAdapter) selectList(ctx context.Context, schemaName, tableName string, columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "*", nil
+	}
+	available, err := m.TableColumns(ctx, schemaName, tableName)
+	if err != nil {
+		return "", err
+	}
+	return buildSelectList(columns, available, quoteMySQLIdent)
+}
+
+// AutoIncrement is the next AUTO_INCREMENT value of a table and its counter column
+type AutoIncrement struct {
+	Table     string `json:"table"`
+	Column    string `json:"column,omitempty"`
+	DataType  string `json:"data_type,omitempty"`
+	NextValue int64  `json:"next_value"`
+}
+
+// ListAutoIncrements returns the AUTO_INCREMENT counters of tables in a schema. On
+// MySQL 8 the values come from cached statistics and may lag by up to
+// information_schema_stats_expiry.
+func (m *MySQLAdapter) ListAutoIncrements(ctx context.Context, schemaName string) ([]AutoIncrement, error) {
+	query := `
+		SELECT t.TABLE_NAME, COALESCE(c.COLUMN_NAME, ''), COALESCE(c.COLUMN_TYPE, ''), t.AUTO_INCREMENT
+		FROM INFORMATION_SCHEMA.TABLES t
+		LEFT JOIN INFORMATION_SCHEMA.COLUMNS c
+			ON c.TABLE_SCHEMA = t.TABLE_SCHEMA
+			AND c.TABLE_NAME = t.TABLE_NAME
+			AND c.EXTRA LIKE '%auto_increment%'
+		WHERE t.TABLE_SCHEMA = ? AND t.AUTO_INCREMENT IS NOT NULL
+		ORDER BY t.TABLE_NAME
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auto increments: %w", err)
+	}
+	defer rows.Close()
+
+	counters := []AutoIncrement{}
+	for rows.Next() {
+		var a AutoIncrement
+		if err := rows.Scan(&a.Table, &a.Column, &a.DataType, &a.NextValue); err != nil {
+			return nil, fmt.Errorf("failed to scan auto increment: %w", err)
+		}
+		counters = append(counters, a)
+	}
+
+	return counters, rows.Err()
+}
+
+// OverviewTables returns the base tables of a schema with their row estimate. On
+// MySQL 8 the estimates come from cached statistics and may lag by up to
+// information_schema_stats_expiry.
+func (m *MySQLAdapter) OverviewTables(ctx context.Context, schemaName string, limit int) ([]OverviewTable, error) {
+	query := `
+		SELECT TABLE_NAME, TABLE_ROWS, COALESCE(TABLE_COMMENT, '')
+		FROM INFORMATION_SCHEMA.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+		LIMIT ?
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []OverviewTable{}
+	for rows.Next() {
+		t := OverviewTable{Type: "table"}
+		var estimate sql.NullInt64
+		if err := rows.Scan(&t.Name, &estimate, &t.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		if estimate.Valid {
+			t.EstimatedRows = &estimate.Int64
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+// SchemaForeignKeys returns the foreign keys declared on the tables of a schema,
+// ordered by table and constraint name, with at most limit keys
+func (m *MySQLAdapter) SchemaForeignKeys(ctx context.Context, schemaName string, limit int) ([]SchemaForeignKey, error) {
+	query := `
+		SELECT table_name, constraint_name, column_name, referenced_table_schema, referenced_table_name, referenced_column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = ? AND referenced_table_name IS NOT NULL
+		ORDER BY table_name, constraint_name, ordinal_position
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	fks := []SchemaForeignKey{}
+	for rows.Next() {
+		var table, name, column, parentSchema, parentTable, parentColumn string
+		if err := rows.Scan(&table, &name, &column, &parentSchema, &parentTable, &parentColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		if len(fks) == 0 || fks[len(fks)-1].Table != table || fks[len(fks)-1].Name != name {
+			// Keys span several rows, so the limit is applied while grouping them
+			if len(fks) == limit {
+				break
+			}
+			fks = append(fks, SchemaForeignKey{
+				Table:      table,
+				ForeignKey: ForeignKey{Name: name, ParentSchema: parentSchema, ParentTable: parentTable},
+			})
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ParentColumns = append(fk.ParentColumns, parentColumn)
+	}
+
+	return fks, rows.Err()
+}
+
+// OverviewViews returns the views of a schema. MySQL has no materialized views.
+func (m *MySQLAdapter) OverviewViews(ctx context.Context, schemaName string, limit int) ([]OverviewView, error) {
+	query := `
+		SELECT TABLE_NAME
+		FROM INFORMATION_SCHEMA.VIEWS
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME
+		LIMIT ?
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []OverviewView{}
+	for rows.Next() {
+		var v OverviewView
+		if err := rows.Scan(&v.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, v)
+	}
+
+	return views, rows.Err()
+}
+
+// OverviewRoutines returns the functions and procedures of a schema with their
+// parameters and, for functions, the return type
+func (m *MySQLAdapter) OverviewRoutines(ctx context.Context, schemaName string, limit int) ([]OverviewRoutine, error) {
+	query := `
+		SELECT r.ROUTINE_NAME, r.ROUTINE_TYPE,
+			COALESCE((
+				SELECT GROUP_CONCAT(CONCAT_WS(' ', p.PARAMETER_MODE, p.PARAMETER_NAME, p.DTD_IDENTIFIER)
+					ORDER BY p.ORDINAL_POSITION SEPARATOR ', ')
+				FROM INFORMATION_SCHEMA.PARAMETERS p
+				WHERE p.SPECIFIC_SCHEMA = r.ROUTINE_SCHEMA AND p.SPECIFIC_NAME = r.SPECIFIC_NAME
+					AND p.ROUTINE_TYPE = r.ROUTINE_TYPE AND p.ORDINAL_POSITION > 0
+			), ''),
+			COALESCE(r.DTD_IDENTIFIER, '')
+		FROM INFORMATION_SCHEMA.ROUTINES r
+		WHERE r.ROUTINE_SCHEMA = ?
+		ORDER BY r.ROUTINE_NAME, r.ROUTINE_TYPE
+		LIMIT ?
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routines: %w", err)
+	}
+	defer rows.Close()
+
+	routines := []OverviewRoutine{}
+	for rows.Next() {
+		var r OverviewRoutine
+		if err := rows.Scan(&r.Name, &r.Type, &r.Arguments, &r.Returns); err != nil {
+			return nil, fmt.Errorf("failed to scan routine: %w", err)
+		}
+		routines = append(routines, r)
+	}
+
+	return routines, rows.Err()
+}
+
+// RoutineDDL returns the CREATE FUNCTION/PROCEDURE statement of a routine. A function
+// and a procedure may share a name, in which case routineType ("FUNCTION" or
+// "PROCEDURE") must pick one.
+func (m *MySQLAdapter) RoutineDDL(ctx context.Context, schemaName, routineName, routineType string) (*RoutineDefinition, error) {
+	query := `
+		SELECT ROUTINE_TYPE
+		FROM INFORMATION_SCHEMA.ROUTINES
+		WHERE ROUTINE_SCHEMA = ? AND ROUTINE_NAME = ?
+			AND (? = '' OR ROUTINE_TYPE = ?)
+		ORDER BY ROUTINE_TYPE
+	`
+
+	routineType = strings.ToUpper(strings.TrimSpace(routineType))
+	rows, err := m.db.QueryContext(ctx, query, schemaName, routineName, routineType, routineType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up routine: %w", err)
+	}
+	defer rows.Close()
+
+	var types []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("failed to scan routine type: %w", err)
+		}
+		types = append(types, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(types) {
+	case 0:
+		return nil, fmt.Errorf("routine %s.%s not found", schemaName, routineName)
+	case 1:
+	default:
+		return nil, fmt.Errorf("both a function and a procedure are named %s.%s; pass routine_type to choose one", schemaName, routineName)
+	}
+
+	showCreateQuery := fmt.Sprintf("SHOW CREATE %s %s.%s", types[0], quoteMySQLIdent(schemaName), quoteMySQLIdent(routineName))
+	var name, sqlMode, characterSet, collation, dbCollation string
+	var createStatement sql.NullString
+	if err := m.db.QueryRowContext(ctx, showCreateQuery).Scan(&name, &sqlMode, &createStatement, &characterSet, &collation, &dbCollation); err != nil {
+		return nil, fmt.Errorf("failed to get create routine statement: %w", err)
+	}
+	if !createStatement.Valid {
+		return nil, fmt.Errorf("no permission to read the definition of %s.%s", schemaName, routineName)
+	}
+
+	return &RoutineDefinition{
+		Schema:     schemaName,
+		Name:       routineName,
+		Type:       types[0],
+		Definition: createStatement.String,
+	}, nil
+}
+
+// quoteMySQLLiteral quotes a string literal. Quotes are doubled and backslashes
+// escaped, which is safe with or without NO_BACKSLASH_ESCAPES.
+func quoteMySQLLiteral(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(value) + "'"
+}
+
+// ShowSettings returns the session's system variables whose name matches a LIKE
+// pattern (all when empty), as reported by SHOW VARIABLES
+func (m *MySQLAdapter) ShowSettings(ctx context.Context, pattern string) ([]Setting, error) {
+	query := "SHOW VARIABLES"
+	if pattern != "" {
+		query += " LIKE " + quoteMySQLLiteral(pattern)
+	}
+
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variables: %w", err)
+	}
+	defer rows.Close()
+
+	settings := []Setting{}
+	for rows.Next() {
+		var s Setting
+		if err := rows.Scan(&s.Name, &s.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan variable: %w", err)
+		}
+		settings = append(settings, s)
+	}
+
+	return settings, rows.Err()
+}
+
+// TransactionSettings reports the default isolation level and read-only mode of new
+// transactions and the session time zone. SystemTimeZone is set when the session
+// time zone is SYSTEM.
+func (m *MySQLAdapter) TransactionSettings(ctx context.Context) (*TransactionSettings, error) {
+	query := "SELECT @@transaction_isolation, @@transaction_read_only, @@time_zone, @@system_time_zone"
+
+	var t TransactionSettings
+	var systemTimeZone string
+	if err := m.db.QueryRowContext(ctx, query).Scan(&t.IsolationLevel, &t.ReadOnly, &t.TimeZone, &systemTimeZone); err != nil {
+		return nil, fmt.Errorf("failed to read transaction settings: %w", err)
+	}
+	t.IsolationLevel = normalizeIsolationLevel(t.IsolationLevel)
+	if t.TimeZone == "SYSTEM" {
+		t.SystemTimeZone = systemTimeZone
+	}
+
+	return &t, nil
 }
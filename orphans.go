@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultOrphanSampleLimit = 10
+	maxOrphanSampleLimit     = 100
+)
+
+// ForeignKey is a foreign key from a child table's columns to a parent table's columns
+type ForeignKey struct {
+	Name          string   `json:"name,omitempty"`
+	Columns       []string `json:"columns"`
+	ParentSchema  string   `json:"parent_schema"`
+	ParentTable   string   `json:"parent_table"`
+	ParentColumns []string `json:"parent_columns"`
+}
+
+// OrphanReport counts child rows whose foreign key references a missing parent row,
+// with a sample of them
+type OrphanReport struct {
+	Schema      string      `json:"schema"`
+	Table       string      `json:"table"`
+	ForeignKey  ForeignKey  `json:"foreign_key"`
+	OrphanCount int64       `json:"orphan_count"`
+	Sample      QueryResult `json:"sample"`
+}
+
+// pickForeignKey selects the foreign key named name, or the only one when name is
+// empty. The error lists the available keys when the choice is ambiguous.
+func pickForeignKey(fks []ForeignKey, name string) (ForeignKey, error) {
+	if len(fks) == 0 {
+		return ForeignKey{}, fmt.Errorf("table has no foreign keys; pass columns, parent_table, and parent_columns explicitly")
+	}
+
+	names := make([]string, len(fks))
+	for i, fk := range fks {
+		if name != "" && fk.Name == name {
+			return fk, nil
+		}
+		names[i] = fk.Name
+	}
+
+	if name != "" {
+		return ForeignKey{}, fmt.Errorf("foreign key %s not found; table has: %s", name, strings.Join(names, ", "))
+	}
+	if len(fks) > 1 {
+		return ForeignKey{}, fmt.Errorf("table has %d foreign keys, pass constraint_name to choose one of: %s", len(fks), strings.Join(names, ", "))
+	}
+	return fks[0], nil
+}
+
+// validateForeignKey checks an explicitly given foreign key for matching column lists
+func validateForeignKey(fk ForeignKey) error {
+	if len(fk.Columns) == 0 || fk.ParentTable == "" || len(fk.ParentColumns) == 0 {
+		return fmt.Errorf("columns, parent_table, and parent_columns are required without a constraint")
+	}
+	if len(fk.Columns) != len(fk.ParentColumns) {
+		return fmt.Errorf("columns has %d entries but parent_columns has %d", len(fk.Columns), len(fk.ParentColumns))
+	}
+	return nil
+}
+
+// buildOrphanQueries builds the count and sample queries for child rows of
+// schemaName.tableName whose key has no parent row. As with MATCH SIMPLE foreign
+// keys, rows with a NULL in any key column are not checked.
+func buildOrphanQueries(schemaName, tableName string, fk ForeignKey, quote func(string) string, limit int) (countQuery, sampleQuery string) {
+	joins := make([]string, len(fk.Columns))
+	notNull := make([]string, len(fk.Columns))
+	for i, col := range fk.Columns {
+		joins[i] = fmt.Sprintf("child.%s = parent.%s", quote(col), quote(fk.ParentColumns[i]))
+		notNull[i] = fmt.Sprintf("child.%s IS NOT NULL", quote(col))
+	}
+
+	from := fmt.Sprintf("FROM %s.%s AS child LEFT JOIN %s.%s AS parent ON %s WHERE %s AND parent.%s IS NULL",
+		quote(schemaName), quote(tableName), quote(fk.ParentSchema), quote(fk.ParentTable),
+		strings.Join(joins, " AND "), strings.Join(notNull, " AND "), quote(fk.ParentColumns[0]))
+
+	countQuery = "SELECT COUNT(*) " + from
+	sampleQuery = fmt.Sprintf("SELECT child.* %s LIMIT %d", from, limit)
+	return countQuery, sampleQuery
+}
+
+// checkOrphans runs the orphan queries on adapter through the registry's query path
+func checkOrphans(ctx context.Context, adapters *AdapterRegistry, adapter DatabaseAdapter, schemaName, tableName string, fk ForeignKey, limit int) (*OrphanReport, error) {
+	quoter, ok := adapter.(IdentifierQuoter)
+	if !ok {
+		return nil, fmt.Errorf("adapter %s does not support identifier quoting", adapter.Name())
+	}
+
+	countQuery, sampleQuery := buildOrphanQueries(schemaName, tableName, fk, quoter.QuoteIdent, limit)
+
+	result, err := adapters.ExecuteSelect(ctx, adapter, countQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		return nil, fmt.Errorf("unexpected COUNT(*) result shape")
+	}
+	count, err := countValue(result.Rows[0][0])
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OrphanReport{Schema: schemaName, Table: tableName, ForeignKey: fk, OrphanCount: count}
+	if count == 0 {
+		report.Sample = QueryResult{Columns: []string{}, Rows: [][]interface{}{}}
+		return report, nil
+	}
+
+	report.Sample, err = adapters.ExecuteSelect(ctx, adapter, sampleQuery)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
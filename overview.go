@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+const (
+	// defaultOverviewItems and maxOverviewItems bound each section of a schema overview
+	defaultOverviewItems = 100
+	maxOverviewItems     = 500
+)
+
+// OverviewTable is a table in a schema overview. EstimatedRows comes from planner
+// statistics and is nil when the table has never been analyzed. Before PostgreSQL 14
+// that also covers analyzed tables that are empty, since both have no pages.
+type OverviewTable struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	EstimatedRows *int64 `json:"estimated_rows"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+// SchemaForeignKey is one edge of a schema's foreign-key graph, from Table to the
+// parent table
+type SchemaForeignKey struct {
+	Table string `json:"table"`
+	ForeignKey
+}
+
+// OverviewView is a view in a schema overview
+type OverviewView struct {
+	Name         string `json:"name"`
+	Materialized bool   `json:"materialized"`
+}
+
+// OverviewRoutine is a function or procedure in a schema overview
+type OverviewRoutine struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Arguments string `json:"arguments,omitempty"`
+	Returns   string `json:"returns,omitempty"`
+}
+
+// SchemaOverviewer is implemented by adapters that can summarize a schema. Each
+// method returns at most limit items in name order.
+type SchemaOverviewer interface {
+	OverviewTables(ctx context.Context, schemaName string, limit int) ([]OverviewTable, error)
+	SchemaForeignKeys(ctx context.Context, schemaName string, limit int) ([]SchemaForeignKey, error)
+	OverviewViews(ctx context.Context, schemaName string, limit int) ([]OverviewView, error)
+	OverviewRoutines(ctx context.Context, schemaName string, limit int) ([]OverviewRoutine, error)
+}
+
+// SchemaOverview summarizes a schema's tables, foreign keys, views, and routines.
+// Truncated names the sections cut at the item cap; Errors holds the sections that
+// failed, whose lists are then empty.
+type SchemaOverview struct {
+	Adapter     string             `json:"adapter"`
+	Schema      string             `json:"schema"`
+	Tables      []OverviewTable    `json:"tables"`
+	ForeignKeys []SchemaForeignKey `json:"foreign_keys"`
+	Views       []OverviewView     `json:"views"`
+	Routines    []OverviewRoutine  `json:"routines"`
+	Truncated   []string           `json:"truncated,omitempty"`
+	Errors      map[string]string  `json:"errors,omitempty"`
+}
+
+// buildSchemaOverview runs the four overview queries concurrently, each asking for
+// one item more than maxItems so truncation can be detected. A failing section is
+// reported in Errors without affecting the others.
+func buildSchemaOverview(ctx context.Context, adapterName string, o SchemaOverviewer, schemaName string, maxItems int) *SchemaOverview {
+	overview := &SchemaOverview{
+		Adapter:     adapterName,
+		Schema:      schemaName,
+		Tables:      []OverviewTable{},
+		ForeignKeys: []SchemaForeignKey{},
+		Views:       []OverviewView{},
+		Routines:    []OverviewRoutine{},
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	run := func(section string, fetch func() (int, error)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := fetch()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if overview.Errors == nil {
+					overview.Errors = make(map[string]string)
+				}
+				overview.Errors[section] = err.Error()
+				return
+			}
+			if n > maxItems {
+				overview.Truncated = append(overview.Truncated, section)
+			}
+		}()
+	}
+
+	run("tables", func() (int, error) {
+		tables, err := o.OverviewTables(ctx, schemaName, maxItems+1)
+		if err == nil {
+			overview.Tables = tables[:min(len(tables), maxItems)]
+		}
+		return len(tables), err
+	})
+	run("foreign_keys", func() (int, error) {
+		fks, err := o.SchemaForeignKeys(ctx, schemaName, maxItems+1)
+		if err == nil {
+			overview.ForeignKeys = fks[:min(len(fks), maxItems)]
+		}
+		return len(fks), err
+	})
+	run("views", func() (int, error) {
+		views, err := o.OverviewViews(ctx, schemaName, maxItems+1)
+		if err == nil {
+			overview.Views = views[:min(len(views), maxItems)]
+		}
+		return len(views), err
+	})
+	run("routines", func() (int, error) {
+		routines, err := o.OverviewRoutines(ctx, schemaName, maxItems+1)
+		if err == nil {
+			overview.Routines = routines[:min(len(routines), maxItems)]
+		}
+		return len(routines), err
+	})
+
+	wg.Wait()
+	sort.Strings(overview.Truncated)
+	return overview
+}
@@ -3,26 +3,142 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
 )
 
+// postgresQueryOptions are the settings that may be applied with SET LOCAL for a single query
+var postgresQueryOptions = map[string]bool{
+	"work_mem":                        true,
+	"statement_timeout":               true,
+	"random_page_cost":                true,
+	"jit":                             true,
+	"max_parallel_workers_per_gather": true,
+	"enable_seqscan":                  true,
+	"enable_indexscan":                true,
+	"enable_indexonlyscan":            true,
+	"enable_bitmapscan":               true,
+	"enable_hashjoin":                 true,
+	"enable_mergejoin":                true,
+	"enable_nestloop":                 true,
+	"enable_sort":                     true,
+}
+
+// postgresProtectedOptions are connection parameters POSTGRES_OPTIONS may not set,
+// because they choose the server, credentials, or TLS behavior
+var postgresProtectedOptions = map[string]bool{
+	"host":        true,
+	"hostaddr":    true,
+	"port":        true,
+	"dbname":      true,
+	"user":        true,
+	"password":    true,
+	"passfile":    true,
+	"sslmode":     true,
+	"sslcert":     true,
+	"sslkey":      true,
+	"sslrootcert": true,
+	"sslpassword": true,
+	"sslinline":   true,
+	"sslsni":      true,
+}
+
 type PostgresAdapter struct {
 	BaseAdapter
-	connectionString string
+	url     string
+	appName string
+	options string
+
+	// excludedSchemas are left out of ListSchemas
+	excludedSchemas []string
 }
 
-func NewPostgresAdapter(connectionString string) *PostgresAdapter {
+func NewPostgresAdapter(cfg *Config) *PostgresAdapter {
 	return &PostgresAdapter{
 		BaseAdapter: BaseAdapter{
-			name:    "postgres",
-			enabled: connectionString != "",
+			name:         "postgres",
+			enabled:      cfg.PostgresURL != "",
+			policy:       NewReadOnlyPolicy(cfg),
+			results:      NewResultOptions(cfg),
+			maxOpenConns: cfg.DBMaxOpenConns,
 		},
-		connectionString: connectionString,
+		url:     cfg.PostgresURL,
+		appName: cfg.AppName,
+		options: cfg.PostgresOptions,
+
+		excludedSchemas: cfg.PostgresExcludedSchemas,
+	}
+}
+
+// postgresConnectionString sets application_name on a URL or key=value connection string
+// so the server's connections are identifiable in pg_stat_activity. An
+// application_name already present in the connection string takes precedence.
+func postgresConnectionString(connectionString, appName string) string {
+	if connectionString == "" || appName == "" || strings.Contains(connectionString, "application_name") {
+		return connectionString
+	}
+
+	if strings.HasPrefix(connectionString, "postgres://") || strings.HasPrefix(connectionString, "postgresql://") {
+		u, err := url.Parse(connectionString)
+		if err != nil {
+			return connectionString
+		}
+		q := u.Query()
+		q.Set("application_name", appName)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(appName)
+	return fmt.Sprintf("%s application_name='%s'", connectionString, value)
+}
+
+// postgresConnectionOptions adds POSTGRES_OPTIONS parameters to a URL or key=value
+// connection string. A parameter already present in the connection string is kept,
+// and the option is skipped with a warning.
+func postgresConnectionOptions(connectionString string, options []connectionOption) (string, error) {
+	if len(options) == 0 {
+		return connectionString, nil
 	}
+
+	if strings.HasPrefix(connectionString, "postgres://") || strings.HasPrefix(connectionString, "postgresql://") {
+		u, err := url.Parse(connectionString)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse connection URL: %w", err)
+		}
+		q := u.Query()
+		for _, opt := range options {
+			if q.Has(opt.Key) {
+				log.Warn().Str("option", opt.Key).Msg("POSTGRES_OPTIONS entry ignored, already set in POSTGRES_URL")
+				continue
+			}
+			q.Set(opt.Key, opt.Value)
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+
+	var b strings.Builder
+	b.WriteString(connectionString)
+	for _, opt := range options {
+		if regexp.MustCompile(`(^|\s)` + regexp.QuoteMeta(opt.Key) + `\s*=`).MatchString(connectionString) {
+			log.Warn().Str("option", opt.Key).Msg("POSTGRES_OPTIONS entry ignored, already set in POSTGRES_URL")
+			continue
+		}
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(opt.Value)
+		fmt.Fprintf(&b, " %s='%s'", opt.Key, value)
+	}
+	return b.String(), nil
 }
 
 func (p *PostgresAdapter) Connect() error {
@@ -30,30 +146,50 @@ func (p *PostgresAdapter) Connect() error {
 		return nil
 	}
 
-	db, err := sql.Open("postgres", p.connectionString)
+	options, err := parseConnectionOptions(p.options, postgresProtectedOptions)
+	if err != nil {
+		return fmt.Errorf("invalid POSTGRES_OPTIONS: %w", err)
+	}
+	connectionString, err := postgresConnectionOptions(p.url, options)
+	if err != nil {
+		return fmt.Errorf("failed to open postgres connection: %w", err)
+	}
+
+	db, err := sql.Open("postgres", postgresConnectionString(connectionString, p.appName))
 	if err != nil {
 		return fmt.Errorf("failed to open postgres connection: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := retryWhileStarting(context.Background(), db.Ping); err != nil {
 		db.Close()
 		return fmt.Errorf("failed to ping postgres: %w", err)
 	}
+	db.SetMaxOpenConns(p.maxOpenConns)
 
 	p.db = db
 	log.Info().Msg("PostgreSQL adapter connected")
 	return nil
 }
 
+// isDatabaseStarting reports whether err is PostgreSQL's cannot_connect_now
+// (SQLSTATE 57P03), sent while the server is starting up or in recovery
+func isDatabaseStarting(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57P03"
+}
+
+// ListSchemas returns the schemas of the current database, leaving out the
+// configured system schemas
 func (p *PostgresAdapter) ListSchemas(ctx context.Context) ([]Schema, error) {
-	query := `
+	condition, args := excludedSchemasCondition("schema_name", p.excludedSchemas, false)
+	query := fmt.Sprintf(`
 		SELECT schema_name 
 		FROM information_schema.schemata 
-		WHERE schema_name NOT IN ('pg_catalog', 'information_schema')
+		WHERE %s
 		ORDER BY schema_name
-	`
+	`, condition)
 
-	rows, err := p.db.QueryContext(ctx, query)
+	rows, err := p.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list schemas: %w", err)
 	}
@@ -71,6 +207,42 @@ func (p *PostgresAdapter) ListSchemas(ctx context.Context) ([]Schema, error) {
 	return schemas, rows.Err()
 }
 
+// ListDatabases returns the databases on the server, except templates. Size is
+// omitted for databases the user cannot connect to. Only the current database can
+// be queried; reaching another one needs a separate POSTGRES_URL.
+func (p *PostgresAdapter) ListDatabases(ctx context.Context) ([]Database, error) {
+	query := `
+		SELECT d.datname, d.datname = current_database(), pg_get_userbyid(d.datdba),
+			pg_encoding_to_char(d.encoding),
+			CASE WHEN has_database_privilege(d.oid, 'CONNECT') THEN pg_database_size(d.oid) END
+		FROM pg_database d
+		WHERE NOT d.datistemplate
+		ORDER BY d.datname
+	`
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	defer rows.Close()
+
+	databases := []Database{}
+	for rows.Next() {
+		var db Database
+		var size sql.NullInt64
+		if err := rows.Scan(&db.Name, &db.Current, &db.Owner, &db.Encoding, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan database: %w", err)
+		}
+		if size.Valid {
+			db.SizeBytes = &size.Int64
+			db.Size = formatBytes(size.Int64)
+		}
+		databases = append(databases, db)
+	}
+
+	return databases, rows.Err()
+}
+
 func (p *PostgresAdapter) GetSchemaDDL(ctx context.Context, schemaName string) (string, error) {
 	var ddls []string
 
@@ -182,18 +354,1543 @@ func (p *PostgresAdapter) GetSchemaDDL(ctx context.Context, schemaName string) (
 }
 
 func (p *PostgresAdapter) ExecuteSelect(ctx context.Context, query string) (QueryResult, error) {
-	query = strings.TrimSpace(query)
-	queryLower := strings.ToLower(query)
+	query, err := validateReadOnlyQuery(query, p.policy)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	conn, err := p.acquireConn(ctx)
+	if err != nil {
+		return QueryResult{}, err
+	}
+	defer conn.Close()
 
-	if !strings.HasPrefix(queryLower, "select") && !strings.HasPrefix(queryLower, "with") {
-		return QueryResult{}, fmt.Errorf("only SELECT queries are allowed")
+	if opts := queryOptionsFrom(ctx); len(opts) > 0 {
+		return p.executeWithOptions(ctx, conn, query, opts)
 	}
 
-	rows, err := p.db.QueryContext(ctx, query)
+	rows, err := conn.QueryContext(ctx, query, queryArgsFrom(ctx)...)
+	if err != nil {
+		return QueryResult{}, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanQueryResult(rows, resultOptionsFrom(ctx, p.results))
+}
+
+// executeWithOptions runs a query in its own transaction with SET LOCAL settings,
+// so the settings are discarded when the transaction ends
+func (p *PostgresAdapter) executeWithOptions(ctx context.Context, conn *sql.Conn, query string, opts QueryOptions) (QueryResult, error) {
+	if err := opts.validate(postgresQueryOptions); err != nil {
+		return QueryResult{}, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return QueryResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, name := range opts.names() {
+		set := fmt.Sprintf("SET LOCAL %s = %s", name, quotePostgresLiteral(opts[name]))
+		if _, err := tx.ExecContext(ctx, set); err != nil {
+			return QueryResult{}, fmt.Errorf("failed to apply query option %s: %w", name, err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, queryArgsFrom(ctx)...)
 	if err != nil {
 		return QueryResult{}, fmt.Errorf("query execution failed: %w", err)
 	}
 	defer rows.Close()
 
-	return scanQueryResult(rows)
+	return scanQueryResult(rows, resultOptionsFrom(ctx, p.results))
+}
+
+// ForeignTable describes a foreign table and the server it is federated to
+type ForeignTable struct {
+	Schema        string `json:"schema"`
+	Name          string `json:"name"`
+	Server        string `json:"server"`
+	Wrapper       string `json:"wrapper"`
+	ServerOptions string `json:"server_options,omitempty"`
+}
+
+// ListForeignTables lists foreign tables, optionally restricted to one schema
+func (p *PostgresAdapter) ListForeignTables(ctx context.Context, schemaName string) ([]ForeignTable, error) {
+	query := `
+		SELECT
+			ft.foreign_table_schema,
+			ft.foreign_table_name,
+			ft.foreign_server_name,
+			w.fdwname,
+			COALESCE(array_to_string(s.srvoptions, ', '), '')
+		FROM information_schema.foreign_tables ft
+		JOIN pg_foreign_server s ON s.srvname = ft.foreign_server_name
+		JOIN pg_foreign_data_wrapper w ON w.oid = s.srvfdw
+		WHERE $1::text = '' OR ft.foreign_table_schema = $1::text
+		ORDER BY ft.foreign_table_schema, ft.foreign_table_name
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []ForeignTable{}
+	for rows.Next() {
+		var t ForeignTable
+		if err := rows.Scan(&t.Schema, &t.Name, &t.Server, &t.Wrapper, &t.ServerOptions); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign table: %w", err)
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+// MaterializedView describes a materialized view and whether it holds data.
+// PostgreSQL does not record when a materialized view was last refreshed.
+type MaterializedView struct {
+	Schema     string `json:"schema"`
+	Name       string `json:"name"`
+	Owner      string `json:"owner"`
+	Populated  bool   `json:"populated"`
+	HasIndexes bool   `json:"has_indexes"`
+	SizeBytes  int64  `json:"size_bytes"`
+	Size       string `json:"size"`
+	Definition string `json:"definition"`
+}
+
+// ListMaterializedViews returns the materialized views in a schema, or in every
+// schema when schemaName is empty. Unpopulated views (created WITH NO DATA and
+// never refreshed) cannot be queried until REFRESH MATERIALIZED VIEW runs.
+func (p *PostgresAdapter) ListMaterializedViews(ctx context.Context, schemaName string) ([]MaterializedView, error) {
+	query := `
+		SELECT
+			m.schemaname,
+			m.matviewname,
+			m.matviewowner,
+			m.ispopulated,
+			m.hasindexes,
+			pg_total_relation_size(format('%I.%I', m.schemaname, m.matviewname)::regclass),
+			m.definition
+		FROM pg_matviews m
+		WHERE $1::text = '' OR m.schemaname = $1::text
+		ORDER BY m.schemaname, m.matviewname
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list materialized views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []MaterializedView{}
+	for rows.Next() {
+		var v MaterializedView
+		if err := rows.Scan(&v.Schema, &v.Name, &v.Owner, &v.Populated, &v.HasIndexes, &v.SizeBytes, &v.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan materialized view: %w", err)
+		}
+		v.Size = formatBytes(v.SizeBytes)
+		v.Definition = strings.TrimSpace(v.Definition)
+		views = append(views, v)
+	}
+
+	return views, rows.Err()
+}
+
+// OverviewTables returns the tables of a schema with the planner's row estimate.
+// Partitions are left out; their partitioned parent is listed instead.
+func (p *PostgresAdapter) OverviewTables(ctx context.Context, schemaName string, limit int) ([]OverviewTable, error) {
+	query := `
+		SELECT c.relname,
+			CASE c.relkind WHEN 'p' THEN 'partitioned table' ELSE 'table' END,
+			CASE WHEN c.reltuples < 0 OR c.reltuples = 0 AND c.relpages = 0 THEN NULL ELSE c.reltuples::bigint END,
+			COALESCE(obj_description(c.oid, 'pg_class'), '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+		ORDER BY c.relname
+		LIMIT $2
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []OverviewTable{}
+	for rows.Next() {
+		var t OverviewTable
+		var estimate sql.NullInt64
+		if err := rows.Scan(&t.Name, &t.Type, &estimate, &t.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		if estimate.Valid {
+			t.EstimatedRows = &estimate.Int64
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+// SchemaForeignKeys returns the foreign keys declared on the tables of a schema,
+// ordered by table and constraint name
+func (p *PostgresAdapter) SchemaForeignKeys(ctx context.Context, schemaName string, limit int) ([]SchemaForeignKey, error) {
+	query := `
+		SELECT c.relname, con.conname, pn.nspname, pc.relname,
+			ARRAY(SELECT a.attname::text FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.ord),
+			ARRAY(SELECT a.attname::text FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum ORDER BY k.ord)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class pc ON pc.oid = con.confrelid
+		JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE con.contype = 'f' AND n.nspname = $1 AND NOT c.relispartition
+		ORDER BY c.relname, con.conname
+		LIMIT $2
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	fks := []SchemaForeignKey{}
+	for rows.Next() {
+		var fk SchemaForeignKey
+		if err := rows.Scan(&fk.Table, &fk.Name, &fk.ParentSchema, &fk.ParentTable,
+			(*pq.StringArray)(&fk.Columns), (*pq.StringArray)(&fk.ParentColumns)); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		fks = append(fks, fk)
+	}
+
+	return fks, rows.Err()
+}
+
+// OverviewViews returns the views and materialized views of a schema
+func (p *PostgresAdapter) OverviewViews(ctx context.Context, schemaName string, limit int) ([]OverviewView, error) {
+	query := `
+		SELECT c.relname, c.relkind = 'm'
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('v', 'm')
+		ORDER BY c.relname
+		LIMIT $2
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []OverviewView{}
+	for rows.Next() {
+		var v OverviewView
+		if err := rows.Scan(&v.Name, &v.Materialized); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, v)
+	}
+
+	return views, rows.Err()
+}
+
+// OverviewRoutines returns the functions and procedures of a schema with their
+// signatures. Routines installed by an extension are left out, since extensions
+// such as PostGIS add hundreds of them.
+func (p *PostgresAdapter) OverviewRoutines(ctx context.Context, schemaName string, limit int) ([]OverviewRoutine, error) {
+	query := `
+		SELECT p.proname,
+			CASE p.prokind WHEN 'p' THEN 'PROCEDURE' WHEN 'a' THEN 'AGGREGATE' WHEN 'w' THEN 'WINDOW' ELSE 'FUNCTION' END,
+			pg_get_function_identity_arguments(p.oid) AS arguments,
+			COALESCE(pg_get_function_result(p.oid), '')
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e'
+			)
+		ORDER BY p.proname, arguments
+		LIMIT $2
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routines: %w", err)
+	}
+	defer rows.Close()
+
+	routines := []OverviewRoutine{}
+	for rows.Next() {
+		var r OverviewRoutine
+		if err := rows.Scan(&r.Name, &r.Type, &r.Arguments, &r.Returns); err != nil {
+			return nil, fmt.Errorf("failed to scan routine: %w", err)
+		}
+		routines = append(routines, r)
+	}
+
+	return routines, rows.Err()
+}
+
+// quotePostgresIdent quotes an identifier for safe inclusion in a query
+func quotePostgresIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteIdent quotes an identifier for PostgreSQL
+func (p *PostgresAdapter) QuoteIdent(name string) string {
+	return quotePostgresIdent(name)
+}
+
+// quotePostgresLiteral quotes a string literal for safe inclusion in a query
+func quotePostgresLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// ListTablesLike lists base tables and views in a schema whose names match a LIKE pattern
+func (p *PostgresAdapter) ListTablesLike(ctx context.Context, schemaName, pattern string) ([]string, error) {
+	query := `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = $1
+			AND table_name LIKE $2
+			AND table_type IN ('BASE TABLE', 'VIEW', 'FOREIGN')
+		ORDER BY table_name
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+// buildUnionQuery builds a UNION ALL over tables projecting columns, tagging each row
+// with its source table and applying a global limit
+func buildUnionQuery(schemaName string, tables, columns []string, limit int) string {
+	projection := "*"
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = quotePostgresIdent(col)
+		}
+		projection = strings.Join(quoted, ", ")
+	}
+
+	parts := make([]string, len(tables))
+	for i, table := range tables {
+		parts[i] = fmt.Sprintf("SELECT %s AS source_table, %s FROM %s.%s",
+			quotePostgresLiteral(table), projection, quotePostgresIdent(schemaName), quotePostgresIdent(table))
+	}
+
+	return fmt.Sprintf("SELECT * FROM (%s) AS matched LIMIT %d", strings.Join(parts, " UNION ALL "), limit)
+}
+
+// LargestTables returns the largest tables in a schema by total relation size, descending
+func (p *PostgresAdapter) LargestTables(ctx context.Context, schemaName string, limit int) ([]TableSize, error) {
+	query := `
+		SELECT c.relname, pg_total_relation_size(c.oid) AS size
+		FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1
+			AND c.relkind IN ('r', 'p', 'm')
+		ORDER BY size DESC, c.relname
+		LIMIT $2
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table sizes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTableSizes(rows)
+}
+
+// ReplicationStatus describes whether the server is a standby and how far behind it is
+type ReplicationStatus struct {
+	IsStandby         bool     `json:"is_standby"`
+	ReceiveLSN        *string  `json:"receive_lsn,omitempty"`
+	ReplayLSN         *string  `json:"replay_lsn,omitempty"`
+	LastReplayTime    *string  `json:"last_replay_time,omitempty"`
+	LagSeconds        *float64 `json:"lag_seconds,omitempty"`
+	WalReceiverStatus *string  `json:"wal_receiver_status,omitempty"`
+	PrimaryHost       *string  `json:"primary_host,omitempty"`
+	PrimaryPort       *int64   `json:"primary_port,omitempty"`
+	Message           string   `json:"message"`
+}
+
+// ReplicationStatus reports standby state, replay lag, and the upstream primary
+func (p *PostgresAdapter) ReplicationStatus(ctx context.Context) (*ReplicationStatus, error) {
+	status := &ReplicationStatus{}
+
+	if err := p.db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&status.IsStandby); err != nil {
+		return nil, fmt.Errorf("failed to check recovery state: %w", err)
+	}
+
+	if !status.IsStandby {
+		status.Message = "Server is a primary; data is current"
+		return status, nil
+	}
+
+	// Lag is zero when everything received has been replayed, otherwise it is
+	// the time since the last replayed transaction
+	lagQuery := `
+		SELECT
+			pg_last_wal_receive_lsn()::text,
+			pg_last_wal_replay_lsn()::text,
+			to_char(pg_last_xact_replay_timestamp() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			CASE
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8
+			END
+	`
+	if err := p.db.QueryRowContext(ctx, lagQuery).Scan(
+		&status.ReceiveLSN, &status.ReplayLSN, &status.LastReplayTime, &status.LagSeconds,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get replay lag: %w", err)
+	}
+
+	receiverQuery := `SELECT status, sender_host, sender_port FROM pg_stat_wal_receiver`
+	err := p.db.QueryRowContext(ctx, receiverQuery).Scan(&status.WalReceiverStatus, &status.PrimaryHost, &status.PrimaryPort)
+	switch {
+	case err == sql.ErrNoRows:
+		status.Message = "Server is a standby with no active WAL receiver (replaying from archive or disconnected); lag may be growing"
+	case err != nil:
+		return nil, fmt.Errorf("failed to read WAL receiver status: %w", err)
+	default:
+		status.Message = "Server is a standby; query results may lag the primary by lag_seconds"
+	}
+
+	return status, nil
+}
+
+// CheckConstraint is a table check constraint and its expression
+type CheckConstraint struct {
+	Table      string `json:"table"`
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// ListCheckConstraints lists check constraints in a schema, optionally for one table
+func (p *PostgresAdapter) ListCheckConstraints(ctx context.Context, schemaName, tableName string) ([]CheckConstraint, error) {
+	query := `
+		SELECT c.relname, con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON con.conrelid = c.oid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1
+			AND ($2::text = '' OR c.relname = $2::text)
+			AND con.contype = 'c'
+		ORDER BY c.relname, con.conname
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list check constraints: %w", err)
+	}
+	defer rows.Close()
+
+	constraints := []CheckConstraint{}
+	for rows.Next() {
+		var c CheckConstraint
+		if err := rows.Scan(&c.Table, &c.Name, &c.Expression); err != nil {
+			return nil, fmt.Errorf("failed to scan check constraint: %w", err)
+		}
+		constraints = append(constraints, c)
+	}
+
+	return constraints, rows.Err()
+}
+
+// ListPartitions returns the partition key and child partitions with their bounds
+// for a table. Non-partitioned tables are reported with Partitioned set to false.
+func (p *PostgresAdapter) ListPartitions(ctx context.Context, schemaName, tableName string) (*PartitionInfo, error) {
+	info := &PartitionInfo{Table: tableName, Partitions: []Partition{}}
+
+	var relkind string
+	var partitionKey sql.NullString
+	parentQuery := `
+		SELECT c.relkind::text, pg_get_partkeydef(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1 AND c.relname = $2
+	`
+	err := p.db.QueryRowContext(ctx, parentQuery, schemaName, tableName).Scan(&relkind, &partitionKey)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table %s.%s not found", schemaName, tableName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up table: %w", err)
+	}
+
+	if relkind != "p" {
+		return info, nil
+	}
+	info.Partitioned = true
+	info.PartitionKey = partitionKey.String
+
+	partitionsQuery := `
+		SELECT child.relname, COALESCE(pg_get_expr(child.relpartbound, child.oid), ''), child.relkind = 'p'
+		FROM pg_inherits i
+		JOIN pg_class parent ON i.inhparent = parent.oid
+		JOIN pg_namespace n ON parent.relnamespace = n.oid
+		JOIN pg_class child ON i.inhrelid = child.oid
+		WHERE n.nspname = $1 AND parent.relname = $2
+		ORDER BY child.relname
+	`
+	rows, err := p.db.QueryContext(ctx, partitionsQuery, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var part Partition
+		if err := rows.Scan(&part.Name, &part.Bound, &part.SubPartitioned); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		info.Partitions = append(info.Partitions, part)
+	}
+
+	return info, rows.Err()
+}
+
+// HasExtension reports whether an extension is installed in the current database
+func (p *PostgresAdapter) HasExtension(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := p.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1)", name).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check extension %s: %w", name, err)
+	}
+	return exists, nil
+}
+
+// Extension is a PostgreSQL extension available on the server. InstalledVersion is
+// set when the extension is installed in the current database.
+type Extension struct {
+	Name             string  `json:"name"`
+	DefaultVersion   *string `json:"default_version"`
+	InstalledVersion *string `json:"installed_version,omitempty"`
+	Schema           *string `json:"schema,omitempty"`
+	Comment          *string `json:"comment,omitempty"`
+}
+
+// Extensions returns the extensions installed in the current database and those
+// available on the server but not installed
+func (p *PostgresAdapter) Extensions(ctx context.Context) (installed, available []Extension, err error) {
+	query := `
+		SELECT a.name, a.default_version, e.extversion, n.nspname, a.comment
+		FROM pg_available_extensions a
+		LEFT JOIN pg_extension e ON e.extname = a.name
+		LEFT JOIN pg_namespace n ON n.oid = e.extnamespace
+		ORDER BY a.name
+	`
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list extensions: %w", err)
+	}
+	defer rows.Close()
+
+	installed, available = []Extension{}, []Extension{}
+	for rows.Next() {
+		var e Extension
+		if err := rows.Scan(&e.Name, &e.DefaultVersion, &e.InstalledVersion, &e.Schema, &e.Comment); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan extension: %w", err)
+		}
+		if e.InstalledVersion != nil {
+			installed = append(installed, e)
+		} else {
+			available = append(available, e)
+		}
+	}
+
+	return installed, available, rows.Err()
+}
+
+// ColumnExists reports whether a table or view in a schema has the given column
+func (p *PostgresAdapter) ColumnExists(ctx context.Context, schemaName, tableName, columnName string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM information_schema.columns
+			WHERE table_schema = $1 AND table_name = $2 AND column_name = $3
+		)
+	`
+
+	var exists bool
+	if err := p.db.QueryRowContext(ctx, query, schemaName, tableName, columnName).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check column: %w", err)
+	}
+	return exists, nil
+}
+
+// buildFuzzySearchQuery builds a pg_trgm similarity search over one column, most similar
+// rows first, selecting projection (a validated select list)
+func buildFuzzySearchQuery(schemaName, tableName, columnName, term, projection string, limit int) string {
+	column := quotePostgresIdent(columnName) + "::text"
+	literal := quotePostgresLiteral(term)
+
+	return fmt.Sprintf("SELECT similarity(%s, %s) AS similarity, %s FROM %s.%s WHERE %s %% %s ORDER BY similarity DESC LIMIT %d",
+		column, literal, projection, quotePostgresIdent(schemaName), quotePostgresIdent(tableName), column, literal, limit)
+}
+
+// tableExportBatchRows is the number of rows ExportTable fetches from its cursor at a time
+const tableExportBatchRows = 1000
+
+// tableExportQuery builds the SELECT a table export reads through its cursor
+func tableExportQuery(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT * FROM %s.%s", quotePostgresIdent(schemaName), quotePostgresIdent(tableName))
+}
+
+// ExportTable reads every row of query, a table export's SELECT, through a
+// server-side cursor in a read-only transaction and passes each batch of at most
+// tableExportBatchRows rows to emit, so the table is never held in memory. Each FETCH
+// is bounded by batchTimeout (0 for none). It stops after maxRows rows (0 for no
+// limit) and reports whether rows were left unread. The cursor and transaction are
+// closed when it returns, including when emit fails because the client went away or
+// ctx ends. observe is called after the DECLARE and after each FETCH with its batch
+// and error.
+func (p *PostgresAdapter) ExportTable(ctx context.Context, query string, maxRows int, batchTimeout time.Duration, emit func(QueryResult) error, observe func(ctx context.Context, statement string, result QueryResult, err error)) (int, bool, error) {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	declare := "DECLARE mcp_export NO SCROLL CURSOR FOR " + query
+	_, err = tx.ExecContext(ctx, declare)
+	observe(ctx, declare, QueryResult{}, err)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to declare export cursor: %w", err)
+	}
+
+	fetch := func(n int) (QueryResult, error) {
+		batchCtx := ctx
+		if batchTimeout > 0 {
+			var cancel context.CancelFunc
+			batchCtx, cancel = context.WithTimeout(ctx, batchTimeout)
+			defer cancel()
+		}
+
+		statement := fmt.Sprintf("FETCH %d FROM mcp_export", n)
+		rows, err := tx.QueryContext(batchCtx, statement)
+		if err != nil {
+			observe(ctx, statement, QueryResult{}, err)
+			return QueryResult{}, fmt.Errorf("failed to fetch export batch: %w", err)
+		}
+		defer rows.Close()
+
+		batch, err := scanResultSet(rows, p.results)
+		observe(ctx, statement, batch, err)
+		return batch, err
+	}
+
+	total := 0
+	for {
+		n := tableExportBatchRows
+		if maxRows > 0 && maxRows-total < n {
+			n = maxRows - total
+		}
+		if n == 0 {
+			// The limit is reached; one more row tells whether the export is complete
+			more, err := fetch(1)
+			if err != nil {
+				return total, false, err
+			}
+			return total, len(more.Rows) > 0, nil
+		}
+
+		batch, err := fetch(n)
+		if err != nil {
+			return total, false, err
+		}
+		total += len(batch.Rows)
+		if err := emit(batch); err != nil {
+			return total, false, err
+		}
+		if len(batch.Rows) < n {
+			return total, false, nil
+		}
+	}
+}
+
+// buildFindValueQuery builds a search for rows of schemaName.tableName where any of
+// columns, cast to text, equals the value bound as $1
+func buildFindValueQuery(schemaName, tableName string, columns []string, limit int) string {
+	conditions := make([]string, len(columns))
+	for i, col := range columns {
+		conditions[i] = quotePostgresIdent(col) + "::text = $1"
+	}
+
+	return fmt.Sprintf("SELECT * FROM %s.%s WHERE %s LIMIT %d",
+		quotePostgresIdent(schemaName), quotePostgresIdent(tableName), strings.Join(conditions, " OR "), limit)
+}
+
+// PrimaryKeyColumns returns the primary key columns of a table in key order
+func (p *PostgresAdapter) PrimaryKeyColumns(ctx context.Context, schemaName, tableName string) ([]string, error) {
+	query := `
+		SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+			AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY'
+			AND tc.table_schema = $1 AND tc.table_name = $2
+		ORDER BY kcu.ordinal_position
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up primary key: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan primary key column: %w", err)
+		}
+		columns = append(columns, name)
+	}
+
+	return columns, rows.Err()
+}
+
+// ForeignKeys returns the foreign keys of a table, ordered by constraint name, with
+// columns in key order
+func (p *PostgresAdapter) ForeignKeys(ctx context.Context, schemaName, tableName string) ([]ForeignKey, error) {
+	query := `
+		SELECT con.conname, pn.nspname, pc.relname,
+			ARRAY(SELECT a.attname::text FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.ord),
+			ARRAY(SELECT a.attname::text FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum ORDER BY k.ord)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class pc ON pc.oid = con.confrelid
+		JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE con.contype = 'f' AND n.nspname = $1 AND c.relname = $2
+		ORDER BY con.conname
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		if err := rows.Scan(&fk.Name, &fk.ParentSchema, &fk.ParentTable,
+			(*pq.StringArray)(&fk.Columns), (*pq.StringArray)(&fk.ParentColumns)); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		fks = append(fks, fk)
+	}
+
+	return fks, rows.Err()
+}
+
+// GetRowQuery builds a lookup of at most one row by primary key, optionally only the
+// given columns, and its bound arguments. key must name exactly the primary key
+// columns. It reads the catalog to validate columns and find the key, but does not
+// run the lookup.
+func (p *PostgresAdapter) GetRowQuery(ctx context.Context, schemaName, tableName string, key map[string]interface{}, columns []string) (string, []interface{}, error) {
+	projection, err := p.selectList(ctx, schemaName, tableName, columns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pkColumns, err := p.PrimaryKeyColumns(ctx, schemaName, tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := primaryKeyArgs(pkColumns, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conditions := make([]string, len(pkColumns))
+	for i, col := range pkColumns {
+		conditions[i] = fmt.Sprintf("%s = $%d", quotePostgresIdent(col), i+1)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s LIMIT 1", projection,
+		quotePostgresIdent(schemaName), quotePostgresIdent(tableName), strings.Join(conditions, " AND "))
+
+	return query, args, nil
+}
+
+// buildExistsQuery builds a SELECT EXISTS check on schemaName.tableName. where may
+// use :name placeholders, which are bound from params as query parameters. A
+// semicolon in where is only allowed inside literals, identifiers, and comments.
+func buildExistsQuery(schemaName, tableName, where string, params map[string]interface{}) (string, []interface{}, error) {
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s.%s", quotePostgresIdent(schemaName), quotePostgresIdent(tableName))
+	if where = strings.TrimSpace(where); where != "" {
+		// Newlines keep a trailing line comment in where from swallowing the closing parentheses
+		query += fmt.Sprintf(" WHERE (\n%s\n)", where)
+	}
+	query += ") AS exists"
+
+	// Any semicolon outside quotes is followed by the closing parentheses, so it
+	// is rejected as the start of another statement
+	if _, err := trimStatementTerminator(query, false); err != nil {
+		return "", nil, fmt.Errorf("invalid where clause: %w", err)
+	}
+
+	return bindNamedParams(query, params)
+}
+
+// extractResultOptions converts extracted rows for replay as INSERT statements:
+// timestamps at full precision, NULL as nil, and no cell truncation
+var extractResultOptions = ResultOptions{TimestampLayout: time.RFC3339Nano}
+
+// ExtractRowsQuery builds a SELECT of up to limit rows (all columns, or only the
+// given ones) from a table, optionally filtered by a WHERE clause. It reads the
+// catalog to validate columns, but does not run the query. The where clause goes
+// through the same statement checks as any other read-only query.
+func (p *PostgresAdapter) ExtractRowsQuery(ctx context.Context, schemaName, tableName string, columns []string, where string, limit int) (string, error) {
+	projection, err := p.selectList(ctx, schemaName, tableName, columns)
+	if err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s", projection, quotePostgresIdent(schemaName), quotePostgresIdent(tableName))
+	if where = strings.TrimSpace(where); where != "" {
+		// Newlines keep a trailing line comment in where from swallowing the LIMIT
+		query += fmt.Sprintf(" WHERE (\n%s\n)", where)
+	}
+	query += fmt.Sprintf(" LIMIT %d", limit)
+
+	return validateReadOnlyQuery(query, p.policy)
+}
+
+// buildInsertStatements renders rows as INSERT statements for schemaName.tableName
+func buildInsertStatements(schemaName, tableName string, result QueryResult) []string {
+	quotedColumns := make([]string, len(result.Columns))
+	for i, col := range result.Columns {
+		quotedColumns[i] = quotePostgresIdent(col)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES ",
+		quotePostgresIdent(schemaName), quotePostgresIdent(tableName), strings.Join(quotedColumns, ", "))
+
+	statements := make([]string, len(result.Rows))
+	for i, row := range result.Rows {
+		values := make([]string, len(row))
+		for j, v := range row {
+			typeName := ""
+			if j < len(result.Types) {
+				typeName = result.Types[j]
+			}
+			values[j] = postgresLiteral(v, typeName)
+		}
+		statements[i] = prefix + "(" + strings.Join(values, ", ") + ");"
+	}
+	return statements
+}
+
+// decimalLiteralPattern matches the text form of a finite NUMERIC value
+var decimalLiteralPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// postgresLiteral renders a converted result value of a column of type typeName as a
+// SQL literal. Numbers and booleans are emitted bare, including NUMERIC values the
+// driver returns as text. Other strings are quoted and left for PostgreSQL to coerce
+// to the column type on insert.
+func postgresLiteral(v interface{}, typeName string) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if val {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case int:
+		return strconv.Itoa(val)
+	case int32:
+		return strconv.FormatInt(int64(val), 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float64:
+		return postgresFloatLiteral(val, 64)
+	case float32:
+		return postgresFloatLiteral(float64(val), 32)
+	case BinaryValue:
+		return fmt.Sprintf("decode(%s, 'base64')", quotePostgresLiteral(val.Binary))
+	case string:
+		if isNumericType(typeName) && decimalLiteralPattern.MatchString(val) {
+			return val
+		}
+		return quotePostgresLiteral(val)
+	default:
+		return quotePostgresLiteral(fmt.Sprint(val))
+	}
+}
+
+// postgresFloatLiteral renders a float bare, or quoted for NaN and infinities, which
+// PostgreSQL only accepts as strings
+func postgresFloatLiteral(f float64, bitSize int) string {
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return quotePostgresLiteral(s)
+	}
+	return s
+}
+
+// CollationInfo returns the database encoding and default collation with the
+// collation of every collatable column in a schema. PostgreSQL has no schema- or
+// table-level collation; "default" means the database collation.
+func (p *PostgresAdapter) CollationInfo(ctx context.Context, schemaName, tableName string) (*CollationInfo, error) {
+	info := &CollationInfo{Schema: schemaName, Tables: []TableCollation{}}
+
+	databaseQuery := `
+		SELECT pg_encoding_to_char(encoding), datcollate, datctype
+		FROM pg_database
+		WHERE datname = current_database()
+	`
+	if err := p.db.QueryRowContext(ctx, databaseQuery).Scan(&info.CharacterSet, &info.Collation, &info.CType); err != nil {
+		return nil, fmt.Errorf("failed to get database collation: %w", err)
+	}
+
+	columnsQuery := `
+		SELECT c.relname, a.attname, co.collname
+		FROM pg_attribute a
+		JOIN pg_class c ON a.attrelid = c.oid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		JOIN pg_collation co ON a.attcollation = co.oid
+		WHERE n.nspname = $1
+			AND ($2::text = '' OR c.relname = $2::text)
+			AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum
+	`
+
+	rows, err := p.db.QueryContext(ctx, columnsQuery, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column collations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+		var col ColumnCollation
+		if err := rows.Scan(&table, &col.Column, &col.Collation); err != nil {
+			return nil, fmt.Errorf("failed to scan column collation: %w", err)
+		}
+		info.Tables = addColumnCollation(info.Tables, table, col)
+	}
+
+	return info, rows.Err()
+}
+
+// TableColumns returns the column names of a table or view in ordinal order
+func (p *PostgresAdapter) TableColumns(ctx context.Context, schemaName, tableName string) ([]string, error) {
+	query := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2
+		ORDER BY ordinal_position
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s.%s not found", schemaName, tableName)
+	}
+	return columns, nil
+}
+
+// selectList builds a validated select list for the requested columns of a table
+func (p *PostgresAdapter) selectList(ctx context.Context, schemaName, tableName string, columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "*", nil
+	}
+	available, err := p.TableColumns(ctx, schemaName, tableName)
+	if err != nil {
+		return "", err
+	}
+	return buildSelectList(columns, available, quotePostgresIdent)
+}
+
+// Sequence describes a sequence, its current value, and the column that owns it
+type Sequence struct {
+	Name        string   `json:"name"`
+	DataType    string   `json:"data_type"`
+	LastValue   *int64   `json:"last_value"`
+	Increment   int64    `json:"increment"`
+	MaxValue    int64    `json:"max_value"`
+	UsedPercent *float64 `json:"used_percent,omitempty"`
+	OwnedBy     string   `json:"owned_by,omitempty"`
+}
+
+// ListSequences returns the sequences in a schema with their current values. LastValue
+// is null for sequences that have never been used or that the user cannot read.
+func (p *PostgresAdapter) ListSequences(ctx context.Context, schemaName string) ([]Sequence, error) {
+	query := `
+		SELECT s.sequencename, s.data_type::text, s.last_value, s.increment_by, s.max_value,
+			COALESCE(owner.owned_by, '')
+		FROM pg_sequences s
+		LEFT JOIN LATERAL (
+			SELECT t.relname || '.' || a.attname AS owned_by
+			FROM pg_class seq
+			JOIN pg_namespace sn ON seq.relnamespace = sn.oid
+			JOIN pg_depend d ON d.objid = seq.oid
+				AND d.classid = 'pg_class'::regclass
+				AND d.refclassid = 'pg_class'::regclass
+				AND d.deptype IN ('a', 'i')
+			JOIN pg_class t ON d.refobjid = t.oid
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+			WHERE sn.nspname = s.schemaname AND seq.relname = s.sequencename
+			LIMIT 1
+		) owner ON true
+		WHERE s.schemaname = $1
+		ORDER BY s.sequencename
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sequences: %w", err)
+	}
+	defer rows.Close()
+
+	sequences := []Sequence{}
+	for rows.Next() {
+		var seq Sequence
+		var lastValue sql.NullInt64
+		if err := rows.Scan(&seq.Name, &seq.DataType, &lastValue, &seq.Increment, &seq.MaxValue, &seq.OwnedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan sequence: %w", err)
+		}
+		if lastValue.Valid {
+			seq.LastValue = &lastValue.Int64
+			if seq.Increment > 0 && seq.MaxValue > 0 {
+				used := math.Round(float64(lastValue.Int64)/float64(seq.MaxValue)*10000) / 100
+				seq.UsedPercent = &used
+			}
+		}
+		sequences = append(sequences, seq)
+	}
+
+	return sequences, rows.Err()
+}
+
+// RoutineDDL returns the CREATE FUNCTION/PROCEDURE statement of a routine. Overloaded
+// functions need arguments, the input argument types (e.g. "integer, text"), to pick
+// one; nil matches any overload.
+func (p *PostgresAdapter) RoutineDDL(ctx context.Context, schemaName, routineName string, arguments *string) (*RoutineDefinition, error) {
+	query := `
+		SELECT
+			CASE p.prokind WHEN 'p' THEN 'PROCEDURE' ELSE 'FUNCTION' END,
+			pg_get_function_identity_arguments(p.oid),
+			pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1::text
+			AND p.proname = $2::text
+			AND p.prokind <> 'a'
+			AND ($3::text IS NULL OR p.oid = to_regprocedure(quote_ident($1::text) || '.' || quote_ident($2::text) || '(' || $3::text || ')'))
+		ORDER BY 2
+	`
+
+	var argTypes interface{}
+	if arguments != nil {
+		argTypes = strings.TrimSpace(*arguments)
+	}
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, routineName, argTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routine definition: %w", err)
+	}
+	defer rows.Close()
+
+	var routines []RoutineDefinition
+	for rows.Next() {
+		r := RoutineDefinition{Schema: schemaName, Name: routineName}
+		if err := rows.Scan(&r.Type, &r.Arguments, &r.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan routine definition: %w", err)
+		}
+		routines = append(routines, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(routines) {
+	case 0:
+		if arguments != nil {
+			return nil, fmt.Errorf("routine %s.%s(%s) not found", schemaName, routineName, *arguments)
+		}
+		return nil, fmt.Errorf("routine %s.%s not found", schemaName, routineName)
+	case 1:
+		return &routines[0], nil
+	}
+
+	signatures := make([]string, len(routines))
+	for i, r := range routines {
+		signatures[i] = fmt.Sprintf("%s(%s)", routineName, r.Arguments)
+	}
+	return nil, fmt.Errorf("routine %s.%s is overloaded; pass arguments to choose one of: %s", schemaName, routineName, strings.Join(signatures, "; "))
+}
+
+// TableFreshness reports when a table was last vacuumed and analyzed, how many rows
+// changed since, and optionally the latest value of a timestamp column
+type TableFreshness struct {
+	Schema               string      `json:"schema"`
+	Table                string      `json:"table"`
+	LastVacuum           *time.Time  `json:"last_vacuum"`
+	LastAutovacuum       *time.Time  `json:"last_autovacuum"`
+	LastAnalyze          *time.Time  `json:"last_analyze"`
+	LastAutoanalyze      *time.Time  `json:"last_autoanalyze"`
+	ModifiedSinceAnalyze int64       `json:"modified_since_analyze"`
+	UpdatedColumn        string      `json:"updated_column,omitempty"`
+	MaxUpdated           interface{} `json:"max_updated,omitempty"`
+}
+
+// TableFreshness reads a table's maintenance timestamps from pg_stat_user_tables. When
+// updatedColumn is set, MAX of that column is included, which reads the table (or an
+// index on the column).
+func (p *PostgresAdapter) TableFreshness(ctx context.Context, schemaName, tableName, updatedColumn string) (*TableFreshness, error) {
+	query := `
+		SELECT last_vacuum, last_autovacuum, last_analyze, last_autoanalyze, n_mod_since_analyze
+		FROM pg_stat_user_tables
+		WHERE schemaname = $1 AND relname = $2
+	`
+
+	f := &TableFreshness{Schema: schemaName, Table: tableName}
+	err := p.db.QueryRowContext(ctx, query, schemaName, tableName).Scan(
+		&f.LastVacuum, &f.LastAutovacuum, &f.LastAnalyze, &f.LastAutoanalyze, &f.ModifiedSinceAnalyze,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table %s.%s not found", schemaName, tableName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+
+	if updatedColumn == "" {
+		return f, nil
+	}
+
+	exists, err := p.ColumnExists(ctx, schemaName, tableName, updatedColumn)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("column %s not found in %s.%s", updatedColumn, schemaName, tableName)
+	}
+
+	maxQuery := fmt.Sprintf("SELECT MAX(%s) FROM %s.%s",
+		quotePostgresIdent(updatedColumn), quotePostgresIdent(schemaName), quotePostgresIdent(tableName))
+	var maxValue interface{}
+	if err := p.db.QueryRowContext(ctx, maxQuery).Scan(&maxValue); err != nil {
+		return nil, fmt.Errorf("failed to read max %s: %w", updatedColumn, err)
+	}
+	f.UpdatedColumn = updatedColumn
+
+	// An empty table or an all-NULL column has no max, not the NULL_REPRESENTATION marker
+	opts := p.results
+	opts.NullValue = nil
+	f.MaxUpdated = convertValue(maxValue, "", opts)
+
+	return f, nil
+}
+
+// ColumnStats is the planner's ANALYZE statistics for one column. NDistinct is the raw
+// pg_stats value (negative means a fraction of the row count); DistinctEstimate
+// converts it to a count when the table's row estimate is known.
+type ColumnStats struct {
+	Column           string    `json:"column"`
+	NullFraction     float64   `json:"null_fraction"`
+	NDistinct        float64   `json:"n_distinct"`
+	DistinctEstimate *int64    `json:"distinct_estimate"`
+	AvgWidth         int       `json:"avg_width_bytes"`
+	Correlation      *float64  `json:"correlation"`
+	MostCommonValues []string  `json:"most_common_values"`
+	MostCommonFreqs  []float64 `json:"most_common_freqs"`
+}
+
+// TableColumnStats holds column statistics for a table, and the columns ANALYZE has
+// not gathered statistics for
+type TableColumnStats struct {
+	Schema        string        `json:"schema"`
+	Table         string        `json:"table"`
+	EstimatedRows *int64        `json:"estimated_rows"`
+	Columns       []ColumnStats `json:"columns"`
+	NotAnalyzed   []string      `json:"not_analyzed"`
+}
+
+// ColumnStats reads per-column statistics from pg_stats without scanning the table.
+// At most mcvLimit most-common values are returned per column. Partitioned tables
+// only have statistics covering their partitions, which are used when present.
+func (p *PostgresAdapter) ColumnStats(ctx context.Context, schemaName, tableName string, mcvLimit int) (*TableColumnStats, error) {
+	var reltuples float64
+	var relpages int64
+	tableQuery := `
+		SELECT c.reltuples, c.relpages
+		FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1 AND c.relname = $2 AND c.relkind IN ('r', 'p', 'm', 'f')
+	`
+	err := p.db.QueryRowContext(ctx, tableQuery, schemaName, tableName).Scan(&reltuples, &relpages)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table %s.%s not found", schemaName, tableName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up table: %w", err)
+	}
+
+	stats := &TableColumnStats{Schema: schemaName, Table: tableName, Columns: []ColumnStats{}, NotAnalyzed: []string{}}
+	// Before the first VACUUM or ANALYZE reltuples is -1 (PostgreSQL 14+), or 0 with
+	// relpages also 0 on older versions. An empty table there looks the same, so its
+	// estimate is unknown too.
+	if reltuples > 0 || reltuples == 0 && relpages > 0 {
+		rows := int64(reltuples)
+		stats.EstimatedRows = &rows
+	}
+
+	query := `
+		SELECT a.attname, s.null_frac, s.n_distinct, s.avg_width, s.correlation,
+			s.most_common_vals::text::text[], s.most_common_freqs
+		FROM pg_attribute a
+		JOIN pg_class c ON a.attrelid = c.oid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		LEFT JOIN LATERAL (
+			SELECT st.null_frac, st.n_distinct, st.avg_width, st.correlation, st.most_common_vals, st.most_common_freqs
+			FROM pg_stats st
+			WHERE st.schemaname = n.nspname AND st.tablename = c.relname AND st.attname = a.attname
+			ORDER BY st.inherited
+			LIMIT 1
+		) s ON true
+		WHERE n.nspname = $1 AND c.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column statistics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var col ColumnStats
+		var nullFrac, nDistinct sql.NullFloat64
+		var avgWidth sql.NullInt64
+		var values pq.StringArray
+		var freqs pq.Float64Array
+		if err := rows.Scan(&col.Column, &nullFrac, &nDistinct, &avgWidth, &col.Correlation, &values, &freqs); err != nil {
+			return nil, fmt.Errorf("failed to scan column statistics: %w", err)
+		}
+		if !nullFrac.Valid {
+			stats.NotAnalyzed = append(stats.NotAnalyzed, col.Column)
+			continue
+		}
+
+		col.NullFraction = nullFrac.Float64
+		col.NDistinct = nDistinct.Float64
+		col.AvgWidth = int(avgWidth.Int64)
+		if col.NDistinct >= 0 {
+			n := int64(col.NDistinct)
+			col.DistinctEstimate = &n
+		} else if stats.EstimatedRows != nil {
+			n := int64(math.Round(-col.NDistinct * float64(*stats.EstimatedRows)))
+			col.DistinctEstimate = &n
+		}
+
+		if len(values) > mcvLimit {
+			values, freqs = values[:mcvLimit], freqs[:mcvLimit]
+		}
+		col.MostCommonValues = []string(values)
+		col.MostCommonFreqs = []float64(freqs)
+		if col.MostCommonValues == nil {
+			col.MostCommonValues, col.MostCommonFreqs = []string{}, []float64{}
+		}
+
+		stats.Columns = append(stats.Columns, col)
+	}
+
+	return stats, rows.Err()
+}
+
+// ShowSettings returns server settings whose name matches a LIKE pattern (all when
+// empty). SessionSettable is set for settings a session may change with SET.
+func (p *PostgresAdapter) ShowSettings(ctx context.Context, pattern string) ([]Setting, error) {
+	query := `
+		SELECT name, setting, unit, category, short_desc, context, source, pending_restart,
+			context IN ('user', 'superuser')
+		FROM pg_settings
+		WHERE $1::text = '' OR name LIKE $1::text
+		ORDER BY name
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := []Setting{}
+	for rows.Next() {
+		var s Setting
+		var settable bool
+		if err := rows.Scan(&s.Name, &s.Value, &s.Unit, &s.Category, &s.Description, &s.Context, &s.Source, &s.PendingRestart, &settable); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		s.SessionSettable = &settable
+		settings = append(settings, s)
+	}
+
+	return settings, rows.Err()
+}
+
+// Privileges lists what the current role may do: the schemas it can use or create
+// objects in, and the tables and views it holds privileges on, directly, through an
+// inherited role, or through PUBLIC
+type Privileges struct {
+	Role    string             `json:"role"`
+	Schemas []SchemaPrivileges `json:"schemas"`
+	Tables  []TablePrivileges  `json:"tables"`
+}
+
+// SchemaPrivileges holds the schema-level privileges (USAGE, CREATE) of the current role
+type SchemaPrivileges struct {
+	Schema     string   `json:"schema"`
+	Privileges []string `json:"privileges"`
+}
+
+// TablePrivileges holds the privileges the current role has on a table or view
+type TablePrivileges struct {
+	Schema     string   `json:"schema"`
+	Table      string   `json:"table"`
+	Privileges []string `json:"privileges"`
+}
+
+// MyPrivileges returns the current role's schema and table privileges, limited to
+// one schema when schemaName is set. System schemas are left out.
+func (p *PostgresAdapter) MyPrivileges(ctx context.Context, schemaName string) (*Privileges, error) {
+	privs := &Privileges{Schemas: []SchemaPrivileges{}, Tables: []TablePrivileges{}}
+	if err := p.db.QueryRowContext(ctx, "SELECT current_user").Scan(&privs.Role); err != nil {
+		return nil, fmt.Errorf("failed to read current role: %w", err)
+	}
+
+	schemaQuery := `
+		SELECT nspname, has_schema_privilege(oid, 'USAGE'), has_schema_privilege(oid, 'CREATE')
+		FROM pg_namespace
+		WHERE nspname NOT LIKE 'pg\_%' AND nspname <> 'information_schema'
+			AND ($1::text = '' OR nspname = $1::text)
+		ORDER BY nspname
+	`
+
+	rows, err := p.db.QueryContext(ctx, schemaQuery, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema privileges: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s SchemaPrivileges
+		var usage, create bool
+		if err := rows.Scan(&s.Schema, &usage, &create); err != nil {
+			return nil, fmt.Errorf("failed to scan schema privileges: %w", err)
+		}
+		if usage {
+			s.Privileges = append(s.Privileges, "USAGE")
+		}
+		if create {
+			s.Privileges = append(s.Privileges, "CREATE")
+		}
+		if len(s.Privileges) > 0 {
+			privs.Schemas = append(privs.Schemas, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tableQuery := `
+		SELECT table_schema, table_name, array_agg(DISTINCT privilege_type ORDER BY privilege_type)
+		FROM information_schema.table_privileges
+		WHERE (grantee = 'PUBLIC' OR grantee IN (SELECT role_name FROM information_schema.enabled_roles))
+			AND table_schema NOT LIKE 'pg\_%' AND table_schema <> 'information_schema'
+			AND ($1::text = '' OR table_schema = $1::text)
+		GROUP BY table_schema, table_name
+		ORDER BY table_schema, table_name
+	`
+
+	tableRows, err := p.db.QueryContext(ctx, tableQuery, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table privileges: %w", err)
+	}
+	defer tableRows.Close()
+
+	for tableRows.Next() {
+		var t TablePrivileges
+		if err := tableRows.Scan(&t.Schema, &t.Table, (*pq.StringArray)(&t.Privileges)); err != nil {
+			return nil, fmt.Errorf("failed to scan table privileges: %w", err)
+		}
+		privs.Tables = append(privs.Tables, t)
+	}
+
+	return privs, tableRows.Err()
+}
+
+// TransactionSettings reports the default isolation level and read-only mode of new
+// transactions and the session time zone
+func (p *PostgresAdapter) TransactionSettings(ctx context.Context) (*TransactionSettings, error) {
+	query := `
+		SELECT current_setting('default_transaction_isolation'),
+			current_setting('default_transaction_read_only') = 'on',
+			current_setting('TimeZone')
+	`
+
+	var t TransactionSettings
+	if err := p.db.QueryRowContext(ctx, query).Scan(&t.IsolationLevel, &t.ReadOnly, &t.TimeZone); err != nil {
+		return nil, fmt.Errorf("failed to read transaction settings: %w", err)
+	}
+	t.IsolationLevel = normalizeIsolationLevel(t.IsolationLevel)
+
+	return &t, nil
+}
+
+// DataDictionaryEntry describes one column of a table, view, or foreign table.
+// References lists the schema.table.column targets of foreign keys on the column.
+type DataDictionaryEntry struct {
+	Table      string  `json:"table"`
+	Column     string  `json:"column"`
+	Type       string  `json:"type"`
+	Nullable   bool    `json:"nullable"`
+	Default    *string `json:"default"`
+	IsPK       bool    `json:"is_pk"`
+	IsFK       bool    `json:"is_fk"`
+	References *string `json:"references"`
+	Comment    *string `json:"comment"`
+}
+
+// DataDictionary describes every column of every table, view, materialized view,
+// and foreign table in a schema, ordered by table and column position
+func (p *PostgresAdapter) DataDictionary(ctx context.Context, schemaName string) ([]DataDictionaryEntry, error) {
+	query := `
+		SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+			pg_get_expr(d.adbin, d.adrelid),
+			EXISTS (
+				SELECT 1 FROM pg_constraint k
+				WHERE k.conrelid = c.oid AND k.contype = 'p' AND a.attnum = ANY (k.conkey)
+			),
+			(
+				SELECT string_agg(fn.nspname || '.' || fc.relname || '.' || fa.attname, ', ' ORDER BY k.conname)
+				FROM pg_constraint k
+				JOIN pg_class fc ON fc.oid = k.confrelid
+				JOIN pg_namespace fn ON fn.oid = fc.relnamespace
+				JOIN pg_attribute fa ON fa.attrelid = k.confrelid
+					AND fa.attnum = k.confkey[array_position(k.conkey, a.attnum)]
+				WHERE k.conrelid = c.oid AND k.contype = 'f' AND a.attnum = ANY (k.conkey)
+			),
+			col_description(c.oid, a.attnum)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		LEFT JOIN pg_attrdef d ON d.adrelid = c.oid AND d.adnum = a.attnum
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		ORDER BY c.relname, a.attnum
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build data dictionary: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []DataDictionaryEntry{}
+	for rows.Next() {
+		var e DataDictionaryEntry
+		if err := rows.Scan(&e.Table, &e.Column, &e.Type, &e.Nullable, &e.Default, &e.IsPK, &e.References, &e.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan data dictionary entry: %w", err)
+		}
+		e.IsFK = e.References != nil
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// dataDictionaryCSV renders a data dictionary as RFC 4180 CSV with a header row.
+// NULL defaults, references, and comments become empty fields.
+func dataDictionaryCSV(entries []DataDictionaryEntry) (string, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+
+	if err := w.Write([]string{"table", "column", "type", "nullable", "default", "is_pk", "is_fk", "references", "comment"}); err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		record := []string{
+			e.Table,
+			e.Column,
+			e.Type,
+			strconv.FormatBool(e.Nullable),
+			stringOrEmpty(e.Default),
+			strconv.FormatBool(e.IsPK),
+			strconv.FormatBool(e.IsFK),
+			stringOrEmpty(e.References),
+			stringOrEmpty(e.Comment),
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// stringOrEmpty dereferences s, returning "" for nil
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
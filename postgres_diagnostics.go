@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/lib/pq"
+)
+
+// seqScanMinLiveRows is the table size below which sequential scans are not worth flagging
+const seqScanMinLiveRows = 1000
+
+// SeqScanTable is a table whose access is dominated by sequential scans
+type SeqScanTable struct {
+	Schema      string `json:"schema"`
+	Table       string `json:"table"`
+	SeqScans    int64  `json:"seq_scans"`
+	SeqRowsRead int64  `json:"seq_rows_read"`
+	IndexScans  int64  `json:"index_scans"`
+	LiveRows    int64  `json:"live_rows"`
+}
+
+// UnusedIndex is an index that has never been scanned since statistics were reset
+type UnusedIndex struct {
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	Index     string `json:"index"`
+	SizeBytes int64  `json:"size_bytes"`
+	Size      string `json:"size"`
+}
+
+// IndexAdvice groups missing-index candidates and unused indexes
+type IndexAdvice struct {
+	MissingIndexCandidates []SeqScanTable `json:"missing_index_candidates"`
+	UnusedIndexes          []UnusedIndex  `json:"unused_indexes"`
+}
+
+// IndexAdvice reports sequential-scan-heavy tables and never-used indexes,
+// optionally restricted to one schema
+func (p *PostgresAdapter) IndexAdvice(ctx context.Context, schemaName string) (*IndexAdvice, error) {
+	advice := &IndexAdvice{
+		MissingIndexCandidates: []SeqScanTable{},
+		UnusedIndexes:          []UnusedIndex{},
+	}
+
+	seqQuery := `
+		SELECT schemaname, relname, seq_scan, seq_tup_read, COALESCE(idx_scan, 0), n_live_tup
+		FROM pg_stat_user_tables
+		WHERE ($1::text = '' OR schemaname = $1::text)
+			AND n_live_tup >= $2
+			AND seq_scan > COALESCE(idx_scan, 0)
+		ORDER BY seq_tup_read DESC
+		LIMIT 50
+	`
+
+	rows, err := p.db.QueryContext(ctx, seqQuery, schemaName, seqScanMinLiveRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t SeqScanTable
+		if err := rows.Scan(&t.Schema, &t.Table, &t.SeqScans, &t.SeqRowsRead, &t.IndexScans, &t.LiveRows); err != nil {
+			return nil, fmt.Errorf("failed to scan table statistics: %w", err)
+		}
+		advice.MissingIndexCandidates = append(advice.MissingIndexCandidates, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	unusedQuery := `
+		SELECT s.schemaname, s.relname, s.indexrelname,
+			pg_relation_size(s.indexrelid), pg_size_pretty(pg_relation_size(s.indexrelid))
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		WHERE ($1::text = '' OR s.schemaname = $1::text)
+			AND s.idx_scan = 0
+			AND NOT i.indisunique
+			AND NOT i.indisprimary
+		ORDER BY pg_relation_size(s.indexrelid) DESC
+	`
+
+	rows, err = p.db.QueryContext(ctx, unusedQuery, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var idx UnusedIndex
+		if err := rows.Scan(&idx.Schema, &idx.Table, &idx.Index, &idx.SizeBytes, &idx.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan index statistics: %w", err)
+		}
+		advice.UnusedIndexes = append(advice.UnusedIndexes, idx)
+	}
+
+	return advice, rows.Err()
+}
+
+// IndexUsage is the scan activity and size of one index. RemovalCandidate is set
+// for indexes never scanned that do not enforce uniqueness.
+type IndexUsage struct {
+	Table            string `json:"table"`
+	Index            string `json:"index"`
+	Scans            int64  `json:"scans"`
+	TuplesRead       int64  `json:"tuples_read"`
+	TuplesFetched    int64  `json:"tuples_fetched"`
+	SizeBytes        int64  `json:"size_bytes"`
+	Size             string `json:"size"`
+	Unique           bool   `json:"unique"`
+	RemovalCandidate bool   `json:"removal_candidate"`
+}
+
+// IndexUsage returns per-index scan statistics for a schema, least scanned first
+func (p *PostgresAdapter) IndexUsage(ctx context.Context, schemaName string) ([]IndexUsage, error) {
+	query := `
+		SELECT s.relname, s.indexrelname, s.idx_scan, s.idx_tup_read, s.idx_tup_fetch,
+			pg_relation_size(s.indexrelid), i.indisunique OR i.indisprimary
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		WHERE s.schemaname = $1
+		ORDER BY s.idx_scan, pg_relation_size(s.indexrelid) DESC
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []IndexUsage{}
+	for rows.Next() {
+		var u IndexUsage
+		if err := rows.Scan(&u.Table, &u.Index, &u.Scans, &u.TuplesRead, &u.TuplesFetched, &u.SizeBytes, &u.Unique); err != nil {
+			return nil, fmt.Errorf("failed to scan index statistics: %w", err)
+		}
+		u.Size = formatBytes(u.SizeBytes)
+		u.RemovalCandidate = u.Scans == 0 && !u.Unique
+		usage = append(usage, u)
+	}
+
+	return usage, rows.Err()
+}
+
+const (
+	// defaultBloatMinPercent is the share of a relation that must be wasted to report it
+	defaultBloatMinPercent = 20
+	// bloatMinBytes hides relations whose estimated waste is too small to act on
+	bloatMinBytes = 1 << 20
+	// bloatReportLimit caps the tables and the indexes returned by BloatReport
+	bloatReportLimit = 50
+)
+
+// RelationBloat is the estimated wasted space in a table or btree index. Unreliable
+// is set when some columns lack statistics or use the name type, which the
+// estimate cannot size.
+type RelationBloat struct {
+	Schema       string  `json:"schema"`
+	Table        string  `json:"table"`
+	Index        string  `json:"index,omitempty"`
+	SizeBytes    int64   `json:"size_bytes"`
+	BloatBytes   int64   `json:"bloat_bytes"`
+	Bloat        string  `json:"bloat"`
+	BloatPercent float64 `json:"bloat_percent"`
+	Unreliable   bool    `json:"estimate_unreliable"`
+}
+
+// BloatReport lists tables and indexes with significant estimated bloat
+type BloatReport struct {
+	Tables  []RelationBloat `json:"tables"`
+	Indexes []RelationBloat `json:"indexes"`
+}
+
+// tableBloatQuery is the widely used estimate (after ioguix/pgsql-bloat-estimation):
+// it sizes an average row from pg_stats, derives the pages the live rows need at
+// the table's fillfactor, and compares that with relpages. MAXALIGN is taken as 8.
+const tableBloatQuery = `
+	WITH tables AS (
+		SELECT ns.nspname, tbl.relname, tbl.reltuples,
+			tbl.relpages + COALESCE(toast.relpages, 0) AS tblpages,
+			COALESCE(toast.reltuples, 0) AS toasttuples,
+			COALESCE(substring(array_to_string(tbl.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 100) AS fillfactor,
+			current_setting('block_size')::numeric AS bs,
+			23 + CASE WHEN max(COALESCE(s.null_frac, 0)) > 0 THEN (7 + count(s.attname)) / 8 ELSE 0 END AS tpl_hdr_size,
+			sum((1 - COALESCE(s.null_frac, 0)) * COALESCE(s.avg_width, 0)) AS tpl_data_size,
+			bool_or(att.atttypid = 'pg_catalog.name'::regtype) OR count(*) <> count(s.attname) AS is_na
+		FROM pg_attribute att
+		JOIN pg_class tbl ON att.attrelid = tbl.oid
+		JOIN pg_namespace ns ON tbl.relnamespace = ns.oid
+		LEFT JOIN pg_stats s ON s.schemaname = ns.nspname AND s.tablename = tbl.relname
+			AND s.attname = att.attname AND NOT s.inherited
+		LEFT JOIN pg_class toast ON tbl.reltoastrelid = toast.oid
+		WHERE att.attnum > 0 AND NOT att.attisdropped
+			AND tbl.relkind IN ('r', 'm') AND tbl.reltuples >= 0
+			AND ns.nspname NOT IN ('pg_catalog', 'information_schema') AND ns.nspname NOT LIKE 'pg_toast%'
+			AND ($1::text = '' OR ns.nspname = $1::text)
+		GROUP BY ns.nspname, tbl.relname, tbl.reltuples, tbl.relpages, tbl.reloptions, toast.relpages, toast.reltuples
+	), estimates AS (
+		SELECT nspname, relname, bs, tblpages, is_na,
+			ceil(reltuples / ((bs - 24) * fillfactor / (tpl_size * 100))) + ceil(toasttuples / 4) AS est_pages
+		FROM (
+			SELECT *, 4 + tpl_hdr_size + tpl_data_size + 16
+				- CASE WHEN tpl_hdr_size % 8 = 0 THEN 8 ELSE tpl_hdr_size % 8 END
+				- CASE WHEN ceil(tpl_data_size)::int % 8 = 0 THEN 8 ELSE ceil(tpl_data_size)::int % 8 END AS tpl_size
+			FROM tables
+		) t
+	)
+	SELECT nspname, relname, (bs * tblpages)::bigint, ((tblpages - est_pages) * bs)::bigint,
+		100 * (tblpages - est_pages) / tblpages, is_na
+	FROM estimates
+	WHERE tblpages > est_pages
+		AND 100 * (tblpages - est_pages) / tblpages >= $2
+		AND (tblpages - est_pages) * bs >= $3
+	ORDER BY (tblpages - est_pages) * bs DESC
+	LIMIT $4
+`
+
+// indexBloatQuery is the matching btree estimate: it sizes an average index tuple
+// from the statistics of the indexed columns (or of the index itself for
+// expression columns) and compares the pages needed at the index's fillfactor
+// with relpages. Indexes whose tuples would not fit a page are left out.
+const indexBloatQuery = `
+	WITH index_columns AS (
+		SELECT n.nspname, ct.relname AS tblname, ci.relname AS idxname, ci.reltuples, ci.relpages,
+			COALESCE(substring(array_to_string(ci.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 90) AS fillfactor,
+			COALESCE(a1.attname, a2.attname) AS attname,
+			COALESCE(a1.atttypid, a2.atttypid) AS atttypid,
+			CASE WHEN a1.attnum IS NULL THEN ci.relname ELSE ct.relname END AS attrelname
+		FROM pg_index i
+		JOIN pg_class ci ON i.indexrelid = ci.oid
+		JOIN pg_class ct ON i.indrelid = ct.oid
+		JOIN pg_namespace n ON ct.relnamespace = n.oid
+		JOIN pg_am am ON ci.relam = am.oid AND am.amname = 'btree'
+		CROSS JOIN LATERAL generate_series(1, i.indnatts) AS k(attpos)
+		LEFT JOIN pg_attribute a1 ON i.indkey[k.attpos - 1] <> 0
+			AND a1.attrelid = i.indrelid AND a1.attnum = i.indkey[k.attpos - 1]
+		LEFT JOIN pg_attribute a2 ON i.indkey[k.attpos - 1] = 0
+			AND a2.attrelid = i.indexrelid AND a2.attnum = k.attpos
+		WHERE ci.relpages > 0 AND ci.reltuples >= 0
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'
+			AND ($1::text = '' OR n.nspname = $1::text)
+	), index_stats AS (
+		SELECT c.nspname, c.tblname, c.idxname, c.reltuples, c.relpages, c.fillfactor,
+			current_setting('block_size')::numeric AS bs,
+			CASE WHEN max(COALESCE(s.null_frac, 0)) = 0 THEN 8 ELSE 8 + ((32 + 8 - 1) / 8) END AS tuple_hdr,
+			sum((1 - COALESCE(s.null_frac, 0)) * COALESCE(s.avg_width, 1024)) AS data_width,
+			bool_or(c.atttypid = 'pg_catalog.name'::regtype) OR count(*) <> count(s.attname) AS is_na
+		FROM index_columns c
+		LEFT JOIN pg_stats s ON s.schemaname = c.nspname AND s.tablename = c.attrelname
+			AND s.attname = c.attname AND NOT s.inherited
+		GROUP BY c.nspname, c.tblname, c.idxname, c.reltuples, c.relpages, c.fillfactor
+	), estimates AS (
+		SELECT nspname, tblname, idxname, bs, relpages, is_na,
+			1 + ceil(reltuples / NULLIF(floor((bs - 16 - 24) * fillfactor / (100 * (4 + tuple_width)::float)), 0)) AS est_pages
+		FROM (
+			SELECT *, tuple_hdr + 8 - CASE WHEN tuple_hdr % 8 = 0 THEN 8 ELSE tuple_hdr % 8 END
+				+ data_width + 8 - CASE
+					WHEN data_width = 0 THEN 0
+					WHEN data_width::integer % 8 = 0 THEN 8
+					ELSE data_width::integer % 8
+				END AS tuple_width
+			FROM index_stats
+		) t
+	)
+	SELECT nspname, tblname, idxname, (bs * relpages)::bigint, ((relpages - est_pages) * bs)::bigint,
+		100 * (relpages - est_pages) / relpages, is_na
+	FROM estimates
+	WHERE relpages > est_pages
+		AND 100 * (relpages - est_pages) / relpages >= $2
+		AND (relpages - est_pages) * bs >= $3
+	ORDER BY (relpages - est_pages) * bs DESC
+	LIMIT $4
+`
+
+// BloatReport estimates wasted space in tables and btree indexes from planner
+// statistics, optionally restricted to one schema. The estimates are approximate
+// and only as good as the last ANALYZE.
+func (p *PostgresAdapter) BloatReport(ctx context.Context, schemaName string, minPercent float64) (*BloatReport, error) {
+	report := &BloatReport{}
+
+	var err error
+	report.Tables, err = p.queryBloat(ctx, tableBloatQuery, false, schemaName, minPercent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate table bloat: %w", err)
+	}
+	report.Indexes, err = p.queryBloat(ctx, indexBloatQuery, true, schemaName, minPercent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate index bloat: %w", err)
+	}
+
+	return report, nil
+}
+
+// queryBloat runs one of the bloat estimate queries
+func (p *PostgresAdapter) queryBloat(ctx context.Context, query string, indexes bool, schemaName string, minPercent float64) ([]RelationBloat, error) {
+	rows, err := p.db.QueryContext(ctx, query, schemaName, minPercent, bloatMinBytes, bloatReportLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bloat := []RelationBloat{}
+	for rows.Next() {
+		var b RelationBloat
+		dest := []interface{}{&b.Schema, &b.Table}
+		if indexes {
+			dest = append(dest, &b.Index)
+		}
+		dest = append(dest, &b.SizeBytes, &b.BloatBytes, &b.BloatPercent, &b.Unreliable)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		b.Bloat = formatBytes(b.BloatBytes)
+		b.BloatPercent = math.Round(b.BloatPercent*10) / 10
+		bloat = append(bloat, b)
+	}
+
+	return bloat, rows.Err()
+}
+
+// maxLockQueryLength caps the query text reported per session by LockReport
+const maxLockQueryLength = 1000
+
+// LockReport is the current lock state: the sessions holding or waiting for locks
+// and the blocking graph between them
+type LockReport struct {
+	Sessions []LockSession `json:"sessions"`
+	Blocking []BlockEdge   `json:"blocking"`
+}
+
+// LockSession is one backend with the locks it holds or is waiting for
+type LockSession struct {
+	PID         int64      `json:"pid"`
+	User        *string    `json:"user"`
+	Application string     `json:"application"`
+	State       *string    `json:"state"`
+	Query       string     `json:"query"`
+	XactSeconds *float64   `json:"xact_seconds"`
+	BlockedBy   []int64    `json:"blocked_by"`
+	Locks       []HeldLock `json:"locks"`
+}
+
+// HeldLock is one lock held (granted) or requested by a session
+type HeldLock struct {
+	LockType string  `json:"lock_type"`
+	Mode     string  `json:"mode"`
+	Granted  bool    `json:"granted"`
+	Relation *string `json:"relation,omitempty"`
+}
+
+// BlockEdge records that BlockedPID is waiting for a lock held by BlockingPID
+type BlockEdge struct {
+	BlockedPID  int64 `json:"blocked_pid"`
+	BlockingPID int64 `json:"blocking_pid"`
+}
+
+// LockReport reads pg_locks and pg_stat_activity for every other session holding
+// or waiting for a lock. Granted virtualxid locks, which every transaction holds on
+// itself, are left out. With blockedOnly, only sessions that are waiting or
+// blocking another session are reported.
+func (p *PostgresAdapter) LockReport(ctx context.Context, blockedOnly bool) (*LockReport, error) {
+	sessionQuery := `
+		SELECT a.pid, a.usename, a.application_name, a.state, left(a.query, $1),
+			EXTRACT(EPOCH FROM now() - a.xact_start)::float8, pg_blocking_pids(a.pid)
+		FROM pg_stat_activity a
+		WHERE a.pid <> pg_backend_pid()
+			AND a.pid IN (SELECT pid FROM pg_locks)
+		ORDER BY a.pid
+	`
+
+	rows, err := p.db.QueryContext(ctx, sessionQuery, maxLockQueryLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session activity: %w", err)
+	}
+	defer rows.Close()
+
+	report := &LockReport{Sessions: []LockSession{}, Blocking: []BlockEdge{}}
+	blocking := make(map[int64]bool)
+	for rows.Next() {
+		s := LockSession{Locks: []HeldLock{}}
+		var blockedBy pq.Int64Array
+		if err := rows.Scan(&s.PID, &s.User, &s.Application, &s.State, &s.Query, &s.XactSeconds, &blockedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan session activity: %w", err)
+		}
+		s.BlockedBy = []int64(blockedBy)
+		if s.BlockedBy == nil {
+			s.BlockedBy = []int64{}
+		}
+		for _, pid := range s.BlockedBy {
+			report.Blocking = append(report.Blocking, BlockEdge{BlockedPID: s.PID, BlockingPID: pid})
+			blocking[pid] = true
+		}
+		report.Sessions = append(report.Sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if blockedOnly {
+		involved := report.Sessions[:0]
+		for _, s := range report.Sessions {
+			if len(s.BlockedBy) > 0 || blocking[s.PID] {
+				involved = append(involved, s)
+			}
+		}
+		report.Sessions = involved
+	}
+	if len(report.Sessions) == 0 {
+		return report, nil
+	}
+
+	index := make(map[int64]int, len(report.Sessions))
+	for i, s := range report.Sessions {
+		index[s.PID] = i
+	}
+
+	lockQuery := `
+		SELECT l.pid, l.locktype, l.mode, l.granted,
+			CASE WHEN l.relation IS NOT NULL THEN l.relation::regclass::text END
+		FROM pg_locks l
+		WHERE l.pid <> pg_backend_pid()
+			AND NOT (l.locktype = 'virtualxid' AND l.granted)
+		ORDER BY l.pid, l.granted, l.locktype
+	`
+
+	rows, err = p.db.QueryContext(ctx, lockQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pid int64
+		var lock HeldLock
+		if err := rows.Scan(&pid, &lock.LockType, &lock.Mode, &lock.Granted, &lock.Relation); err != nil {
+			return nil, fmt.Errorf("failed to scan lock: %w", err)
+		}
+		// Sessions that started after the activity snapshot are skipped
+		if i, ok := index[pid]; ok {
+			report.Sessions[i].Locks = append(report.Sessions[i].Locks, lock)
+		}
+	}
+
+	return report, rows.Err()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// catalogTestDB answers queries from a fake connection with canned single-column rows.
+// A query gets the rows of the first entry in results whose match it contains, and no
+// rows otherwise. A query containing block waits for its context to end. Every
+// statement is recorded.
+type catalogTestDB struct {
+	results []catalogTestResult
+	block   string
+
+	mu         sync.Mutex
+	statements []string
+}
+
+func (d *catalogTestDB) record(statement string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, statement)
+}
+
+type catalogTestResult struct {
+	match string
+	rows  []string
+}
+
+func (d *catalogTestDB) Connect(context.Context) (driver.Conn, error) {
+	return &catalogTestConn{d}, nil
+}
+func (d *catalogTestDB) Driver() driver.Driver { return nil }
+
+type catalogTestConn struct{ db *catalogTestDB }
+
+func (c *catalogTestConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *catalogTestConn) Close() error              { return nil }
+func (c *catalogTestConn) Begin() (driver.Tx, error) { return catalogTestTx{}, nil }
+func (c *catalogTestConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return catalogTestTx{}, nil
+}
+func (c *catalogTestConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query)
+	return driver.RowsAffected(0), nil
+}
+func (c *catalogTestConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query)
+	if c.db.block != "" && strings.Contains(query, c.db.block) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	for _, r := range c.db.results {
+		if strings.Contains(query, r.match) {
+			return &catalogTestRows{values: r.rows}, nil
+		}
+	}
+	return &catalogTestRows{}, nil
+}
+
+type catalogTestTx struct{}
+
+func (catalogTestTx) Commit() error   { return nil }
+func (catalogTestTx) Rollback() error { return nil }
+
+type catalogTestRows struct {
+	values []string
+	next   int
+}
+
+func (r *catalogTestRows) Columns() []string { return []string{"column_name"} }
+func (r *catalogTestRows) Close() error      { return nil }
+func (r *catalogTestRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	dest[0] = r.values[r.next]
+	r.next++
+	return nil
+}
+
+func catalogTestAdapter(pkColumns, columns []string) *PostgresAdapter {
+	fake := &catalogTestDB{results: []catalogTestResult{
+		{match: "PRIMARY KEY", rows: pkColumns},
+		{match: "information_schema.columns", rows: columns},
+	}}
+	return &PostgresAdapter{BaseAdapter: BaseAdapter{name: "postgres", enabled: true, db: sql.OpenDB(fake)}}
+}
+
+func TestGetRowQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		pkColumns []string
+		key       map[string]interface{}
+		columns   []string
+		wantQuery string
+		wantArgs  []interface{}
+		wantErr   string
+	}{
+		{
+			name:      "single primary key",
+			pkColumns: []string{"id"},
+			key:       map[string]interface{}{"id": float64(42)},
+			wantQuery: `SELECT * FROM "public"."users" WHERE "id" = $1 LIMIT 1`,
+			wantArgs:  []interface{}{int64(42)},
+		},
+		{
+			name:      "composite primary key in key order",
+			pkColumns: []string{"tenant_id", "order_no"},
+			key:       map[string]interface{}{"order_no": "A-7", "tenant_id": float64(3)},
+			wantQuery: `SELECT * FROM "public"."users" WHERE "tenant_id" = $1 AND "order_no" = $2 LIMIT 1`,
+			wantArgs:  []interface{}{int64(3), "A-7"},
+		},
+		{
+			name:      "selected columns",
+			pkColumns: []string{"id"},
+			key:       map[string]interface{}{"id": "9007199254740993"},
+			columns:   []string{"email", "id"},
+			wantQuery: `SELECT "email", "id" FROM "public"."users" WHERE "id" = $1 LIMIT 1`,
+			wantArgs:  []interface{}{"9007199254740993"},
+		},
+		{
+			name:      "missing key column",
+			pkColumns: []string{"tenant_id", "order_no"},
+			key:       map[string]interface{}{"tenant_id": float64(3)},
+			wantErr:   "key must name exactly the primary key columns (tenant_id, order_no); missing: order_no",
+		},
+		{
+			name:      "extra key column",
+			pkColumns: []string{"id"},
+			key:       map[string]interface{}{"id": float64(1), "email": "a@example.com"},
+			wantErr:   "key must name exactly the primary key columns (id); not in primary key: email",
+		},
+		{
+			name:      "missing and extra key columns",
+			pkColumns: []string{"tenant_id", "order_no"},
+			key:       map[string]interface{}{"tenant_id": float64(3), "order": float64(1)},
+			wantErr:   "key must name exactly the primary key columns (tenant_id, order_no); missing: order_no; not in primary key: order",
+		},
+		{
+			name:    "no primary key",
+			key:     map[string]interface{}{"id": float64(1)},
+			wantErr: "table has no primary key",
+		},
+		{
+			name:      "unknown selected column",
+			pkColumns: []string{"id"},
+			key:       map[string]interface{}{"id": float64(1)},
+			columns:   []string{"nope"},
+			wantErr:   "nope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := catalogTestAdapter(tt.pkColumns, []string{"id", "email", "tenant_id", "order_no"})
+			query, args, err := adapter.GetRowQuery(context.Background(), "public", "users", tt.key, tt.columns)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetRowQuery error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRowQuery: %v", err)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestGetRowToolDryRun(t *testing.T) {
+	cfg := &Config{}
+	registry := NewToolRegistry(cfg)
+	registerPostgresQueryTools(registry, NewAdapterRegistry(), catalogTestAdapter([]string{"tenant_id", "order_no"}, nil), cfg)
+
+	arguments := json.RawMessage(`{"schema_name":"public","table_name":"orders","key":{"order_no":"A-7","tenant_id":3},"dry_run":true}`)
+	result, err := registry.CallTool(context.Background(), "postgres_get_row", arguments)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	want := `SELECT * FROM "public"."orders" WHERE "tenant_id" = $1 AND "order_no" = $2 LIMIT 1` +
+		"\n-- parameter 1: 3\n-- parameter 2: \"A-7\""
+	if got := result.Content[0].(TextContent).Text; got != want {
+		t.Errorf("dry run = %q, want %q", got, want)
+	}
+
+	arguments = json.RawMessage(`{"schema_name":"public","table_name":"orders","key":{"tenant_id":3},"dry_run":true}`)
+	if _, err := registry.CallTool(context.Background(), "postgres_get_row", arguments); err == nil || !strings.Contains(err.Error(), "missing: order_no") {
+		t.Errorf("CallTool error = %v, want missing order_no", err)
+	}
+}
+
+func TestPostgresLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		typeName string
+		want     string
+	}{
+		{name: "null", value: nil, want: "NULL"},
+		{name: "true", value: true, typeName: "BOOL", want: "TRUE"},
+		{name: "false", value: false, typeName: "BOOL", want: "FALSE"},
+		{name: "int64", value: int64(-42), typeName: "INT8", want: "-42"},
+		{name: "int", value: 7, want: "7"},
+		{name: "int32", value: int32(12), typeName: "INT4", want: "12"},
+		{name: "uint64", value: uint64(18446744073709551615), want: "18446744073709551615"},
+		{name: "float64", value: 2.5, typeName: "FLOAT8", want: "2.5"},
+		{name: "float32", value: float32(0.1), typeName: "FLOAT4", want: "0.1"},
+		{name: "large float", value: 1e21, typeName: "FLOAT8", want: "1e+21"},
+		{name: "float NaN", value: math.NaN(), typeName: "FLOAT8", want: "'NaN'"},
+		{name: "float infinity", value: math.Inf(-1), typeName: "FLOAT8", want: "'-Inf'"},
+		{name: "numeric text", value: "12.50", typeName: "NUMERIC", want: "12.50"},
+		{name: "negative numeric text", value: "-0.001", typeName: "NUMERIC", want: "-0.001"},
+		{name: "numeric NaN", value: "NaN", typeName: "NUMERIC", want: "'NaN'"},
+		{name: "numeric-looking text", value: "12.50", typeName: "TEXT", want: "'12.50'"},
+		{name: "string", value: "plain", typeName: "TEXT", want: "'plain'"},
+		{name: "string with quote", value: "it's", typeName: "TEXT", want: "'it''s'"},
+		{name: "untyped string", value: "42", want: "'42'"},
+		{name: "binary", value: BinaryValue{Binary: "AAE="}, typeName: "BYTEA", want: "decode('AAE=', 'base64')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postgresLiteral(tt.value, tt.typeName); got != tt.want {
+				t.Errorf("postgresLiteral(%#v, %q) = %s, want %s", tt.value, tt.typeName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildInsertStatements(t *testing.T) {
+	result := QueryResult{
+		Columns: []string{"id", "price", "active", "name"},
+		Types:   []string{"INT8", "NUMERIC", "BOOL", "TEXT"},
+		Rows: [][]interface{}{
+			{int64(1), "9.99", true, "Widget"},
+			{int64(2), nil, false, "O'Brien"},
+		},
+	}
+	want := []string{
+		`INSERT INTO "public"."items" ("id", "price", "active", "name") VALUES (1, 9.99, TRUE, 'Widget');`,
+		`INSERT INTO "public"."items" ("id", "price", "active", "name") VALUES (2, NULL, FALSE, 'O''Brien');`,
+	}
+	if got := buildInsertStatements("public", "items", result); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildInsertStatements =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -5,6 +5,10 @@ import "encoding/json"
 const (
 	// ProtocolVersion is the MCP protocol version this server implements
 	ProtocolVersion = "2025-03-26"
+
+	// ServerName and ServerVersion identify the server in initialize results and discovery
+	ServerName    = "MCP Storage Server"
+	ServerVersion = "1.0.0"
 )
 
 // JSON-RPC 2.0 Types
@@ -46,6 +50,9 @@ const (
 	MethodNotFound = -32601
 	InvalidParams  = -32602
 	InternalError  = -32603
+
+	// ResourceNotFound is the MCP error code for resources/read of an unknown URI
+	ResourceNotFound = -32002
 )
 
 // MCP Protocol Types
@@ -130,12 +137,18 @@ type ListToolsResult struct {
 type CallToolParams struct {
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments"`
+
+	// Meta is the optional client _meta object, e.g. {"progressToken": ...}
+	Meta map[string]interface{} `json:"_meta,omitempty"`
 }
 
 // CallToolResult represents the result of a tools/call request
 type CallToolResult struct {
 	Content []Content `json:"content"`
 	IsError bool      `json:"isError,omitempty"`
+
+	// Meta is the optional _meta object returned to the client
+	Meta map[string]interface{} `json:"_meta,omitempty"`
 }
 
 // Content represents content in a tool result
@@ -168,6 +181,28 @@ type Resource struct {
 	MimeType    string `json:"mimeType,omitempty"`
 }
 
+// ListResourcesResult represents the result of a resources/list request
+type ListResourcesResult struct {
+	Resources []Resource `json:"resources"`
+}
+
+// ReadResourceParams represents parameters for a resources/read request
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ReadResourceResult represents the result of a resources/read request
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ResourceContents is the text contents of a resource
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
 // Progress represents progress information
 type Progress struct {
 	Token      string  `json:"token"`
@@ -191,6 +226,23 @@ const (
 	LogLevelEmergency LogLevel = "emergency"
 )
 
+// logLevelSeverity orders log levels from least to most severe
+var logLevelSeverity = map[LogLevel]int{
+	LogLevelDebug:     0,
+	LogLevelInfo:      1,
+	LogLevelNotice:    2,
+	LogLevelWarning:   3,
+	LogLevelError:     4,
+	LogLevelCritical:  5,
+	LogLevelAlert:     6,
+	LogLevelEmergency: 7,
+}
+
+// SetLevelParams represents logging/setLevel parameters
+type SetLevelParams struct {
+	Level LogLevel `json:"level"`
+}
+
 // LogEntry represents a log entry
 type LogEntry struct {
 	Level  LogLevel `json:"level"`
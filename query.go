@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ReadOnlyPolicy controls which statements pass read-only validation
+type ReadOnlyPolicy struct {
+	// AllowRoutines permits CALL statements for routines listed in Routines.
+	// The server cannot verify that a routine is actually read-only; the
+	// allowlist is the operator's assertion that it is.
+	AllowRoutines bool
+	Routines      []string
+
+	// MySQLQuoting selects MySQL string and comment rules when scanning for
+	// statement separators; otherwise PostgreSQL rules apply
+	MySQLQuoting bool
+}
+
+// NewReadOnlyPolicy builds the read-only policy from configuration
+func NewReadOnlyPolicy(cfg *Config) ReadOnlyPolicy {
+	return ReadOnlyPolicy{
+		AllowRoutines: cfg.AllowReadonlyRoutines,
+		Routines:      cfg.ReadonlyRoutines,
+	}
+}
+
+// NewMySQLReadOnlyPolicy builds the read-only policy for MySQL, which scans
+// queries with MySQL quoting and comment rules
+func NewMySQLReadOnlyPolicy(cfg *Config) ReadOnlyPolicy {
+	policy := NewReadOnlyPolicy(cfg)
+	policy.MySQLQuoting = true
+	return policy
+}
+
+// validateReadOnlyQuery checks that a query is a single read-only statement and
+// returns it trimmed, without a trailing semicolon. Leading comments are allowed,
+// and so are parenthesized query expressions such as (SELECT ...) LIMIT 0.
+func validateReadOnlyQuery(query string, policy ReadOnlyPolicy) (string, error) {
+	query, err := trimStatementTerminator(strings.TrimSpace(query), policy.MySQLQuoting)
+	if err != nil {
+		return "", err
+	}
+	statement := stripLeadingComments(query)
+	queryLower := strings.ToLower(statement)
+
+	inner := queryLower
+	for strings.HasPrefix(inner, "(") {
+		inner = stripLeadingComments(inner[1:])
+	}
+	if strings.HasPrefix(inner, "select") || strings.HasPrefix(inner, "with") {
+		return query, nil
+	}
+
+	if hasKeywordPrefix(queryLower, "call") {
+		if !policy.AllowRoutines {
+			return "", fmt.Errorf("routine calls are disabled (set ALLOW_READONLY_ROUTINES=true)")
+		}
+
+		name := routineName(statement[len("call"):])
+		if name == "" {
+			return "", fmt.Errorf("could not determine routine name in CALL statement")
+		}
+		if !policy.routineAllowed(name) {
+			return "", fmt.Errorf("routine %s is not in the read-only allowlist", name)
+		}
+		return query, nil
+	}
+
+	return "", fmt.Errorf("only SELECT queries are allowed")
+}
+
+// trimStatementTerminator removes one trailing semicolon (and anything after it that
+// is only whitespace or comments) and rejects queries holding more than one
+// statement. Semicolons inside string literals, quoted identifiers, dollar quotes,
+// and comments are ignored.
+func trimStatementTerminator(query string, mysqlQuoting bool) (string, error) {
+	terminator := -1
+	n := len(query)
+	for i := 0; i < n; {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			backslash := c != '`' && mysqlQuoting ||
+				c == '\'' && i > 0 && (query[i-1] == 'e' || query[i-1] == 'E') && (i < 2 || !isIdentChar(query[i-2]))
+			i = skipQuoted(query, i, c, backslash)
+
+		case c == '$' && !mysqlQuoting:
+			if end, ok := skipDollarQuoted(query, i); ok {
+				i = end
+			} else {
+				i++
+			}
+
+		case c == '-' && i+1 < n && query[i+1] == '-' && (!mysqlQuoting || i+2 == n || query[i+2] <= ' '),
+			c == '#' && mysqlQuoting:
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			i += end
+			continue
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = n
+			} else {
+				i += end + 4
+			}
+			continue
+
+		case c == ';':
+			if terminator >= 0 {
+				return "", fmt.Errorf("multiple statements are not allowed")
+			}
+			terminator = i
+			i++
+			continue
+
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+			continue
+
+		default:
+			i++
+		}
+
+		// Code after a semicolon starts another statement
+		if terminator >= 0 {
+			return "", fmt.Errorf("multiple statements are not allowed")
+		}
+	}
+
+	if terminator >= 0 {
+		query = strings.TrimSpace(query[:terminator])
+	}
+	return query, nil
+}
+
+// stripLeadingComments removes leading whitespace and -- or /* */ comments
+func stripLeadingComments(stmt string) string {
+	for {
+		stmt = strings.TrimSpace(stmt)
+		switch {
+		case strings.HasPrefix(stmt, "--"):
+			end := strings.IndexByte(stmt, '\n')
+			if end < 0 {
+				return ""
+			}
+			stmt = stmt[end+1:]
+		case strings.HasPrefix(stmt, "/*"):
+			end := strings.Index(stmt, "*/")
+			if end < 0 {
+				return ""
+			}
+			stmt = stmt[end+2:]
+		default:
+			return stmt
+		}
+	}
+}
+
+// routineAllowed reports whether a normalized routine name is allowlisted
+func (p ReadOnlyPolicy) routineAllowed(name string) bool {
+	for _, allowed := range p.Routines {
+		if strings.ToLower(allowed) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeywordPrefix reports whether s starts with keyword followed by a non-identifier character
+func hasKeywordPrefix(s, keyword string) bool {
+	if !strings.HasPrefix(s, keyword) {
+		return false
+	}
+	if len(s) == len(keyword) {
+		return true
+	}
+	c := s[len(keyword)]
+	return !(c == '_' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9')
+}
+
+// routineName extracts the lowercased, unquoted routine name from the text following CALL
+func routineName(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "( \t\n;"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.NewReplacer("`", "", `"`, "").Replace(s)
+	return strings.ToLower(s)
+}
+
+// QueryOptions are per-query database settings, keyed by setting name
+type QueryOptions map[string]string
+
+type queryOptionsKey struct{}
+
+// optionValuePattern restricts option values to simple tokens such as 64MB, off, or 1.1
+var optionValuePattern = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+// withQueryOptions attaches per-query options to a context
+func withQueryOptions(ctx context.Context, opts QueryOptions) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryOptionsKey{}, opts)
+}
+
+// queryOptionsFrom returns the per-query options attached to a context
+func queryOptionsFrom(ctx context.Context) QueryOptions {
+	opts, _ := ctx.Value(queryOptionsKey{}).(QueryOptions)
+	return opts
+}
+
+// parseQueryOptions converts tool-provided options into QueryOptions, stringifying values
+func parseQueryOptions(raw map[string]interface{}) QueryOptions {
+	if len(raw) == 0 {
+		return nil
+	}
+	opts := make(QueryOptions, len(raw))
+	for name, value := range raw {
+		opts[strings.ToLower(name)] = fmt.Sprint(value)
+	}
+	return opts
+}
+
+// validate checks every option against an allowlist and the value pattern
+func (o QueryOptions) validate(allowed map[string]bool) error {
+	for _, name := range o.names() {
+		if !allowed[name] {
+			return fmt.Errorf("query option %s is not allowed", name)
+		}
+		if !optionValuePattern.MatchString(o[name]) {
+			return fmt.Errorf("invalid value for query option %s: %q", name, o[name])
+		}
+	}
+	return nil
+}
+
+// names returns the option names in sorted order
+func (o QueryOptions) names() []string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type queryArgsKey struct{}
+
+// withQueryArgs attaches positional query arguments to a context
+func withQueryArgs(ctx context.Context, args []interface{}) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryArgsKey{}, args)
+}
+
+// queryArgsFrom returns the positional query arguments attached to a context
+func queryArgsFrom(ctx context.Context) []interface{} {
+	args, _ := ctx.Value(queryArgsKey{}).([]interface{})
+	return args
+}
+
+// bindNamedParams rewrites :name placeholders in a PostgreSQL query to $1..$n and
+// returns the matching arguments. A repeated name reuses its position. Text inside
+// string literals, quoted identifiers, dollar quotes, and comments is left alone,
+// as are :: casts and array slices such as arr[lo:hi]. Every placeholder must have
+// a value and every value must be used.
+func bindNamedParams(query string, params map[string]interface{}) (string, []interface{}, error) {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	positions := make(map[string]int)
+	var args []interface{}
+	var missing []string
+	brackets := 0
+
+	n := len(query)
+	for i := 0; i < n; {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			// E'...' strings use backslash escapes
+			escapes := c == '\'' && i > 0 && (query[i-1] == 'e' || query[i-1] == 'E') && (i < 2 || !isIdentChar(query[i-2]))
+			end := skipQuoted(query, i, c, escapes)
+			b.WriteString(query[i:end])
+			i = end
+
+		case c == '$':
+			if end, ok := skipDollarQuoted(query, i); ok {
+				b.WriteString(query[i:end])
+				i = end
+				break
+			}
+			if i+1 < n && isDigit(query[i+1]) {
+				return "", nil, fmt.Errorf("positional placeholders ($%c...) cannot be mixed with named parameters", query[i+1])
+			}
+			b.WriteByte(c)
+			i++
+
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = n
+			} else {
+				end += i + 4
+			}
+			b.WriteString(query[i:end])
+			i = end
+
+		case c == ':' && i+1 < n && query[i+1] == ':':
+			b.WriteString("::")
+			i += 2
+
+		case c == '[' || c == ']':
+			// A colon between brackets separates slice bounds
+			if c == '[' {
+				brackets++
+			} else if brackets > 0 {
+				brackets--
+			}
+			b.WriteByte(c)
+			i++
+
+		case c == ':' && brackets == 0 && i+1 < n && isIdentStart(query[i+1]) && query[i+1] < 0x80:
+			end := i + 1
+			for end < n && isIdentChar(query[end]) && query[end] != '$' {
+				end++
+			}
+			name := query[i+1 : end]
+
+			pos, seen := positions[name]
+			if !seen {
+				value, ok := params[name]
+				if !ok {
+					missing = append(missing, name)
+				}
+				args = append(args, jsonArg(value))
+				pos = len(args)
+				positions[name] = pos
+			}
+			fmt.Fprintf(&b, "$%d", pos)
+			i = end
+
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("missing values for parameters: %s", strings.Join(missing, ", "))
+	}
+	var unused []string
+	for name := range params {
+		if _, ok := positions[name]; !ok {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", nil, fmt.Errorf("parameters not used in query: %s", strings.Join(unused, ", "))
+	}
+
+	return b.String(), args, nil
+}
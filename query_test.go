@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindNamedParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		params  map[string]interface{}
+		want    string
+		args    []interface{}
+		wantErr bool
+	}{
+		{
+			name:   "repeated name reuses its position",
+			query:  "SELECT * FROM t WHERE a = :id OR b = :id AND c = :name",
+			params: map[string]interface{}{"id": 1, "name": "x"},
+			want:   "SELECT * FROM t WHERE a = $1 OR b = $1 AND c = $2",
+			args:   []interface{}{1, "x"},
+		},
+		{
+			name:   "placeholder inside a string literal",
+			query:  "SELECT ':x', E'\\':x', \":x\" FROM t WHERE a = :x",
+			params: map[string]interface{}{"x": 1},
+			want:   "SELECT ':x', E'\\':x', \":x\" FROM t WHERE a = $1",
+			args:   []interface{}{1},
+		},
+		{
+			name:   "cast",
+			query:  "SELECT :v::int, now()::date",
+			params: map[string]interface{}{"v": "5"},
+			want:   "SELECT $1::int, now()::date",
+			args:   []interface{}{"5"},
+		},
+		{
+			name:   "array slice",
+			query:  "SELECT arr[lo:hi], arr[:hi], arr[1:2][a:b] FROM t WHERE id = :id",
+			params: map[string]interface{}{"id": 7},
+			want:   "SELECT arr[lo:hi], arr[:hi], arr[1:2][a:b] FROM t WHERE id = $1",
+			args:   []interface{}{7},
+		},
+		{
+			name:   "comments and dollar quotes",
+			query:  "SELECT $$:x$$, $tag$ :x $tag$ -- :x\n/* :x */ FROM t WHERE a = :x",
+			params: map[string]interface{}{"x": true},
+			want:   "SELECT $$:x$$, $tag$ :x $tag$ -- :x\n/* :x */ FROM t WHERE a = $1",
+			args:   []interface{}{true},
+		},
+		{name: "missing value", query: "SELECT :a, :b", params: map[string]interface{}{"a": 1}, wantErr: true},
+		{name: "unused value", query: "SELECT :a", params: map[string]interface{}{"a": 1, "b": 2}, wantErr: true},
+		{name: "mixed with positional", query: "SELECT :a, $1", params: map[string]interface{}{"a": 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := bindNamedParams(tt.query, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bindNamedParams error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestTrimStatementTerminator(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		mysql   bool
+		want    string
+		wantErr bool
+	}{
+		{name: "no terminator", query: "SELECT 1", want: "SELECT 1"},
+		{name: "trailing semicolon", query: "SELECT 1;", want: "SELECT 1"},
+		{name: "whitespace after semicolon", query: "SELECT 1 ;  \n\t", want: "SELECT 1"},
+		{name: "line comment after semicolon", query: "SELECT 1; -- done", want: "SELECT 1"},
+		{name: "block comment after semicolon", query: "SELECT 1; /* done; really */", want: "SELECT 1"},
+		{name: "mysql hash comment after semicolon", query: "SELECT 1; # done", mysql: true, want: "SELECT 1"},
+		{name: "semicolon in string", query: "SELECT 'a;b'", want: "SELECT 'a;b'"},
+		{name: "semicolon in doubled-quote string", query: "SELECT 'it''s; ok';", want: "SELECT 'it''s; ok'"},
+		{name: "semicolon in escape string", query: `SELECT E'\'; DROP TABLE x'`, want: `SELECT E'\'; DROP TABLE x'`},
+		{name: "escaped backslash ends an escape string", query: `SELECT E'\\'; DROP TABLE x`, wantErr: true},
+		{name: "semicolon in quoted identifier", query: `SELECT "a;b" FROM t`, want: `SELECT "a;b" FROM t`},
+		{name: "semicolon in dollar quote", query: "SELECT $$a;b$$", want: "SELECT $$a;b$$"},
+		{name: "semicolon in tagged dollar quote", query: "SELECT $tag$ ; DROP TABLE x; $tag$;", want: "SELECT $tag$ ; DROP TABLE x; $tag$"},
+		{name: "semicolon in mysql backticks", query: "SELECT `a;b` FROM t;", mysql: true, want: "SELECT `a;b` FROM t"},
+		{name: "semicolon in mysql backslash string", query: `SELECT 'a\'; b'`, mysql: true, want: `SELECT 'a\'; b'`},
+		{name: "second statement", query: "select 1; drop table x", wantErr: true},
+		{name: "second statement after terminator", query: "select 1; drop table x;", wantErr: true},
+		{name: "two terminators", query: "select 1;;", wantErr: true},
+		{name: "second statement on mysql", query: "select 1; drop table x", mysql: true, wantErr: true},
+		{name: "backslash does not escape a postgres string", query: `SELECT 'a\'; drop table x`, wantErr: true},
+		{name: "double dash without space is not a mysql comment", query: "SELECT 1; --x", mysql: true, wantErr: true},
+		{name: "statement after a comment", query: "SELECT 1; /* c */ drop table x", wantErr: true},
+		// An unterminated quote or comment runs to the end, so nothing after it can
+		// split off; the database rejects the query as a syntax error
+		{name: "unterminated string", query: "SELECT 'a; drop table x", want: "SELECT 'a; drop table x"},
+		{name: "unterminated quoted identifier", query: `SELECT "a; drop table x`, want: `SELECT "a; drop table x`},
+		{name: "unterminated dollar quote", query: "SELECT $tag$ a; drop table x", want: "SELECT $tag$ a; drop table x"},
+		{name: "unterminated block comment", query: "SELECT 1 /* ; drop table x", want: "SELECT 1 /* ; drop table x"},
+		{name: "unterminated block comment after terminator", query: "SELECT 1; /* drop table x", want: "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trimStatementTerminator(tt.query, tt.mysql)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("trimStatementTerminator(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("trimStatementTerminator(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// NewQueryLogHook returns an after-query hook that logs each executed query at
+// debug level. It also sends the query to the request's session as a log
+// notification (debug, or warning for a failed query), filtered by the level the
+// session set. For adapters listed in LOG_REDACT_LITERALS ("true" or "all" for
+// every adapter) string and numeric literals are replaced with ? in both, so they
+// show the query shape without the values. The executed query is unaffected.
+func NewQueryLogHook(cfg *Config) AfterQueryHook {
+	redact := make(map[string]bool)
+	for _, name := range cfg.LogRedactLiterals {
+		redact[strings.ToLower(name)] = true
+	}
+	redactAll := redact["true"] || redact["all"]
+
+	return func(ctx context.Context, adapter DatabaseAdapter, query string, result QueryResult, err error) {
+		session := sessionFrom(ctx)
+		if !debugMode && session == nil {
+			return
+		}
+
+		name := adapter.Name()
+		if redactAll || redact[name] {
+			query = redactLiterals(query, name == "mysql")
+		}
+
+		if session != nil {
+			if err != nil {
+				session.Log(LogLevelWarning, "queryLog", fmt.Sprintf("%s query failed: %v: %s", name, err, query))
+			} else {
+				session.Log(LogLevelDebug, "queryLog", fmt.Sprintf("%s query returned %d rows: %s", name, len(result.Rows), query))
+			}
+		}
+		if !debugMode {
+			return
+		}
+
+		event := loggerFrom(ctx).Debug().Str("scope", "queryLog").Str("adapter", name).Str("query", query)
+		if err != nil {
+			event.Err(err).Msg("Query failed")
+			return
+		}
+		event.Int("rows", len(result.Rows)).Msg("Query executed")
+	}
+}
+
+// redactLiterals replaces string and numeric literals in query with ?. Quoted
+// identifiers, comments, and $n placeholders are kept. With mysqlQuoting,
+// double-quoted text is a string literal and backslash escapes apply in strings;
+// otherwise PostgreSQL rules apply (double quotes delimit identifiers, backslash
+// escapes only in E'...' strings, and $tag$...$tag$ dollar quoting).
+func redactLiterals(query string, mysqlQuoting bool) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	n := len(query)
+	for i := 0; i < n; {
+		c := query[i]
+		switch {
+		case c == '\'':
+			i = skipQuoted(query, i, '\'', mysqlQuoting)
+			b.WriteByte('?')
+
+		case c == '"' && mysqlQuoting:
+			i = skipQuoted(query, i, '"', true)
+			b.WriteByte('?')
+
+		case c == '"' || c == '`':
+			end := skipQuoted(query, i, c, false)
+			b.WriteString(query[i:end])
+			i = end
+
+		case c == '$' && !mysqlQuoting:
+			if end, ok := skipDollarQuoted(query, i); ok {
+				b.WriteByte('?')
+				i = end
+				break
+			}
+			// $n placeholder or stray $
+			end := i + 1
+			for end < n && isDigit(query[end]) {
+				end++
+			}
+			b.WriteString(query[i:end])
+			i = end
+
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = n
+			} else {
+				end += i + 4
+			}
+			b.WriteString(query[i:end])
+			i = end
+
+		case isDigit(c) || c == '.' && i+1 < n && isDigit(query[i+1]):
+			i = skipNumber(query, i)
+			b.WriteByte('?')
+
+		case isIdentStart(c):
+			end := i + 1
+			for end < n && isIdentChar(query[end]) {
+				end++
+			}
+			// Prefixed strings such as E'...', X'...', B'...', N'...'
+			if end == i+1 && end < n && query[end] == '\'' && strings.ContainsRune("eExXbBnN", rune(c)) {
+				i = skipQuoted(query, end, '\'', mysqlQuoting || c == 'e' || c == 'E')
+				b.WriteByte('?')
+				break
+			}
+			b.WriteString(query[i:end])
+			i = end
+
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// skipQuoted returns the index just past the quoted section starting at query[start].
+// A doubled quote is an escaped quote; with backslash, \ escapes the next byte.
+func skipQuoted(query string, start int, quote byte, backslash bool) int {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// skipDollarQuoted returns the index just past a $tag$...$tag$ string starting at
+// query[start], or false if query[start] does not open one
+func skipDollarQuoted(query string, start int) (int, bool) {
+	end := start + 1
+	if end < len(query) && isIdentStart(query[end]) {
+		for end < len(query) && isIdentChar(query[end]) && query[end] != '$' {
+			end++
+		}
+	}
+	if end >= len(query) || query[end] != '$' {
+		return 0, false
+	}
+
+	tag := query[start : end+1]
+	closing := strings.Index(query[end+1:], tag)
+	if closing < 0 {
+		return len(query), true
+	}
+	return end + 1 + closing + len(tag), true
+}
+
+// skipNumber returns the index just past the numeric literal starting at query[start]
+func skipNumber(query string, start int) int {
+	i := start
+	n := len(query)
+	if i+1 < n && query[i] == '0' && (query[i+1] == 'x' || query[i+1] == 'X') {
+		i += 2
+		for i < n && strings.IndexByte("0123456789abcdefABCDEF", query[i]) >= 0 {
+			i++
+		}
+		return i
+	}
+	for i < n && (isDigit(query[i]) || query[i] == '.') {
+		i++
+	}
+	if i < n && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < n && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < n && isDigit(query[j]) {
+			i = j
+			for i < n && isDigit(query[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRedactLiterals(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		mysql bool
+		want  string
+	}{
+		{name: "string literal", query: "SELECT * FROM users WHERE email = 'x@y.com'", want: "SELECT * FROM users WHERE email = ?"},
+		{name: "numeric literals", query: "SELECT * FROM t WHERE a = 42 AND b > 3.14 AND c < 1e-3 AND d = .5", want: "SELECT * FROM t WHERE a = ? AND b > ? AND c < ? AND d = ?"},
+		{name: "hex number", query: "SELECT 0xFF", want: "SELECT ?"},
+		{name: "escaped quote", query: "SELECT 'it''s' AS x", want: "SELECT ? AS x"},
+		{name: "password", query: "ALTER ROLE app PASSWORD 'hunter2'", want: "ALTER ROLE app PASSWORD ?"},
+		{name: "prefixed strings", query: `SELECT E'a\'b', X'1F', B'101', N'name'`, want: "SELECT ?, ?, ?, ?"},
+		{name: "dollar quoted", query: "SELECT $tag$secret ' text$tag$, $$x$$", want: "SELECT ?, ?"},
+		{name: "placeholders kept", query: "SELECT * FROM t WHERE id = $1 AND name = $2", want: "SELECT * FROM t WHERE id = $1 AND name = $2"},
+		{name: "quoted identifiers kept", query: `SELECT "col1", "it's" FROM "t2" WHERE x = 'v'`, want: `SELECT "col1", "it's" FROM "t2" WHERE x = ?`},
+		{name: "identifiers with digits kept", query: "SELECT col1, t2.c3 FROM t2", want: "SELECT col1, t2.c3 FROM t2"},
+		{name: "comments kept", query: "SELECT 1 -- count 'x'\n/* note 2 */ FROM t", want: "SELECT ? -- count 'x'\n/* note 2 */ FROM t"},
+		{name: "unterminated string", query: "SELECT 'secret", want: "SELECT ?"},
+		{name: "mysql double-quoted string", query: `SELECT * FROM users WHERE name = "bob" AND pass = 'p\'w'`, mysql: true, want: "SELECT * FROM users WHERE name = ? AND pass = ?"},
+		{name: "mysql backticks kept", query: "SELECT `select` FROM `t1` WHERE id = 9", mysql: true, want: "SELECT `select` FROM `t1` WHERE id = ?"},
+		{name: "mysql placeholders kept", query: "SELECT * FROM t WHERE a = ? AND b = 'x'", mysql: true, want: "SELECT * FROM t WHERE a = ? AND b = ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactLiterals(tt.query, tt.mysql); got != tt.want {
+				t.Errorf("redactLiterals(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryLogHookRedaction(t *testing.T) {
+	saved := debugMode
+	debugMode = true
+	defer func() { debugMode = saved }()
+
+	tests := []struct {
+		name    string
+		redact  []string
+		adapter string
+		want    string
+		hidden  []string
+	}{
+		{name: "redacted adapter", redact: []string{"postgres"}, adapter: "postgres", want: "SELECT * FROM users WHERE email = ? AND id = $1", hidden: []string{"x@y.com", "hunter2"}},
+		{name: "redact all", redact: []string{"true"}, adapter: "postgres", want: "SELECT * FROM users WHERE email = ? AND id = $1", hidden: []string{"x@y.com", "hunter2"}},
+		{name: "other adapter", redact: []string{"mysql"}, adapter: "postgres", want: "SELECT * FROM users WHERE email = 'x@y.com' AND id = $1", hidden: []string{"hunter2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := zerolog.New(&buf).Level(zerolog.DebugLevel)
+			session := NewSessionManager(0, 0).CreateSession()
+			session.SetLogLevel(LogLevelDebug)
+			ctx := withSession(logger.WithContext(context.Background()), session)
+
+			adapters := NewAdapterRegistry()
+			adapters.AddAfterQueryHook(NewQueryLogHook(&Config{LogRedactLiterals: tt.redact}))
+			adapter := &recordingAdapter{name: tt.adapter}
+			query := "SELECT * FROM users WHERE email = 'x@y.com' AND id = $1"
+			// Bound arguments are never logged
+			if _, err := adapters.ExecuteSelect(withQueryArgs(ctx, []interface{}{"hunter2"}), adapter, query); err != nil {
+				t.Fatal(err)
+			}
+			if adapter.query != query {
+				t.Errorf("executed %q, want the unredacted query", adapter.query)
+			}
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("log %q: %v", buf.String(), err)
+			}
+			if entry["query"] != tt.want {
+				t.Errorf("logged query = %v, want %q", entry["query"], tt.want)
+			}
+
+			notifications := session.TakeNotifications()
+			if len(notifications) != 1 {
+				t.Fatalf("got %d notifications, want 1", len(notifications))
+			}
+			for _, secret := range tt.hidden {
+				if strings.Contains(buf.String(), secret) || strings.Contains(string(notifications[0].Params), secret) {
+					t.Errorf("%q leaked into the log or notification", secret)
+				}
+			}
+		})
+	}
+}
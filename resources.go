@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// metadataURIScheme prefixes the URI of every metadata resource, e.g. meta://postgres/columns
+	metadataURIScheme = "meta://"
+
+	// defaultMetadataResourceMaxRows applies when METADATA_RESOURCE_MAX_ROWS is not positive
+	defaultMetadataResourceMaxRows = 1000
+)
+
+// metadataView is a catalog view that can be exposed as a resource
+type metadataView struct {
+	description string
+	query       string
+
+	// schemaColumn is checked against the adapter's excluded schemas
+	schemaColumn string
+}
+
+// metadataViews holds the exposable views per adapter. Each query has a WHERE %s,
+// filled with the excluded-schemas condition on schemaColumn, and ends with LIMIT %d,
+// filled with the row cap plus one so truncation can be detected.
+var metadataViews = map[string]map[string]metadataView{
+	"postgres": {
+		"schemata": {
+			description: "Schemas of the current PostgreSQL database",
+			query: `SELECT schema_name, schema_owner
+				FROM information_schema.schemata
+				WHERE %s AND schema_name NOT LIKE 'pg_toast%%'
+				ORDER BY schema_name LIMIT %d`,
+			schemaColumn: "schema_name",
+		},
+		"tables": {
+			description: "Tables and views of the current PostgreSQL database",
+			query: `SELECT table_schema, table_name, table_type
+				FROM information_schema.tables
+				WHERE %s
+				ORDER BY table_schema, table_name LIMIT %d`,
+			schemaColumn: "table_schema",
+		},
+		"columns": {
+			description: "Columns of every table and view in the current PostgreSQL database",
+			query: `SELECT table_schema, table_name, column_name, ordinal_position, data_type, is_nullable, column_default
+				FROM information_schema.columns
+				WHERE %s
+				ORDER BY table_schema, table_name, ordinal_position LIMIT %d`,
+			schemaColumn: "table_schema",
+		},
+		"routines": {
+			description: "Functions and procedures of the current PostgreSQL database",
+			query: `SELECT routine_schema, routine_name, routine_type, data_type, external_language
+				FROM information_schema.routines
+				WHERE %s
+				ORDER BY routine_schema, routine_name LIMIT %d`,
+			schemaColumn: "routine_schema",
+		},
+	},
+	"mysql": {
+		"schemata": {
+			description: "Databases (schemas) on the MySQL server",
+			query: `SELECT SCHEMA_NAME, DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME
+				FROM INFORMATION_SCHEMA.SCHEMATA
+				WHERE %s
+				ORDER BY SCHEMA_NAME LIMIT %d`,
+			schemaColumn: "SCHEMA_NAME",
+		},
+		"tables": {
+			description: "Tables and views on the MySQL server",
+			query: `SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_TYPE, ENGINE
+				FROM INFORMATION_SCHEMA.TABLES
+				WHERE %s
+				ORDER BY TABLE_SCHEMA, TABLE_NAME LIMIT %d`,
+			schemaColumn: "TABLE_SCHEMA",
+		},
+		"columns": {
+			description: "Columns of every table and view on the MySQL server",
+			query: `SELECT TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME, ORDINAL_POSITION, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT
+				FROM INFORMATION_SCHEMA.COLUMNS
+				WHERE %s
+				ORDER BY TABLE_SCHEMA, TABLE_NAME, ORDINAL_POSITION LIMIT %d`,
+			schemaColumn: "TABLE_SCHEMA",
+		},
+		"routines": {
+			description: "Functions and procedures on the MySQL server",
+			query: `SELECT ROUTINE_SCHEMA, ROUTINE_NAME, ROUTINE_TYPE, DATA_TYPE
+				FROM INFORMATION_SCHEMA.ROUTINES
+				WHERE %s
+				ORDER BY ROUTINE_SCHEMA, ROUTINE_NAME LIMIT %d`,
+			schemaColumn: "ROUTINE_SCHEMA",
+		},
+	},
+}
+
+// metadataResource is one configured adapter view
+type metadataResource struct {
+	adapter string
+	view    string
+}
+
+func (r metadataResource) uri() string {
+	return metadataURIScheme + r.adapter + "/" + r.view
+}
+
+// MetadataResources exposes configured catalog views as read-only MCP resources
+type MetadataResources struct {
+	adapters  *AdapterRegistry
+	resources map[string]metadataResource
+	maxRows   int
+
+	// excludedSchemas are left out of every view, per adapter
+	excludedSchemas map[string][]string
+}
+
+// NewMetadataResources creates the resources named in METADATA_RESOURCES. Entries
+// are adapter/view pairs, e.g. postgres/columns; "*" as the view exposes every view
+// of that adapter. Unknown entries and adapters that are not configured are skipped
+// with a warning.
+func NewMetadataResources(cfg *Config, adapters *AdapterRegistry) *MetadataResources {
+	m := &MetadataResources{
+		adapters:  adapters,
+		resources: make(map[string]metadataResource),
+		maxRows:   cfg.MetadataResourceMaxRows,
+		excludedSchemas: map[string][]string{
+			"postgres": cfg.PostgresExcludedSchemas,
+			"mysql":    cfg.MySQLExcludedSchemas,
+		},
+	}
+
+	for _, entry := range cfg.MetadataResources {
+		adapter, view, _ := strings.Cut(entry, "/")
+		views, ok := metadataViews[adapter]
+		if !ok {
+			log.Warn().Str("entry", entry).Msg("Ignoring METADATA_RESOURCES entry, unknown adapter")
+			continue
+		}
+		if _, ok := adapters.Get(adapter); !ok {
+			log.Warn().Str("entry", entry).Msg("Ignoring METADATA_RESOURCES entry, adapter is not configured")
+			continue
+		}
+
+		if view == "*" {
+			for name := range views {
+				r := metadataResource{adapter: adapter, view: name}
+				m.resources[r.uri()] = r
+			}
+			continue
+		}
+		if _, ok := views[view]; !ok {
+			log.Warn().Str("entry", entry).Msg("Ignoring METADATA_RESOURCES entry, unknown view (expected schemata, tables, columns, routines or *)")
+			continue
+		}
+		r := metadataResource{adapter: adapter, view: view}
+		m.resources[r.uri()] = r
+	}
+
+	return m
+}
+
+// IsEmpty reports whether no resources are exposed
+func (m *MetadataResources) IsEmpty() bool {
+	return len(m.resources) == 0
+}
+
+// List returns the exposed resources sorted by URI
+func (m *MetadataResources) List() []Resource {
+	resources := make([]Resource, 0, len(m.resources))
+	for uri, r := range m.resources {
+		resources = append(resources, Resource{
+			URI:         uri,
+			Name:        r.adapter + " " + r.view,
+			Description: metadataViews[r.adapter][r.view].description,
+			MimeType:    "application/json",
+		})
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+	return resources
+}
+
+// Read runs the view behind uri and returns its rows as JSON, capped at the
+// configured row limit. Schemas excluded for the adapter are left out.
+func (m *MetadataResources) Read(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	r, ok := m.resources[uri]
+	if !ok {
+		return nil, NewRPCError(ResourceNotFound, "Resource not found", uri)
+	}
+	adapter, ok := m.adapters.Get(r.adapter)
+	if !ok {
+		return nil, NewRPCError(ResourceNotFound, "Resource not found", uri)
+	}
+
+	limit := m.maxRows
+	if limit <= 0 {
+		limit = defaultMetadataResourceMaxRows
+	}
+	view := metadataViews[r.adapter][r.view]
+	condition, args := excludedSchemasCondition(view.schemaColumn, m.excludedSchemas[r.adapter], r.adapter == "mysql")
+	result, err := m.adapters.ExecuteSelect(withQueryArgs(ctx, args), adapter, fmt.Sprintf(view.query, condition, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+
+	truncated := len(result.Rows) > limit
+	if truncated {
+		result.Rows = result.Rows[:limit]
+	}
+	text, err := json.Marshal(map[string]interface{}{
+		"columns":   result.Columns,
+		"rows":      result.Rows,
+		"truncated": truncated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReadResourceResult{
+		Contents: []ResourceContents{{URI: uri, MimeType: "application/json", Text: string(text)}},
+	}, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingAdapter records the last query it was asked to run and its arguments
+type recordingAdapter struct {
+	name  string
+	query string
+	args  []interface{}
+}
+
+func (a *recordingAdapter) Name() string    { return a.name }
+func (a *recordingAdapter) Connect() error  { return nil }
+func (a *recordingAdapter) Close() error    { return nil }
+func (a *recordingAdapter) IsEnabled() bool { return true }
+func (a *recordingAdapter) ListSchemas(context.Context) ([]Schema, error) {
+	return nil, nil
+}
+func (a *recordingAdapter) GetSchemaDDL(context.Context, string) (string, error) {
+	return "", nil
+}
+func (a *recordingAdapter) ExecuteSelect(ctx context.Context, query string) (QueryResult, error) {
+	a.query, a.args = query, queryArgsFrom(ctx)
+	return QueryResult{Columns: []string{"schema"}}, nil
+}
+
+func TestExcludedSchemasCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		excluded []string
+		mysql    bool
+		want     string
+		args     []interface{}
+	}{
+		{name: "postgres", excluded: []string{"pg_catalog", "ext"}, want: "s NOT IN ($1, $2)", args: []interface{}{"pg_catalog", "ext"}},
+		{name: "mysql", excluded: []string{"mysql", "sys"}, mysql: true, want: "s NOT IN (?, ?)", args: []interface{}{"mysql", "sys"}},
+		{name: "nothing excluded", want: "TRUE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := excludedSchemasCondition("s", tt.excluded, tt.mysql)
+			if got != tt.want || !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("excludedSchemasCondition = %q, %v, want %q, %v", got, args, tt.want, tt.args)
+			}
+		})
+	}
+}
+
+func TestMetadataResourcesExcludedSchemas(t *testing.T) {
+	postgres := &recordingAdapter{name: "postgres"}
+	mysql := &recordingAdapter{name: "mysql"}
+	adapters := NewAdapterRegistry()
+	for _, a := range []*recordingAdapter{postgres, mysql} {
+		if err := adapters.Register(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{
+		MetadataResources:       []string{"postgres/tables", "mysql/*"},
+		MetadataResourceMaxRows: 10,
+		PostgresExcludedSchemas: []string{"pg_catalog", "information_schema", "timescaledb_information"},
+		MySQLExcludedSchemas:    nil,
+	}
+	resources := NewMetadataResources(cfg, adapters)
+
+	if _, err := resources.Read(context.Background(), "meta://postgres/tables"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(postgres.query, "WHERE table_schema NOT IN ($1, $2, $3)") {
+		t.Errorf("postgres query does not apply the exclusions: %s", postgres.query)
+	}
+	if want := []interface{}{"pg_catalog", "information_schema", "timescaledb_information"}; !reflect.DeepEqual(postgres.args, want) {
+		t.Errorf("postgres args = %v, want %v", postgres.args, want)
+	}
+
+	// An empty exclusion list exposes the system schemas too
+	if _, err := resources.Read(context.Background(), "meta://mysql/schemata"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(mysql.query, "WHERE TRUE") || mysql.args != nil {
+		t.Errorf("mysql query = %s with args %v, want no exclusions", mysql.query, mysql.args)
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	dateLayout      = "2006-01-02"
+	timeLayout      = "15:04:05.999999999"
+	timeTZLayout    = "15:04:05.999999999Z07:00"
+	defaultTSFormat = "rfc3339nano"
+	nullMarker      = "NULL"
+
+	// maxResultImages caps the ImageContent blocks one query result can produce
+	maxResultImages = 20
+)
+
+// timestampFormats maps TIMESTAMP_FORMAT values to Go time layouts
+var timestampFormats = map[string]string{
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc3339":     time.RFC3339,
+	"date":        dateLayout,
+}
+
+// nullRepresentations maps NULL_REPRESENTATION values to the output value for SQL NULL
+var nullRepresentations = map[string]interface{}{
+	"null":   nil,
+	"empty":  "",
+	"marker": nullMarker,
+}
+
+// temporalLayouts are the textual forms drivers use for temporal values returned as bytes
+var temporalLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	dateLayout,
+}
+
+// BinaryValue is the output envelope for binary column values, so clients can
+// tell them apart from text and decode them
+type BinaryValue struct {
+	Binary   string `json:"$binary"`
+	Encoding string `json:"encoding"`
+
+	// Truncated is set when only the first MAX_CELL_BYTES bytes are included
+	Truncated  bool `json:"truncated,omitempty"`
+	TotalBytes int  `json:"total_bytes,omitempty"`
+}
+
+// TruncatedValue replaces a text value longer than MAX_CELL_BYTES. Value holds the
+// leading bytes followed by a "…[truncated N bytes]" suffix.
+type TruncatedValue struct {
+	Value      string `json:"value"`
+	Truncated  bool   `json:"truncated"`
+	TotalBytes int    `json:"total_bytes"`
+}
+
+// ResultOptions controls how scanned values are converted for output
+type ResultOptions struct {
+	// TimestampLayout is the layout used for timestamp/datetime columns
+	TimestampLayout string
+
+	// MaxCellBytes truncates text and binary values longer than this; 0 disables
+	MaxCellBytes int
+
+	// NullValue is returned for SQL NULL; nil renders as JSON null
+	NullValue interface{}
+}
+
+// NewResultOptions builds result conversion options from configuration
+func NewResultOptions(cfg *Config) ResultOptions {
+	layout, ok := timestampFormats[strings.ToLower(cfg.TimestampFormat)]
+	if !ok {
+		log.Warn().Str("timestamp_format", cfg.TimestampFormat).Msg("Unknown timestamp format, using rfc3339nano")
+		layout = timestampFormats[defaultTSFormat]
+	}
+
+	nullValue, ok := nullRepresentations[strings.ToLower(cfg.NullRepresentation)]
+	if !ok {
+		log.Warn().Str("null_representation", cfg.NullRepresentation).Msg("Unknown NULL representation, using null")
+	}
+
+	return ResultOptions{
+		TimestampLayout: layout,
+		MaxCellBytes:    cfg.MaxCellBytes,
+		NullValue:       nullValue,
+	}
+}
+
+type resultOptionsKey struct{}
+
+// withResultOptions overrides the adapter's result conversion for queries run with ctx
+func withResultOptions(ctx context.Context, opts ResultOptions) context.Context {
+	return context.WithValue(ctx, resultOptionsKey{}, opts)
+}
+
+// resultOptionsFrom returns the result options attached to a context, or fallback
+func resultOptionsFrom(ctx context.Context, fallback ResultOptions) ResultOptions {
+	if opts, ok := ctx.Value(resultOptionsKey{}).(ResultOptions); ok {
+		return opts
+	}
+	return fallback
+}
+
+// columnTypeNames returns the upper-cased database type name of each column. When
+// the driver reports no type information the names are empty, and convertValue
+// falls back to type-agnostic conversion (bytes as strings, times as timestamps).
+func columnTypeNames(rows *sql.Rows, count int) []string {
+	names := make([]string, count)
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		log.Debug().Err(err).Str("scope", "columnTypeNames").Msg("Column types unavailable, converting values without type information")
+		return names
+	}
+
+	missing := 0
+	for i := range names {
+		if i < len(types) {
+			names[i] = strings.ToUpper(types[i].DatabaseTypeName())
+		}
+		if names[i] == "" {
+			missing++
+		}
+	}
+	if missing > 0 {
+		log.Debug().Str("scope", "columnTypeNames").Int("columns", count).Int("untyped", missing).Msg("Driver reported no type for some columns, converting them without type information")
+	}
+	return names
+}
+
+// convertValue converts a scanned value into its output representation
+func convertValue(v interface{}, typeName string, opts ResultOptions) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return opts.NullValue
+	case time.Time:
+		return formatTemporal(val, typeName, opts)
+	case []byte:
+		if isBinaryType(typeName) {
+			return binaryValue(val, opts.MaxCellBytes)
+		}
+		if isTemporalType(typeName) {
+			if t, ok := parseTemporal(string(val)); ok {
+				return formatTemporal(t, typeName, opts)
+			}
+		}
+		return truncateText(string(val), opts.MaxCellBytes)
+	case string:
+		return truncateText(val, opts.MaxCellBytes)
+	default:
+		return val
+	}
+}
+
+// binaryValue wraps binary data for output, keeping at most maxBytes bytes when maxBytes > 0
+func binaryValue(data []byte, maxBytes int) BinaryValue {
+	if maxBytes <= 0 || len(data) <= maxBytes {
+		return BinaryValue{Binary: base64.StdEncoding.EncodeToString(data), Encoding: "base64"}
+	}
+	return BinaryValue{
+		Binary:     base64.StdEncoding.EncodeToString(data[:maxBytes]),
+		Encoding:   "base64",
+		Truncated:  true,
+		TotalBytes: len(data),
+	}
+}
+
+// scalarQueryResult is a single-cell query result with its value surfaced at the top level
+type scalarQueryResult struct {
+	QueryResult
+	Scalar bool        `json:"scalar"`
+	Value  interface{} `json:"value"`
+}
+
+// marshalQueryResult encodes a query result for a tool response. A result of exactly
+// one column and one row also gets "scalar": true and its cell as "value", so a
+// SELECT count(*) can be read without indexing into rows.
+func marshalQueryResult(result QueryResult) ([]byte, error) {
+	if len(result.Columns) == 1 && len(result.Rows) == 1 && len(result.Rows[0]) == 1 && len(result.MoreResultSets) == 0 {
+		return json.Marshal(scalarQueryResult{QueryResult: result, Scalar: true, Value: result.Rows[0][0]})
+	}
+	return json.Marshal(result)
+}
+
+// markdownTable renders result as a markdown table. Each header shows the column's
+// database type when known, SQL NULL is shown as NULL, and pipes and line breaks in
+// values are escaped so every row stays on one line.
+func markdownTable(result QueryResult) string {
+	var b strings.Builder
+
+	headers := make([]string, len(result.Columns))
+	for i, col := range result.Columns {
+		headers[i] = markdownCell(col)
+		if i < len(result.Types) && result.Types[i] != "" {
+			headers[i] += " (" + strings.ToLower(result.Types[i]) + ")"
+		}
+	}
+	b.WriteString("| " + strings.Join(headers, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(headers)) + "\n")
+
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = markdownValue(v)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return b.String()
+}
+
+// markdownValue formats one result value for a markdown table cell
+func markdownValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return nullMarker
+	case string:
+		return markdownCell(val)
+	case BinaryValue, TruncatedValue:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return markdownCell(fmt.Sprint(val))
+		}
+		return markdownCell(string(data))
+	default:
+		return markdownCell(fmt.Sprint(val))
+	}
+}
+
+// markdownCell escapes text for use inside a markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// ImageRef replaces a binary value moved into an ImageContent block of the tool
+// result. Image is the 1-based index of that block among the result's images.
+type ImageRef struct {
+	Image    int    `json:"$image"`
+	MimeType string `json:"mime_type"`
+}
+
+// imageResult renders result as a JSON text block followed by an ImageContent block
+// for each binary value in columns ("*" for every column) whose bytes are a
+// recognised image. Those values are replaced by an ImageRef in the text; other
+// values, values truncated by MAX_CELL_BYTES, and images past maxResultImages stay
+// as they are.
+func imageResult(result QueryResult, columns []string) (*CallToolResult, error) {
+	selected := make(map[int]bool)
+	for _, name := range columns {
+		found := false
+		for i, col := range result.Columns {
+			if name == "*" || col == name {
+				selected[i] = true
+				found = true
+			}
+		}
+		if !found && name != "*" {
+			return nil, fmt.Errorf("image column %s not in result (columns: %s)", name, strings.Join(result.Columns, ", "))
+		}
+	}
+
+	var images []Content
+	for _, row := range result.Rows {
+		for i, v := range row {
+			bin, ok := v.(BinaryValue)
+			if !selected[i] || !ok || bin.Truncated || len(images) >= maxResultImages {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(bin.Binary)
+			if err != nil {
+				continue
+			}
+			mimeType := http.DetectContentType(data)
+			if !strings.HasPrefix(mimeType, "image/") {
+				continue
+			}
+			images = append(images, ImageContent{Type: "image", Data: bin.Binary, MimeType: mimeType})
+			row[i] = ImageRef{Image: len(images), MimeType: mimeType}
+		}
+	}
+
+	resultJSON, err := marshalQueryResult(result)
+	if err != nil {
+		return nil, err
+	}
+	return &CallToolResult{
+		Content: append([]Content{TextContent{Type: "text", Text: string(resultJSON)}}, images...),
+	}, nil
+}
+
+// truncateText returns s unchanged if it fits in maxBytes (or maxBytes is 0), otherwise
+// a TruncatedValue cut at a UTF-8 boundary
+func truncateText(s string, maxBytes int) interface{} {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return TruncatedValue{
+		Value:      fmt.Sprintf("%s…[truncated %d bytes]", s[:cut], len(s)-cut),
+		Truncated:  true,
+		TotalBytes: len(s),
+	}
+}
+
+// isBinaryType reports whether a database type name holds raw bytes
+func isBinaryType(typeName string) bool {
+	switch typeName {
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY":
+		return true
+	}
+	return false
+}
+
+// isNumericType reports whether a database type name holds a number
+func isNumericType(typeName string) bool {
+	switch strings.TrimPrefix(typeName, "UNSIGNED ") {
+	case "INT2", "INT4", "INT8", "FLOAT4", "FLOAT8", "NUMERIC",
+		"TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE", "YEAR":
+		return true
+	}
+	return false
+}
+
+// isTemporalType reports whether a database type name holds a date or timestamp
+func isTemporalType(typeName string) bool {
+	switch typeName {
+	case "DATE", "TIMESTAMP", "TIMESTAMPTZ", "DATETIME":
+		return true
+	}
+	return false
+}
+
+// formatTemporal formats a time value as ISO-8601 according to its column type
+func formatTemporal(t time.Time, typeName string, opts ResultOptions) string {
+	switch typeName {
+	case "DATE":
+		return t.Format(dateLayout)
+	case "TIME":
+		return t.Format(timeLayout)
+	case "TIMETZ":
+		return t.Format(timeTZLayout)
+	default:
+		return t.Format(opts.TimestampLayout)
+	}
+}
+
+// parseTemporal parses a driver-provided textual date or timestamp
+func parseTemporal(s string) (time.Time, bool) {
+	for _, layout := range temporalLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatBytes formats a byte count as a human-readable size using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Series is a query result reshaped for charting: one label and one value per row
+type Series struct {
+	X      string        `json:"x"`
+	Y      string        `json:"y"`
+	Labels []interface{} `json:"labels"`
+	Values []interface{} `json:"values"`
+}
+
+// buildSeries reshapes result into a series of x labels and numeric y values.
+// The y column must have a numeric database type; NULL values stay null.
+func buildSeries(result QueryResult, x, y string) (*Series, error) {
+	xIndex, yIndex := -1, -1
+	for i, col := range result.Columns {
+		if col == x && xIndex < 0 {
+			xIndex = i
+		}
+		if col == y && yIndex < 0 {
+			yIndex = i
+		}
+	}
+	if xIndex < 0 || yIndex < 0 {
+		var missing []string
+		if xIndex < 0 {
+			missing = append(missing, x)
+		}
+		if yIndex < 0 {
+			missing = append(missing, y)
+		}
+		return nil, fmt.Errorf("column(s) %s not in result (columns: %s)", strings.Join(missing, ", "), strings.Join(result.Columns, ", "))
+	}
+	if yIndex < len(result.Types) && result.Types[yIndex] != "" && !isNumericType(result.Types[yIndex]) {
+		return nil, fmt.Errorf("y column %s has non-numeric type %s", y, result.Types[yIndex])
+	}
+
+	series := &Series{
+		X:      x,
+		Y:      y,
+		Labels: make([]interface{}, len(result.Rows)),
+		Values: make([]interface{}, len(result.Rows)),
+	}
+	for i, row := range result.Rows {
+		series.Labels[i] = row[xIndex]
+
+		value, err := seriesValue(row[yIndex])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: y column %s: %w", i+1, y, err)
+		}
+		series.Values[i] = value
+	}
+
+	return series, nil
+}
+
+// seriesValue converts a result value into a float64, keeping NULL as nil. The
+// string forms of NULL_REPRESENTATION also count as NULL, since y is numeric.
+func seriesValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case string:
+		if val == "" || val == nullMarker {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not numeric", val)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("value of type %T is not numeric", v)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		maxBytes int
+		want     interface{}
+	}{
+		{name: "disabled", s: "hello world", maxBytes: 0, want: "hello world"},
+		{name: "under the limit", s: "hello", maxBytes: 10, want: "hello"},
+		{name: "exactly at the limit", s: "hello", maxBytes: 5, want: "hello"},
+		{name: "one byte over", s: "hello!", maxBytes: 5, want: TruncatedValue{Value: "hello…[truncated 1 bytes]", Truncated: true, TotalBytes: 6}},
+		{name: "empty", s: "", maxBytes: 5, want: ""},
+		// "é" is two bytes; a limit inside it backs up to the start of the rune
+		{name: "cut inside a two-byte rune", s: "caféx", maxBytes: 4, want: TruncatedValue{Value: "caf…[truncated 3 bytes]", Truncated: true, TotalBytes: 6}},
+		{name: "cut after a two-byte rune", s: "caféx", maxBytes: 5, want: TruncatedValue{Value: "café…[truncated 1 bytes]", Truncated: true, TotalBytes: 6}},
+		// "🙂" is four bytes
+		{name: "cut inside a four-byte rune", s: "a🙂b", maxBytes: 3, want: TruncatedValue{Value: "a…[truncated 5 bytes]", Truncated: true, TotalBytes: 6}},
+		{name: "limit smaller than the first rune", s: "🙂", maxBytes: 2, want: TruncatedValue{Value: "…[truncated 4 bytes]", Truncated: true, TotalBytes: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateText(tt.s, tt.maxBytes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("truncateText(%q, %d) = %#v, want %#v", tt.s, tt.maxBytes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		maxBytes int
+		want     BinaryValue
+	}{
+		{name: "disabled", data: []byte{0, 1, 2, 3}, want: BinaryValue{Binary: "AAECAw==", Encoding: "base64"}},
+		{name: "exactly at the limit", data: []byte{0, 1, 2, 3}, maxBytes: 4, want: BinaryValue{Binary: "AAECAw==", Encoding: "base64"}},
+		{name: "over the limit", data: []byte{0, 1, 2, 3}, maxBytes: 3, want: BinaryValue{Binary: "AAEC", Encoding: "base64", Truncated: true, TotalBytes: 4}},
+		{name: "empty", data: []byte{}, maxBytes: 3, want: BinaryValue{Binary: "", Encoding: "base64"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := binaryValue(tt.data, tt.maxBytes); got != tt.want {
+				t.Errorf("binaryValue(%v, %d) = %#v, want %#v", tt.data, tt.maxBytes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertValueCellLimit(t *testing.T) {
+	opts := ResultOptions{MaxCellBytes: 4}
+	tests := []struct {
+		name     string
+		value    interface{}
+		typeName string
+		want     interface{}
+	}{
+		{name: "string over the limit", value: "abcdef", typeName: "TEXT", want: TruncatedValue{Value: "abcd…[truncated 2 bytes]", Truncated: true, TotalBytes: 6}},
+		{name: "text bytes over the limit", value: []byte("abcdef"), typeName: "JSONB", want: TruncatedValue{Value: "abcd…[truncated 2 bytes]", Truncated: true, TotalBytes: 6}},
+		{name: "binary over the limit", value: []byte("abcdef"), typeName: "BYTEA", want: BinaryValue{Binary: "YWJjZA==", Encoding: "base64", Truncated: true, TotalBytes: 6}},
+		{name: "NULL is never truncated", value: nil, typeName: "TEXT", want: nil},
+		{name: "numbers are never truncated", value: int64(1234567), typeName: "INT8", want: int64(1234567)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertValue(tt.value, tt.typeName, opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertValue(%#v, %q) = %#v, want %#v", tt.value, tt.typeName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertValueBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		typeName string
+		want     interface{}
+	}{
+		{name: "bytea", value: []byte{0xde, 0xad, 0xbe, 0xef}, typeName: "BYTEA", want: BinaryValue{Binary: "3q2+7w==", Encoding: "base64"}},
+		{name: "blob", value: []byte("hi"), typeName: "BLOB", want: BinaryValue{Binary: "aGk=", Encoding: "base64"}},
+		{name: "varbinary", value: []byte{0}, typeName: "VARBINARY", want: BinaryValue{Binary: "AA==", Encoding: "base64"}},
+		{name: "text bytes stay a string", value: []byte("plain text"), typeName: "TEXT", want: "plain text"},
+		{name: "untyped bytes stay a string", value: []byte("plain text"), typeName: "", want: "plain text"},
+		{name: "string stays a string", value: "plain text", typeName: "VARCHAR", want: "plain text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertValue(tt.value, tt.typeName, ResultOptions{}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertValue(%#v, %q) = %#v, want %#v", tt.value, tt.typeName, got, tt.want)
+			}
+		})
+	}
+
+	data, err := json.Marshal(convertValue([]byte("hi"), "BYTEA", ResultOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"$binary":"aGk=","encoding":"base64"}`; string(data) != want {
+		t.Errorf("binary envelope JSON = %s, want %s", data, want)
+	}
+}
+
+func TestNullRepresentation(t *testing.T) {
+	tests := []struct {
+		representation string
+		want           interface{}
+		wantJSON       string
+	}{
+		{representation: "", want: nil, wantJSON: `[null,1]`},
+		{representation: "null", want: nil, wantJSON: `[null,1]`},
+		{representation: "empty", want: "", wantJSON: `["",1]`},
+		{representation: "marker", want: "NULL", wantJSON: `["NULL",1]`},
+		{representation: "Marker", want: "NULL", wantJSON: `["NULL",1]`},
+		{representation: "unknown", want: nil, wantJSON: `[null,1]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.representation, func(t *testing.T) {
+			opts := NewResultOptions(&Config{NullRepresentation: tt.representation, TimestampFormat: defaultTSFormat})
+			got := convertValue(nil, "TEXT", opts)
+			if got != tt.want {
+				t.Errorf("NULL converted to %#v, want %#v", got, tt.want)
+			}
+
+			data, err := json.Marshal([]interface{}{got, convertValue(int64(1), "INT8", opts)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.wantJSON {
+				t.Errorf("row JSON = %s, want %s", data, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestSeriesValueTreatsNullRepresentationsAsNull(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{value: nil, want: nil},
+		{value: "", want: nil},
+		{value: "NULL", want: nil},
+		{value: int64(3), want: float64(3)},
+		{value: 2.5, want: 2.5},
+		{value: "1.25", want: 1.25},
+		{value: "n/a", wantErr: true},
+		{value: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := seriesValue(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("seriesValue(%#v) = %#v, %v, want %#v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMarshalQueryResultScalar(t *testing.T) {
+	tests := []struct {
+		name   string
+		result QueryResult
+		want   string
+	}{
+		{
+			name:   "count",
+			result: QueryResult{Columns: []string{"count"}, Rows: [][]interface{}{{int64(42)}}},
+			want:   `{"columns":["count"],"rows":[[42]],"scalar":true,"value":42}`,
+		},
+		{
+			name:   "NULL scalar",
+			result: QueryResult{Columns: []string{"max"}, Rows: [][]interface{}{{nil}}},
+			want:   `{"columns":["max"],"rows":[[null]],"scalar":true,"value":null}`,
+		},
+		{
+			name:   "two columns",
+			result: QueryResult{Columns: []string{"a", "b"}, Rows: [][]interface{}{{int64(1), int64(2)}}},
+			want:   `{"columns":["a","b"],"rows":[[1,2]]}`,
+		},
+		{
+			name:   "two rows",
+			result: QueryResult{Columns: []string{"a"}, Rows: [][]interface{}{{int64(1)}, {int64(2)}}},
+			want:   `{"columns":["a"],"rows":[[1],[2]]}`,
+		},
+		{
+			name:   "no rows",
+			result: QueryResult{Columns: []string{"a"}, Rows: [][]interface{}{}},
+			want:   `{"columns":["a"],"rows":[]}`,
+		},
+		{
+			name: "more result sets",
+			result: QueryResult{
+				Columns:        []string{"a"},
+				Rows:           [][]interface{}{{int64(1)}},
+				MoreResultSets: []QueryResult{{Columns: []string{"b"}, Rows: [][]interface{}{{int64(2)}}}},
+			},
+			want: `{"columns":["a"],"rows":[[1]],"more_result_sets":[{"columns":["b"],"rows":[[2]]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := marshalQueryResult(tt.result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("marshalQueryResult = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertValueTemporal(t *testing.T) {
+	plus2 := time.FixedZone("", 2*60*60)
+	ts := time.Date(2024, 3, 9, 14, 5, 6, 123456000, plus2)
+	rfc3339Nano := ResultOptions{TimestampLayout: time.RFC3339Nano}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		typeName string
+		opts     ResultOptions
+		want     interface{}
+	}{
+		{name: "date", value: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), typeName: "DATE", opts: rfc3339Nano, want: "2024-03-09"},
+		{name: "time", value: time.Date(0, 1, 1, 14, 5, 6, 500000000, time.UTC), typeName: "TIME", opts: rfc3339Nano, want: "14:05:06.5"},
+		{name: "timetz", value: time.Date(0, 1, 1, 14, 5, 6, 0, plus2), typeName: "TIMETZ", opts: rfc3339Nano, want: "14:05:06+02:00"},
+		{name: "timestamptz", value: ts, typeName: "TIMESTAMPTZ", opts: rfc3339Nano, want: "2024-03-09T14:05:06.123456+02:00"},
+		{name: "timestamp as rfc3339", value: ts, typeName: "TIMESTAMP", opts: ResultOptions{TimestampLayout: time.RFC3339}, want: "2024-03-09T14:05:06+02:00"},
+		{name: "timestamp as date", value: ts, typeName: "TIMESTAMPTZ", opts: ResultOptions{TimestampLayout: dateLayout}, want: "2024-03-09"},
+		{name: "untyped time", value: ts, typeName: "", opts: rfc3339Nano, want: "2024-03-09T14:05:06.123456+02:00"},
+		{name: "mysql datetime bytes", value: []byte("2024-03-09 14:05:06"), typeName: "DATETIME", opts: rfc3339Nano, want: "2024-03-09T14:05:06Z"},
+		{name: "timestamptz bytes", value: []byte("2024-03-09 14:05:06.5+02"), typeName: "TIMESTAMPTZ", opts: rfc3339Nano, want: "2024-03-09T14:05:06.5+02:00"},
+		{name: "date bytes", value: []byte("2024-03-09"), typeName: "DATE", opts: rfc3339Nano, want: "2024-03-09"},
+		{name: "unparseable bytes stay text", value: []byte("infinity"), typeName: "TIMESTAMP", opts: rfc3339Nano, want: "infinity"},
+		{name: "temporal-looking text is not converted", value: []byte("2024-03-09"), typeName: "TEXT", opts: rfc3339Nano, want: "2024-03-09"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertValue(tt.value, tt.typeName, tt.opts); got != tt.want {
+				t.Errorf("convertValue(%v, %q) = %#v, want %#v", tt.value, tt.typeName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResultOptionsTimestampFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "rfc3339nano", want: time.RFC3339Nano},
+		{format: "RFC3339", want: time.RFC3339},
+		{format: "date", want: dateLayout},
+		{format: "unknown", want: time.RFC3339Nano},
+	}
+	for _, tt := range tests {
+		if got := NewResultOptions(&Config{TimestampFormat: tt.format}).TimestampLayout; got != tt.want {
+			t.Errorf("TIMESTAMP_FORMAT=%s: layout %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -17,30 +19,57 @@ type Session struct {
 	ClientInfo   *ClientInfo
 	Data         map[string]interface{} // For storing session-specific data
 	mu           sync.RWMutex
+
+	// notifications holds log notifications waiting for the client to fetch them
+	notifications []JSONRPCNotification
 }
 
 // SessionManager manages MCP sessions
 type SessionManager struct {
-	sessions map[string]*Session
-	mu       sync.RWMutex
-	ttl      time.Duration
+	sessions    map[string]*Session
+	mu          sync.RWMutex
+	ttl         time.Duration
+	maxLifetime time.Duration
 }
 
-// NewSessionManager creates a new session manager
-func NewSessionManager(ttl time.Duration) *SessionManager {
+// NewSessionManager creates a new session manager. ttl expires idle sessions;
+// maxLifetime, if set, expires sessions by age regardless of activity.
+func NewSessionManager(ttl, maxLifetime time.Duration) *SessionManager {
 	sm := &SessionManager{
-		sessions: make(map[string]*Session),
-		ttl:      ttl,
+		sessions:    make(map[string]*Session),
+		ttl:         ttl,
+		maxLifetime: maxLifetime,
 	}
 
-	// Start cleanup goroutine if TTL is set
-	if ttl > 0 {
-		go sm.cleanupExpiredSessions()
+	// Start cleanup goroutine if any expiry is set
+	if interval := sm.cleanupInterval(); interval > 0 {
+		go sm.cleanupExpiredSessions(interval)
 	}
 
 	return sm
 }
 
+// cleanupInterval returns how often to check for expired sessions, or 0 if sessions never expire
+func (sm *SessionManager) cleanupInterval() time.Duration {
+	interval := sm.ttl / 2
+	if half := sm.maxLifetime / 2; half > 0 && (interval == 0 || half < interval) {
+		interval = half
+	}
+	return interval
+}
+
+// isExpired checks whether a session has been idle longer than the TTL or
+// has existed longer than the maximum lifetime
+func (sm *SessionManager) isExpired(session *Session, now time.Time) bool {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	if sm.ttl > 0 && now.Sub(session.LastActivity) > sm.ttl {
+		return true
+	}
+	return sm.maxLifetime > 0 && now.Sub(session.CreatedAt) > sm.maxLifetime
+}
+
 // CreateSession creates a new session
 func (sm *SessionManager) CreateSession() *Session {
 	l := log.With().Str("scope", "CreateSession").Logger()
@@ -60,17 +89,23 @@ func (sm *SessionManager) CreateSession() *Session {
 	return session
 }
 
-// GetSession retrieves a session by ID
+// GetSession retrieves a session by ID. An expired session the cleanup has not
+// removed yet is deleted and reported missing rather than revived by the lookup.
 func (sm *SessionManager) GetSession(id string) (*Session, bool) {
 	sm.mu.RLock()
 	session, exists := sm.sessions[id]
 	sm.mu.RUnlock()
 
-	if exists {
-		session.Touch()
+	if !exists {
+		return nil, false
+	}
+	if sm.isExpired(session, time.Now()) {
+		sm.DeleteSession(id)
+		return nil, false
 	}
 
-	return session, exists
+	session.Touch()
+	return session, true
 }
 
 // DeleteSession removes a session
@@ -85,9 +120,9 @@ func (sm *SessionManager) DeleteSession(id string) {
 }
 
 // cleanupExpiredSessions periodically removes expired sessions
-func (sm *SessionManager) cleanupExpiredSessions() {
+func (sm *SessionManager) cleanupExpiredSessions(interval time.Duration) {
 	l := log.With().Str("scope", "cleanupExpiredSessions").Logger()
-	ticker := time.NewTicker(sm.ttl / 2)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for range ticker.C {
@@ -96,7 +131,7 @@ func (sm *SessionManager) cleanupExpiredSessions() {
 
 		sm.mu.RLock()
 		for id, session := range sm.sessions {
-			if now.Sub(session.LastActivity) > sm.ttl {
+			if sm.isExpired(session, now) {
 				expired = append(expired, id)
 			}
 		}
@@ -150,3 +185,103 @@ func (s *Session) IsInitialized() bool {
 	s.mu.RUnlock()
 	return initialized
 }
+
+// ClientName returns the name the client gave in initialize, or "" before initialize
+func (s *Session) ClientName() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.ClientInfo == nil {
+		return ""
+	}
+	return s.ClientInfo.Name
+}
+
+// sessionLogLevelKey is the session data key holding the client's requested log level
+const sessionLogLevelKey = "logLevel"
+
+// maxPendingNotifications caps the notifications queued for a session between
+// fetches; the oldest are dropped first
+const maxPendingNotifications = 100
+
+// SetLogLevel records the minimum log level this session's client asked to receive.
+// It only affects log notifications for this session, never the server's own logging.
+func (s *Session) SetLogLevel(level LogLevel) {
+	s.SetData(sessionLogLevelKey, level)
+}
+
+// LogLevelEnabled reports whether a log notification at level should be sent to this
+// session. Sessions that never set a level receive info and above.
+func (s *Session) LogLevelEnabled(level LogLevel) bool {
+	minLevel := LogLevelInfo
+	if value, ok := s.GetData(sessionLogLevelKey); ok {
+		minLevel = value.(LogLevel)
+	}
+	return logLevelSeverity[level] >= logLevelSeverity[minLevel]
+}
+
+// Log queues a notifications/message for this session's client, unless level is
+// below the level the client set
+func (s *Session) Log(level LogLevel, logger, data string) {
+	if !s.LogLevelEnabled(level) {
+		return
+	}
+	params, err := json.Marshal(LogEntry{Level: level, Logger: logger, Data: data})
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifications = append(s.notifications, JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/message",
+		Params:  params,
+	})
+	if over := len(s.notifications) - maxPendingNotifications; over > 0 {
+		s.notifications = append([]JSONRPCNotification(nil), s.notifications[over:]...)
+	}
+}
+
+// TakeNotifications returns the queued notifications, oldest first, and clears the queue
+func (s *Session) TakeNotifications() []JSONRPCNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.notifications
+	s.notifications = nil
+	if pending == nil {
+		pending = []JSONRPCNotification{}
+	}
+	return pending
+}
+
+const sessionDefaultAdapterKey = "defaultAdapter"
+
+// SetDefaultAdapter records the adapter generic tools use for this session when no
+// adapter argument is given. An empty name clears it.
+func (s *Session) SetDefaultAdapter(name string) {
+	s.SetData(sessionDefaultAdapterKey, name)
+}
+
+// DefaultAdapter returns the session's default adapter name, or "" when none is set
+func (s *Session) DefaultAdapter() string {
+	if value, ok := s.GetData(sessionDefaultAdapterKey); ok {
+		return value.(string)
+	}
+	return ""
+}
+
+type sessionKey struct{}
+
+// withSession attaches the request's session to a context
+func withSession(ctx context.Context, session *Session) context.Context {
+	if session == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// sessionFrom returns the session attached to a context, or nil
+func sessionFrom(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionKey{}).(*Session)
+	return session
+}
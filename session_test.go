@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetSessionExpiry(t *testing.T) {
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		maxLifetime  time.Duration
+		idle         time.Duration
+		age          time.Duration
+		wantExisting bool
+	}{
+		{name: "active", ttl: time.Hour, idle: time.Minute, age: time.Minute, wantExisting: true},
+		{name: "idle past ttl", ttl: time.Hour, idle: 2 * time.Hour, age: 2 * time.Hour},
+		{name: "older than max lifetime", ttl: time.Hour, maxLifetime: time.Hour, idle: time.Minute, age: 2 * time.Hour},
+		{name: "no expiry", idle: 48 * time.Hour, age: 48 * time.Hour, wantExisting: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSessionManager(tt.ttl, tt.maxLifetime)
+			session := sm.CreateSession()
+			now := time.Now()
+			session.CreatedAt = now.Add(-tt.age)
+			session.LastActivity = now.Add(-tt.idle)
+
+			got, ok := sm.GetSession(session.ID)
+			if ok != tt.wantExisting || (got != nil) != tt.wantExisting {
+				t.Fatalf("GetSession = %v, %v, want existing %v", got, ok, tt.wantExisting)
+			}
+			if tt.wantExisting {
+				if time.Since(got.LastActivity) > time.Second {
+					t.Error("GetSession did not touch the session")
+				}
+				return
+			}
+
+			// The expired session is gone, not revived by the lookup
+			if _, ok := sm.GetSession(session.ID); ok {
+				t.Error("expired session found on the second lookup")
+			}
+		})
+	}
+}
+
+func TestSessionLogQueueDropsOldest(t *testing.T) {
+	session := NewSessionManager(0, 0).CreateSession()
+	session.SetLogLevel(LogLevelDebug)
+	for i := 0; i < maxPendingNotifications+5; i++ {
+		session.Log(LogLevelInfo, "test", strconv.Itoa(i))
+	}
+
+	pending := session.TakeNotifications()
+	if len(pending) != maxPendingNotifications {
+		t.Fatalf("queued %d notifications, want %d", len(pending), maxPendingNotifications)
+	}
+	var first LogEntry
+	if err := json.Unmarshal(pending[0].Params, &first); err != nil || first.Data != "5" {
+		t.Errorf("oldest queued = %+v, %v, want message 5", first, err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSignatureVerifier(t *testing.T) {
+	sign := func(key, body string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	const body = `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+	// Signatures carry no timestamp or nonce, so clock skew does not apply and a
+	// captured request can be replayed; only the body is authenticated
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		status    int
+	}{
+		{name: "valid", body: body, signature: sign("secret", body), status: fiber.StatusOK},
+		{name: "valid with sha256= prefix", body: body, signature: "sha256=" + sign("secret", body), status: fiber.StatusOK},
+		{name: "valid upper-case hex", body: body, signature: strings.ToUpper(sign("secret", body)), status: fiber.StatusOK},
+		{name: "tampered body", body: strings.Replace(body, "ping", "tools/list", 1), signature: sign("secret", body), status: fiber.StatusUnauthorized},
+		{name: "wrong key", body: body, signature: sign("other", body), status: fiber.StatusUnauthorized},
+		{name: "missing header", body: body, status: fiber.StatusUnauthorized},
+		{name: "not hex", body: body, signature: "not-a-signature", status: fiber.StatusUnauthorized},
+		{name: "truncated signature", body: body, signature: sign("secret", body)[:32], status: fiber.StatusUnauthorized},
+	}
+
+	app := fiber.New()
+	app.Post("/", NewSignatureVerifier("secret"), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// templateTypes maps the placeholder types accepted in {{name:type}} (and their
+// aliases) to a canonical type and the PostgreSQL cast applied to its parameter
+var templateTypes = map[string]struct {
+	canonical string
+	pgCast    string
+}{
+	"text":      {"text", "text"},
+	"string":    {"text", "text"},
+	"integer":   {"integer", "bigint"},
+	"int":       {"integer", "bigint"},
+	"number":    {"number", "numeric"},
+	"numeric":   {"number", "numeric"},
+	"boolean":   {"boolean", "boolean"},
+	"bool":      {"boolean", "boolean"},
+	"date":      {"date", "date"},
+	"timestamp": {"timestamp", "timestamptz"},
+	"uuid":      {"uuid", "uuid"},
+}
+
+// templateTimestampLayouts are the accepted timestamp formats, most specific first
+var templateTimestampLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// templateDialect selects how bound placeholders are written: PostgreSQL reuses a
+// $n position, with a cast, for each name; MySQL writes ? and repeats the argument
+type templateDialect int
+
+const (
+	templatePostgres templateDialect = iota
+	templateMySQL
+)
+
+// bindQueryTemplate replaces {{name:type}} placeholders with bound parameters after
+// checking each value against its declared type. Every placeholder must declare a
+// type (the same one each time it appears) and have a value, and every value must be
+// used. Braces inside string literals, quoted identifiers, and comments are left
+// alone. A query without placeholders and no values is returned unchanged.
+func bindQueryTemplate(query string, values map[string]interface{}, dialect templateDialect) (string, []interface{}, error) {
+	mysqlQuoting := dialect == templateMySQL
+
+	var b strings.Builder
+	b.Grow(len(query))
+
+	types := make(map[string]string)
+	positions := make(map[string]int)
+	coerced := make(map[string]interface{})
+	var args []interface{}
+	var missing []string
+
+	n := len(query)
+	for i := 0; i < n; {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			backslash := c != '`' && mysqlQuoting ||
+				c == '\'' && i > 0 && (query[i-1] == 'e' || query[i-1] == 'E') && (i < 2 || !isIdentChar(query[i-2]))
+			end := skipQuoted(query, i, c, backslash)
+			b.WriteString(query[i:end])
+			i = end
+
+		case c == '$' && !mysqlQuoting:
+			if end, ok := skipDollarQuoted(query, i); ok {
+				b.WriteString(query[i:end])
+				i = end
+				break
+			}
+			b.WriteByte(c)
+			i++
+
+		case c == '-' && i+1 < n && query[i+1] == '-' && (!mysqlQuoting || i+2 == n || query[i+2] <= ' '),
+			c == '#' && mysqlQuoting:
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = n
+			} else {
+				end += i + 4
+			}
+			b.WriteString(query[i:end])
+			i = end
+
+		case c == '{' && i+1 < n && query[i+1] == '{':
+			end := strings.Index(query[i+2:], "}}")
+			if end < 0 {
+				return "", nil, fmt.Errorf("unterminated placeholder at offset %d", i)
+			}
+			name, typ, err := parseTemplatePlaceholder(query[i+2 : i+2+end])
+			if err != nil {
+				return "", nil, err
+			}
+			i += end + 4
+
+			if declared, ok := types[name]; ok && declared != typ {
+				return "", nil, fmt.Errorf("placeholder %s is declared as both %s and %s", name, declared, typ)
+			}
+			if _, seen := types[name]; !seen {
+				types[name] = typ
+				value, ok := values[name]
+				if !ok {
+					missing = append(missing, name)
+				} else {
+					v, err := coerceTemplateValue(value, typ)
+					if err != nil {
+						return "", nil, fmt.Errorf("placeholder %s: %w", name, err)
+					}
+					coerced[name] = v
+				}
+			}
+
+			if dialect == templateMySQL {
+				args = append(args, coerced[name])
+				b.WriteByte('?')
+				break
+			}
+			pos, ok := positions[name]
+			if !ok {
+				args = append(args, coerced[name])
+				pos = len(args)
+				positions[name] = pos
+			}
+			fmt.Fprintf(&b, "$%d::%s", pos, templateTypes[typ].pgCast)
+
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("missing values for placeholders: %s", strings.Join(missing, ", "))
+	}
+	var unused []string
+	for name := range values {
+		if _, ok := types[name]; !ok {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", nil, fmt.Errorf("values for undeclared placeholders: %s", strings.Join(unused, ", "))
+	}
+
+	return b.String(), args, nil
+}
+
+// parseTemplatePlaceholder splits the inside of {{name:type}} and returns the name
+// and canonical type
+func parseTemplatePlaceholder(inner string) (string, string, error) {
+	name, typ, ok := strings.Cut(inner, ":")
+	name, typ = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(typ))
+	if !isTemplateName(name) {
+		return "", "", fmt.Errorf("invalid placeholder name in {{%s}}", inner)
+	}
+	if !ok || typ == "" {
+		return "", "", fmt.Errorf("placeholder %s must declare a type, e.g. {{%s:text}}", name, name)
+	}
+	t, known := templateTypes[typ]
+	if !known {
+		return "", "", fmt.Errorf("placeholder %s has unknown type %s (supported: text, integer, number, boolean, date, timestamp, uuid)", name, typ)
+	}
+	return name, t.canonical, nil
+}
+
+// isTemplateName reports whether name is a valid placeholder name: a letter or
+// underscore followed by letters, digits, or underscores
+func isTemplateName(name string) bool {
+	if name == "" || !isIdentStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isIdentChar(name[i]) || name[i] == '$' {
+			return false
+		}
+	}
+	return true
+}
+
+// coerceTemplateValue checks a JSON value against a canonical placeholder type and
+// converts it to the value bound as the parameter. Strings are accepted for every
+// type, since clients often send numbers and booleans as strings.
+func coerceTemplateValue(value interface{}, typ string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	s, isString := value.(string)
+	switch typ {
+	case "text":
+		if !isString {
+			return nil, fmt.Errorf("expected a string, got %T", value)
+		}
+		return s, nil
+
+	case "integer":
+		if f, ok := value.(float64); ok {
+			if f != math.Trunc(f) || math.Abs(f) >= 1<<63 {
+				return nil, fmt.Errorf("expected an integer, got %v", f)
+			}
+			return int64(f), nil
+		}
+		if isString {
+			i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected an integer, got %q", s)
+			}
+			return i, nil
+		}
+
+	case "number":
+		if f, ok := value.(float64); ok {
+			return f, nil
+		}
+		if isString {
+			// Keep the string so decimals are not rounded through float64
+			if _, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+				return nil, fmt.Errorf("expected a number, got %q", s)
+			}
+			return strings.TrimSpace(s), nil
+		}
+
+	case "boolean":
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+		if isString {
+			v, err := strconv.ParseBool(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("expected a boolean, got %q", s)
+			}
+			return v, nil
+		}
+
+	case "date":
+		if isString {
+			d, err := time.Parse("2006-01-02", strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("expected a date as YYYY-MM-DD, got %q", s)
+			}
+			return d.Format("2006-01-02"), nil
+		}
+
+	case "timestamp":
+		if isString {
+			for _, layout := range templateTimestampLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("expected an RFC 3339 timestamp, got %q", s)
+		}
+
+	case "uuid":
+		if isString {
+			u, err := uuid.Parse(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("expected a UUID, got %q", s)
+			}
+			return u.String(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("expected a %s, got %T", typ, value)
+}
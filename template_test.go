@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoerceTemplateValue(t *testing.T) {
+	tests := []struct {
+		typ     string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{typ: "text", value: "a", want: "a"},
+		{typ: "text", value: 1.0, wantErr: true},
+		{typ: "integer", value: 42.0, want: int64(42)},
+		{typ: "integer", value: " 7 ", want: int64(7)},
+		{typ: "integer", value: 1.5, wantErr: true},
+		{typ: "integer", value: "x", wantErr: true},
+		{typ: "integer", value: true, wantErr: true},
+		{typ: "number", value: 1.5, want: 1.5},
+		{typ: "number", value: " 3.14 ", want: "3.14"},
+		{typ: "number", value: "abc", wantErr: true},
+		{typ: "boolean", value: true, want: true},
+		{typ: "boolean", value: "false", want: false},
+		{typ: "boolean", value: "yes", wantErr: true},
+		{typ: "boolean", value: 1.0, wantErr: true},
+		{typ: "date", value: "2024-02-29", want: "2024-02-29"},
+		{typ: "date", value: "2023-02-29", wantErr: true},
+		{typ: "date", value: 1.0, wantErr: true},
+		{typ: "timestamp", value: "2024-01-02T03:04:05Z", want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{typ: "timestamp", value: "2024-01-02 03:04:05", want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{typ: "timestamp", value: "2024-01-02", want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{typ: "timestamp", value: "soon", wantErr: true},
+		{typ: "uuid", value: "A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11", want: "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{typ: "uuid", value: "nope", wantErr: true},
+		{typ: "integer", value: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		got, err := coerceTemplateValue(tt.value, tt.typ)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("coerceTemplateValue(%#v, %s) error = %v, wantErr %v", tt.value, tt.typ, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("coerceTemplateValue(%#v, %s) = %#v, want %#v", tt.value, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestBindQueryTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		values  map[string]interface{}
+		dialect templateDialect
+		want    string
+		args    []interface{}
+		wantErr bool
+	}{
+		{
+			name:   "postgres reuses positions and casts",
+			query:  "SELECT * FROM t WHERE id = {{id:int}} OR parent = {{id:integer}} AND name = {{name:string}}",
+			values: map[string]interface{}{"id": "5", "name": "x"},
+			want:   "SELECT * FROM t WHERE id = $1::bigint OR parent = $1::bigint AND name = $2::text",
+			args:   []interface{}{int64(5), "x"},
+		},
+		{
+			name:    "mysql repeats arguments",
+			query:   "SELECT * FROM t WHERE id = {{id:int}} OR parent = {{id:int}} AND active = {{on:bool}}",
+			values:  map[string]interface{}{"id": 5.0, "on": "true"},
+			dialect: templateMySQL,
+			want:    "SELECT * FROM t WHERE id = ? OR parent = ? AND active = ?",
+			args:    []interface{}{int64(5), int64(5), true},
+		},
+		{
+			name:   "postgres quotes and comments",
+			query:  "SELECT '{{a:int}}', E'\\'{{a:int}}', \"{{b}}\", $$ {{c}} $$ -- {{d}}\n/* {{e}} */ FROM t WHERE id = {{id:uuid}}",
+			values: map[string]interface{}{"id": "A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11"},
+			want:   "SELECT '{{a:int}}', E'\\'{{a:int}}', \"{{b}}\", $$ {{c}} $$ -- {{d}}\n/* {{e}} */ FROM t WHERE id = $1::uuid",
+			args:   []interface{}{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		},
+		{
+			name:    "mysql quotes and comments",
+			query:   "SELECT 'it\\'s {{a}}', `{{b}}` # {{c}}\n-- {{d}}\nFROM t WHERE d = {{d:date}}",
+			values:  map[string]interface{}{"d": "2024-01-31"},
+			dialect: templateMySQL,
+			want:    "SELECT 'it\\'s {{a}}', `{{b}}` # {{c}}\n-- {{d}}\nFROM t WHERE d = ?",
+			args:    []interface{}{"2024-01-31"},
+		},
+		{
+			name:  "no placeholders",
+			query: "SELECT 1",
+			want:  "SELECT 1",
+		},
+		{name: "missing value", query: "SELECT {{a:int}}", wantErr: true},
+		{name: "value for an undeclared placeholder", query: "SELECT {{a:int}}", values: map[string]interface{}{"a": 1.0, "b": 2.0}, wantErr: true},
+		{name: "unused value without placeholders", query: "SELECT 1", values: map[string]interface{}{"a": 1.0}, wantErr: true},
+		{name: "type not declared", query: "SELECT {{a}}", values: map[string]interface{}{"a": 1.0}, wantErr: true},
+		{name: "unknown type", query: "SELECT {{a:json}}", values: map[string]interface{}{"a": 1.0}, wantErr: true},
+		{name: "conflicting types", query: "SELECT {{a:int}}, {{a:text}}", values: map[string]interface{}{"a": 1.0}, wantErr: true},
+		{name: "value of the wrong type", query: "SELECT {{a:int}}", values: map[string]interface{}{"a": "one"}, wantErr: true},
+		{name: "unterminated", query: "SELECT {{a:int", values: map[string]interface{}{"a": 1.0}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := bindQueryTemplate(tt.query, tt.values, tt.dialect)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bindQueryTemplate error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
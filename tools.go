@@ -3,43 +3,105 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// schemaDumpTimeout is the execution budget for tools that dump whole schemas
+const schemaDumpTimeout = 2 * time.Minute
+
 // ToolRegistry manages available tools
 type ToolRegistry struct {
-	tools    map[string]Tool
-	handlers map[string]ToolHandler
-	mu       sync.RWMutex
+	tools          map[string]Tool
+	handlers       map[string]ToolHandler
+	validators     map[string]ToolValidator
+	timeouts       map[string]time.Duration
+	defaultTimeout time.Duration
+	mu             sync.RWMutex
+
+	// maxTools caps the number of registered tools; 0 means no limit
+	maxTools int
+	// skipped counts registrations refused because the cap was reached
+	skipped int
+	// maxOutputChars caps the text returned by one tool call; 0 means no limit
+	maxOutputChars int
 }
 
 // ToolHandler is a function that handles tool execution
 type ToolHandler func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error)
 
+// ToolValidator checks tool arguments without executing the tool
+type ToolValidator func(arguments json.RawMessage) error
+
+// ToolOption configures optional behavior of a registered tool
+type ToolOption func(r *ToolRegistry, name string)
+
+// WithValidator adds a pre-check that runs when validating the tool's arguments
+func WithValidator(validator ToolValidator) ToolOption {
+	return func(r *ToolRegistry, name string) {
+		r.validators[name] = validator
+	}
+}
+
+// WithTimeout overrides the default execution timeout for the tool
+func WithTimeout(timeout time.Duration) ToolOption {
+	return func(r *ToolRegistry, name string) {
+		r.timeouts[name] = timeout
+	}
+}
+
 // NewToolRegistry creates a new tool registry
-func NewToolRegistry() *ToolRegistry {
+func NewToolRegistry(cfg *Config) *ToolRegistry {
 	return &ToolRegistry{
-		tools:    make(map[string]Tool),
-		handlers: make(map[string]ToolHandler),
+		tools:          make(map[string]Tool),
+		handlers:       make(map[string]ToolHandler),
+		validators:     make(map[string]ToolValidator),
+		timeouts:       make(map[string]time.Duration),
+		defaultTimeout: cfg.ToolTimeout,
+		maxTools:       cfg.MaxTools,
+		maxOutputChars: cfg.MaxOutputChars,
 	}
 }
 
 // RegisterTool registers a tool with its handler
-func (r *ToolRegistry) RegisterTool(tool Tool, handler ToolHandler) {
+func (r *ToolRegistry) RegisterTool(tool Tool, handler ToolHandler, opts ...ToolOption) {
 	l := log.With().Str("scope", "RegisterTool").Logger()
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	// Re-registering an existing name replaces it and never counts against the cap
+	if _, exists := r.tools[tool.Name]; !exists && r.maxTools > 0 && len(r.tools) >= r.maxTools {
+		r.skipped++
+		l.Warn().Str("tool", tool.Name).Int("max_tools", r.maxTools).Msg("Tool limit reached, tool not registered")
+		return
+	}
+
 	r.tools[tool.Name] = tool
 	r.handlers[tool.Name] = handler
+	for _, opt := range opts {
+		opt(r, tool.Name)
+	}
 
 	l.Debug().Str("tool", tool.Name).Msg("Tool registered")
 }
 
+// SkippedTools returns how many registrations were refused by the MAX_TOOLS cap
+func (r *ToolRegistry) SkippedTools() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.skipped
+}
+
 // ListTools returns all registered tools
 func (r *ToolRegistry) ListTools() []Tool {
 	r.mu.RLock()
@@ -54,10 +116,12 @@ func (r *ToolRegistry) ListTools() []Tool {
 
 // CallTool executes a tool by name
 func (r *ToolRegistry) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
-	l := log.With().Str("scope", "CallTool").Str("tool", name).Logger()
+	l := loggerFrom(ctx).With().Str("scope", "CallTool").Str("tool", name).Logger()
 
 	r.mu.RLock()
 	handler, exists := r.handlers[name]
+	timeout, hasTimeout := r.timeouts[name]
+	tool := r.tools[name]
 	r.mu.RUnlock()
 
 	if !exists {
@@ -65,16 +129,39 @@ func (r *ToolRegistry) CallTool(ctx context.Context, name string, arguments json
 		return nil, fmt.Errorf("tool not found: %s", name)
 	}
 
+	arguments = normalizeBooleanArguments(tool.InputSchema, arguments)
+
+	ctx, span := tracer.Start(ctx, "tool.call", trace.WithAttributes(attribute.String("mcp.tool.name", name)))
+	defer span.End()
+
+	if !hasTimeout {
+		timeout = r.defaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	if debugMode {
-		l.Debug().RawJSON("arguments", arguments).Msg("Calling tool")
+		l.Debug().RawJSON("arguments", arguments).Dur("timeout", timeout).Msg("Calling tool")
 	}
 
 	result, err := handler(ctx, arguments)
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !errors.Is(err, ErrPoolExhausted) {
+			err = fmt.Errorf("tool %s timed out after %s: %w", name, timeout, err)
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		l.Error().Err(err).Msg("Tool execution failed")
 		return nil, err
 	}
 
+	if truncated := limitOutput(result, r.maxOutputChars); truncated > 0 {
+		l.Warn().Int("max_output_chars", r.maxOutputChars).Int("chars_dropped", truncated).Msg("Tool output truncated")
+	}
+
 	if debugMode {
 		l.Debug().Interface("result", result).Msg("Tool execution completed")
 	}
@@ -82,8 +169,182 @@ func (r *ToolRegistry) CallTool(ctx context.Context, name string, arguments json
 	return result, nil
 }
 
+// limitOutput truncates the text content of result so the characters across all
+// text blocks fit in maxChars, then appends a notice block saying how much was cut.
+// The block that crosses the budget is cut at a character boundary and later text
+// blocks are dropped; other content is kept. It returns the number of characters
+// removed, 0 when the result already fits or maxChars is 0.
+func limitOutput(result *CallToolResult, maxChars int) int {
+	if result == nil || maxChars <= 0 {
+		return 0
+	}
+
+	total := 0
+	for _, c := range result.Content {
+		if text, ok := c.(TextContent); ok {
+			total += utf8.RuneCountInString(text.Text)
+		}
+	}
+	if total <= maxChars {
+		return 0
+	}
+
+	remaining := maxChars
+	content := make([]Content, 0, len(result.Content)+1)
+	for _, c := range result.Content {
+		text, ok := c.(TextContent)
+		if !ok {
+			content = append(content, c)
+			continue
+		}
+		if remaining == 0 {
+			continue
+		}
+		if n := utf8.RuneCountInString(text.Text); n > remaining {
+			cut := 0
+			for i := 0; i < remaining; i++ {
+				_, size := utf8.DecodeRuneInString(text.Text[cut:])
+				cut += size
+			}
+			text.Text = text.Text[:cut]
+			remaining = 0
+		} else {
+			remaining -= n
+		}
+		content = append(content, text)
+	}
+
+	dropped := total - maxChars
+	content = append(content, TextContent{
+		Type: "text",
+		Text: fmt.Sprintf("[output truncated: showing %d of %d characters (MAX_OUTPUT_CHARS=%d); narrow the query or lower its limit to see the rest]", maxChars, total, maxChars),
+	})
+	result.Content = content
+
+	return dropped
+}
+
+// parseArguments decodes tool arguments into v, treating missing arguments as an empty object
+func parseArguments(arguments json.RawMessage, v interface{}) error {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(arguments, v); err != nil {
+		return fmt.Errorf("invalid parameters: %w", err)
+	}
+	return nil
+}
+
+// clampLimit applies a default to a non-positive limit and caps it at max
+func clampLimit(limit, defaultLimit, max int) int {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// textResult wraps text as a tool result
+func textResult(text string) *CallToolResult {
+	return &CallToolResult{
+		Content: []Content{
+			TextContent{
+				Type: "text",
+				Text: text,
+			},
+		},
+	}
+}
+
+// jsonResult marshals v and wraps it as a text tool result
+func jsonResult(v interface{}) (*CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return textResult(string(data)), nil
+}
+
+// dryRunProperty is the input schema of the dry_run argument shared by tools that
+// generate their own SQL
+var dryRunProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "Return the SQL this tool would run, with its bound parameters, instead of running it",
+}
+
+// dryRunResult returns a generated query as text for dry runs. Bound arguments are
+// listed after it as comments, in placeholder order.
+func dryRunResult(query string, args []interface{}) *CallToolResult {
+	var b strings.Builder
+	b.WriteString(query)
+	for i, arg := range args {
+		value, err := json.Marshal(arg)
+		if err != nil {
+			value = []byte(fmt.Sprint(arg))
+		}
+		fmt.Fprintf(&b, "\n-- parameter %d: %s", i+1, value)
+	}
+	return textResult(b.String())
+}
+
+// ArgumentErrors checks arguments against the tool's input schema and returns every
+// problem found. Unknown tools have no argument errors.
+func (r *ToolRegistry) ArgumentErrors(name string, arguments json.RawMessage) []string {
+	r.mu.RLock()
+	tool, exists := r.tools[name]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil
+	}
+	return validateArguments(tool.InputSchema, arguments)
+}
+
+// ValidateTool checks arguments against the tool's input schema and its declared
+// pre-checks without executing it
+func (r *ToolRegistry) ValidateTool(name string, arguments json.RawMessage) (*ValidationResult, error) {
+	r.mu.RLock()
+	_, exists := r.tools[name]
+	validator := r.validators[name]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("tool not found: %s", name)
+	}
+
+	errs := r.ArgumentErrors(name, arguments)
+	if len(errs) == 0 && validator != nil {
+		if err := validator(arguments); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	return &ValidationResult{Valid: len(errs) == 0, Errors: errs}, nil
+}
+
+// validateQueryArguments pre-checks query tool arguments: the query must pass read-only
+// validation and any options must be allowlisted
+func validateQueryArguments(arguments json.RawMessage, policy ReadOnlyPolicy, allowedOptions map[string]bool) error {
+	var params struct {
+		Query   string                 `json:"query"`
+		Options map[string]interface{} `json:"options"`
+	}
+
+	if err := parseArguments(arguments, &params); err != nil {
+		return err
+	}
+
+	if _, err := validateReadOnlyQuery(params.Query, policy); err != nil {
+		return err
+	}
+
+	return parseQueryOptions(params.Options).validate(allowedOptions)
+}
+
 // RegisterTools registers all tools for the MCP server
-func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
+func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry, cfg *Config) {
 	l := log.With().Str("scope", "RegisterTools").Logger()
 
 
@@ -95,7 +356,7 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 		registry.RegisterTool(
 			Tool{
 				Name:        "postgres_schemas",
-				Description: "List all schemas in the PostgreSQL database",
+				Description: "List all schemas in the connected PostgreSQL database. Other databases on the server have their own schemas, which are not listed here (see postgres_list_databases)",
 				InputSchema: InputSchema{
 					Type:       "object",
 					Properties: map[string]interface{}{},
@@ -167,6 +428,7 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					},
 				}, nil
 			},
+			WithTimeout(schemaDumpTimeout),
 		)
 
 		// postgres_query_select tool
@@ -179,7 +441,26 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					Properties: map[string]interface{}{
 						"query": map[string]interface{}{
 							"type":        "string",
-							"description": "SELECT query to execute",
+							"description": "SELECT query to execute, optionally with {{name:type}} placeholders filled from values",
+						},
+						"options": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": true,
+							"description":          "Per-query settings applied with SET LOCAL, e.g. {\"work_mem\": \"64MB\", \"enable_seqscan\": \"off\"}. Only allowlisted settings are accepted",
+						},
+						"describe_result": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Return only the result's columns, their types, and its row count (via a wrapping COUNT(*)) instead of the rows. SELECT and WITH queries only",
+						},
+						"image_columns": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]interface{}{"type": "string"},
+							"description": "Binary columns to return as image content, or [\"*\"] for all columns. Values whose bytes are a PNG, JPEG, GIF, WebP, BMP or ICO image become image blocks after the JSON result and are replaced in it by {\"$image\": n, \"mime_type\": ...}; other values are unchanged",
+						},
+						"values": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": true,
+							"description":          "Values for {{name:type}} placeholders in the query, e.g. {\"start_date\": \"2024-01-01\"} for WHERE created_at >= {{start_date:date}}. Types: text, integer, number, boolean, date, timestamp, uuid. Each value is checked against its type and bound as a parameter",
 						},
 					},
 					Required: []string{"query"},
@@ -187,7 +468,11 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 			},
 			func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
 				var params struct {
-					Query string `json:"query"`
+					Query          string                 `json:"query"`
+					Options        map[string]interface{} `json:"options"`
+					Values         map[string]interface{} `json:"values"`
+					DescribeResult FlexBool               `json:"describe_result"`
+					ImageColumns   []string               `json:"image_columns"`
 				}
 
 				if err := json.Unmarshal(arguments, &params); err != nil {
@@ -198,13 +483,31 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					return nil, fmt.Errorf("query is required")
 				}
 
-				result, err := postgresAdapter.ExecuteSelect(ctx, params.Query)
+				query, args, err := bindQueryTemplate(params.Query, params.Values, templatePostgres)
 				if err != nil {
 					return nil, err
 				}
 
+				ctx = withQueryOptions(withQueryArgs(ctx, args), parseQueryOptions(params.Options))
+				if params.DescribeResult {
+					desc, err := describeResult(ctx, adapters, postgresAdapter, query, postgresAdapter.policy)
+					if err != nil {
+						return nil, err
+					}
+					return jsonResult(desc)
+				}
+
+				result, err := adapters.ExecuteSelect(ctx, postgresAdapter, query)
+				if err != nil {
+					return nil, err
+				}
+
+				if len(params.ImageColumns) > 0 {
+					return imageResult(result, params.ImageColumns)
+				}
+
 				// Convert to JSON
-				resultJSON, err := json.Marshal(result)
+				resultJSON, err := marshalQueryResult(result)
 				if err != nil {
 					return nil, err
 				}
@@ -218,7 +521,19 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					},
 				}, nil
 			},
+			WithValidator(func(arguments json.RawMessage) error {
+				return validateQueryArguments(arguments, postgresAdapter.policy, postgresQueryOptions)
+			}),
 		)
+
+		registerPostgresIntrospectionTools(registry, postgresAdapter)
+		registerPostgresQueryTools(registry, adapters, postgresAdapter, cfg)
+		if cfg.EnableDiagnosticTools {
+			registerPostgresDiagnosticTools(registry, postgresAdapter)
+		}
+		if cfg.AllowWrites {
+			registerPostgresWriteTools(registry, adapters, postgresAdapter)
+		}
 	}
 
 	// MySQL tools
@@ -235,7 +550,26 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					Properties: map[string]interface{}{
 						"query": map[string]interface{}{
 							"type":        "string",
-							"description": "SELECT query to execute",
+							"description": "SELECT query to execute, optionally with {{name:type}} placeholders filled from values",
+						},
+						"options": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": true,
+							"description":          "Per-query variables applied as SET_VAR optimizer hints, e.g. {\"max_execution_time\": 5000}. Only allowlisted variables are accepted",
+						},
+						"describe_result": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Return only the result's columns, their types, and its row count (via a wrapping COUNT(*)) instead of the rows. SELECT and WITH queries only",
+						},
+						"image_columns": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]interface{}{"type": "string"},
+							"description": "Binary columns to return as image content, or [\"*\"] for all columns. Values whose bytes are a PNG, JPEG, GIF, WebP, BMP or ICO image become image blocks after the JSON result and are replaced in it by {\"$image\": n, \"mime_type\": ...}; other values are unchanged",
+						},
+						"values": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": true,
+							"description":          "Values for {{name:type}} placeholders in the query, e.g. {\"start_date\": \"2024-01-01\"} for WHERE created_at >= {{start_date:date}}. Types: text, integer, number, boolean, date, timestamp, uuid. Each value is checked against its type and bound as a parameter",
 						},
 					},
 					Required: []string{"query"},
@@ -243,7 +577,11 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 			},
 			func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
 				var params struct {
-					Query string `json:"query"`
+					Query          string                 `json:"query"`
+					Options        map[string]interface{} `json:"options"`
+					Values         map[string]interface{} `json:"values"`
+					DescribeResult FlexBool               `json:"describe_result"`
+					ImageColumns   []string               `json:"image_columns"`
 				}
 
 				if err := json.Unmarshal(arguments, &params); err != nil {
@@ -254,13 +592,31 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					return nil, fmt.Errorf("query is required")
 				}
 
-				result, err := mysqlAdapter.ExecuteSelect(ctx, params.Query)
+				query, args, err := bindQueryTemplate(params.Query, params.Values, templateMySQL)
+				if err != nil {
+					return nil, err
+				}
+
+				ctx = withQueryOptions(withQueryArgs(ctx, args), parseQueryOptions(params.Options))
+				if params.DescribeResult {
+					desc, err := describeResult(ctx, adapters, mysqlAdapter, query, mysqlAdapter.policy)
+					if err != nil {
+						return nil, err
+					}
+					return jsonResult(desc)
+				}
+
+				result, err := adapters.ExecuteSelect(ctx, mysqlAdapter, query)
 				if err != nil {
 					return nil, err
 				}
 
+				if len(params.ImageColumns) > 0 {
+					return imageResult(result, params.ImageColumns)
+				}
+
 				// Convert to JSON
-				resultJSON, err := json.Marshal(result)
+				resultJSON, err := marshalQueryResult(result)
 				if err != nil {
 					return nil, err
 				}
@@ -274,13 +630,16 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					},
 				}, nil
 			},
+			WithValidator(func(arguments json.RawMessage) error {
+				return validateQueryArguments(arguments, mysqlAdapter.policy, mysqlQueryOptions)
+			}),
 		)
 
 		// mysql_schema_ddls tool
 		registry.RegisterTool(
 			Tool{
 				Name:        "mysql_schema_ddls",
-				Description: "Get DDL statements for a MySQL schema",
+				Description: "Get DDL statements for a MySQL schema (in MySQL a schema is a database)",
 				InputSchema: InputSchema{
 					Type: "object",
 					Properties: map[string]interface{}{
@@ -319,8 +678,29 @@ func RegisterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
 					},
 				}, nil
 			},
+			WithTimeout(schemaDumpTimeout),
 		)
+
+		registerMySQLIntrospectionTools(registry, mysqlAdapter)
+		registerMySQLQueryTools(registry, adapters, mysqlAdapter)
+	}
+
+	registerServerInfoTools(registry, adapters, cfg)
+
+	if !adapters.IsEmpty() {
+		registerAdapterInfoTools(registry, adapters)
+		registerGenericAdapterTools(registry, adapters)
+
+		// The default adapter is stored per session
+		if cfg.UseSession {
+			registerDefaultAdapterTool(registry, adapters)
+		}
+	}
+
+	// Cross-adapter tools need at least two databases to compare
+	if len(adapters.List()) >= 2 {
+		registerCrossAdapterTools(registry, adapters)
 	}
 
-	l.Info().Int("total_tools", len(registry.ListTools())).Msg("Tools registered")
+	l.Info().Int("total_tools", len(registry.ListTools())).Int("skipped_tools", registry.SkippedTools()).Msg("Tools registered")
 }
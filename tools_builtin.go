@@ -0,0 +1,481 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdapterCount is the row count of a table on one adapter, or why it could not be counted
+type AdapterCount struct {
+	Adapter string `json:"adapter"`
+	Count   *int64 `json:"count"`
+	Error   string `json:"error,omitempty"`
+}
+
+// countRows counts the rows of schemaName.tableName on adapter through the registry's query path
+func countRows(ctx context.Context, adapters *AdapterRegistry, adapter DatabaseAdapter, schemaName, tableName string) AdapterCount {
+	count := AdapterCount{Adapter: adapter.Name()}
+
+	quoter, ok := adapter.(IdentifierQuoter)
+	if !ok {
+		count.Error = "adapter does not support identifier quoting"
+		return count
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s", quoter.QuoteIdent(schemaName), quoter.QuoteIdent(tableName))
+	result, err := adapters.ExecuteSelect(ctx, adapter, query)
+	if err != nil {
+		count.Error = err.Error()
+		return count
+	}
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		count.Error = "unexpected COUNT(*) result shape"
+		return count
+	}
+
+	n, err := countValue(result.Rows[0][0])
+	if err != nil {
+		count.Error = err.Error()
+		return count
+	}
+	count.Count = &n
+	return count
+}
+
+// countValue converts a COUNT(*) result value, which drivers return as an integer or text
+func countValue(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case int64:
+		return val, nil
+	case string:
+		return strconv.ParseInt(val, 10, 64)
+	default:
+		return 0, fmt.Errorf("unexpected count value of type %T", v)
+	}
+}
+
+// ResultDescription is the shape and size of a query result without its rows
+type ResultDescription struct {
+	Columns  []ResultColumn `json:"columns"`
+	RowCount int64          `json:"row_count"`
+}
+
+// ResultColumn is one column of a described result. Type is empty when the driver
+// does not report it.
+type ResultColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// describeResult reads the columns of query by running it with LIMIT 0 and counts
+// its rows with a wrapping COUNT(*), so no row data is fetched. Only SELECT and WITH
+// queries can be wrapped this way. The columns come from the parenthesized query
+// itself rather than a derived table, which MySQL rejects when two columns share a
+// name; the COUNT(*) renames them through a derived column list for the same reason.
+func describeResult(ctx context.Context, adapters *AdapterRegistry, adapter DatabaseAdapter, query string, policy ReadOnlyPolicy) (*ResultDescription, error) {
+	query, err := validateReadOnlyQuery(query, policy)
+	if err != nil {
+		return nil, err
+	}
+	statement := strings.ToLower(stripLeadingComments(query))
+	if !strings.HasPrefix(statement, "select") && !strings.HasPrefix(statement, "with") {
+		return nil, fmt.Errorf("describe_result supports SELECT and WITH queries only")
+	}
+
+	// Newlines keep a trailing line comment in query from swallowing the wrapper
+	shape, err := adapters.ExecuteSelect(ctx, adapter, fmt.Sprintf("(\n%s\n) LIMIT 0", query))
+	if err != nil {
+		return nil, err
+	}
+	aliases := make([]string, len(shape.Columns))
+	for i := range aliases {
+		aliases[i] = fmt.Sprintf("c%d", i+1)
+	}
+	result, err := adapters.ExecuteSelect(ctx, adapter, fmt.Sprintf("SELECT COUNT(*) FROM (\n%s\n) AS described (%s)", query, strings.Join(aliases, ", ")))
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		return nil, fmt.Errorf("unexpected COUNT(*) result shape")
+	}
+	count, err := countValue(result.Rows[0][0])
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &ResultDescription{Columns: make([]ResultColumn, len(shape.Columns)), RowCount: count}
+	for i, name := range shape.Columns {
+		desc.Columns[i].Name = name
+		if i < len(shape.Types) {
+			desc.Columns[i].Type = shape.Types[i]
+		}
+	}
+	return desc, nil
+}
+
+// registerServerInfoTools registers tools that report on the server itself and are
+// available with or without database adapters
+func registerServerInfoTools(registry *ToolRegistry, adapters *AdapterRegistry, cfg *Config) {
+	// storage_info tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "storage_info",
+			Description: "Report server uptime, version, Go version, MCP protocol version, configured database adapters, and enabled feature flags. Use it as a diagnostic snapshot of this server",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			names := adapters.List()
+			sort.Strings(names)
+
+			return jsonResult(map[string]interface{}{
+				"name":             ServerName,
+				"version":          ServerVersion,
+				"go_version":       runtime.Version(),
+				"protocol_version": ProtocolVersion,
+				"started_at":       startTime.UTC().Format(time.RFC3339),
+				"uptime_seconds":   int64(time.Since(startTime).Seconds()),
+				"adapters":         names,
+				"features": map[string]bool{
+					"sessions":                cfg.UseSession,
+					"strict_protocol_version": cfg.StrictProtocolVersion,
+					"signed_requests":         cfg.HMACSecret != "",
+					"oauth_mock":              cfg.OAuthMock,
+					"api_key":                 cfg.APIKey != "",
+					"readonly_routines":       cfg.AllowReadonlyRoutines,
+					"diagnostic_tools":        cfg.EnableDiagnosticTools,
+					"debug_stats":             cfg.EnableDebugStats,
+					"allow_writes":            cfg.AllowWrites,
+					"tracing":                 cfg.OTLPEndpoint != "",
+					"pretty_json":             cfg.PrettyJSON,
+				},
+			})
+		},
+	)
+}
+
+// registerAdapterInfoTools registers tools that report on every configured adapter
+func registerAdapterInfoTools(registry *ToolRegistry, adapters *AdapterRegistry) {
+	// transaction_settings tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "transaction_settings",
+			Description: "Report each database adapter's default transaction isolation level, whether transactions default to read-only, and the session time zone. Use it to reason about consistency guarantees and how timestamps are interpreted",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			names := adapters.List()
+			sort.Strings(names)
+
+			settings := make(map[string]interface{}, len(names))
+			for _, name := range names {
+				adapter, _ := adapters.Get(name)
+				reader, ok := adapter.(TransactionSettingsReader)
+				if !ok {
+					settings[name] = map[string]string{"error": "adapter does not report transaction settings"}
+					continue
+				}
+
+				t, err := reader.TransactionSettings(ctx)
+				if err != nil {
+					settings[name] = map[string]string{"error": err.Error()}
+					continue
+				}
+				settings[name] = t
+			}
+
+			return jsonResult(map[string]interface{}{"adapters": settings})
+		},
+	)
+}
+
+// resolveAdapter returns the named adapter. Without a name it falls back to the
+// session's default adapter, then to the only configured adapter.
+func resolveAdapter(ctx context.Context, adapters *AdapterRegistry, name string) (DatabaseAdapter, error) {
+	if name == "" {
+		if session := sessionFrom(ctx); session != nil {
+			name = session.DefaultAdapter()
+		}
+	}
+	if name == "" {
+		names := adapters.List()
+		if len(names) != 1 {
+			sort.Strings(names)
+			if sessionFrom(ctx) == nil {
+				return nil, fmt.Errorf("adapter is required (available: %v)", names)
+			}
+			return nil, fmt.Errorf("adapter is required (available: %v); set a session default with set_default_adapter", names)
+		}
+		name = names[0]
+	}
+
+	adapter, ok := adapters.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown adapter: %s (available: %v)", name, adapters.List())
+	}
+	return adapter, nil
+}
+
+// registerDefaultAdapterTool registers set_default_adapter, which stores a default
+// adapter in the session for the generic tools
+func registerDefaultAdapterTool(registry *ToolRegistry, adapters *AdapterRegistry) {
+	// set_default_adapter tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "set_default_adapter",
+			Description: "Set the database adapter that generic tools such as query_select use in this session when no adapter argument is given. Requires a session (Mcp-Session-Id); pass an empty adapter to clear the default",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"adapter": map[string]interface{}{
+						"type":        "string",
+						"description": "Adapter name, e.g. postgres or mysql",
+					},
+				},
+				Required: []string{"adapter"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Adapter string `json:"adapter"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			session := sessionFrom(ctx)
+			if session == nil {
+				return nil, fmt.Errorf("set_default_adapter requires a session: send the Mcp-Session-Id from initialize")
+			}
+			if params.Adapter != "" {
+				if _, ok := adapters.Get(params.Adapter); !ok {
+					return nil, fmt.Errorf("unknown adapter: %s (available: %v)", params.Adapter, adapters.List())
+				}
+			}
+			session.SetDefaultAdapter(params.Adapter)
+
+			return jsonResult(map[string]interface{}{"default_adapter": params.Adapter})
+		},
+	)
+}
+
+// registerGenericAdapterTools registers the tools that take an adapter argument,
+// defaulting to the session's default adapter
+func registerGenericAdapterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
+	// query_select tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "query_select",
+			Description: "Execute a read-only SELECT query on any configured database adapter. Without an adapter argument it uses the session's default adapter (see set_default_adapter), or the only adapter when just one is configured",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to execute, optionally with {{name:type}} placeholders filled from values",
+					},
+					"adapter": map[string]interface{}{
+						"type":        "string",
+						"description": "Adapter to query, e.g. postgres or mysql (default: the session's default adapter)",
+					},
+					"values": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": true,
+						"description":          "Values for {{name:type}} placeholders in the query. Each value is checked against its type and bound as a parameter",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query   string                 `json:"query"`
+				Adapter string                 `json:"adapter"`
+				Values  map[string]interface{} `json:"values"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+
+			adapter, err := resolveAdapter(ctx, adapters, params.Adapter)
+			if err != nil {
+				return nil, err
+			}
+
+			dialect := templatePostgres
+			if _, ok := adapter.(*MySQLAdapter); ok {
+				dialect = templateMySQL
+			}
+			query, args, err := bindQueryTemplate(params.Query, params.Values, dialect)
+			if err != nil {
+				return nil, err
+			}
+
+			result, err := adapters.ExecuteSelect(withQueryArgs(ctx, args), adapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			resultJSON, err := marshalQueryResult(result)
+			if err != nil {
+				return nil, err
+			}
+			return textResult(string(resultJSON)), nil
+		},
+	)
+
+	// schema_overview tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "schema_overview",
+			Description: "Summarize a schema in one call: its tables with estimated row counts, the foreign-key graph between them, and its views and routines. Use it to orient yourself in an unfamiliar database before reaching for the detailed tools. Sections are capped at max_items and listed in truncated when cut; a failing section is reported in errors",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema (PostgreSQL) or database (MySQL) to summarize",
+					},
+					"adapter": map[string]interface{}{
+						"type":        "string",
+						"description": "Adapter to inspect, e.g. postgres or mysql (default: the session's default adapter)",
+					},
+					"max_items": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum entries per section (default: %d, max: %d)", defaultOverviewItems, maxOverviewItems),
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				Adapter    string `json:"adapter"`
+				MaxItems   int    `json:"max_items"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			adapter, err := resolveAdapter(ctx, adapters, params.Adapter)
+			if err != nil {
+				return nil, err
+			}
+			overviewer, ok := adapter.(SchemaOverviewer)
+			if !ok {
+				return nil, fmt.Errorf("adapter %s does not support schema overviews", adapter.Name())
+			}
+
+			maxItems := clampLimit(params.MaxItems, defaultOverviewItems, maxOverviewItems)
+			return jsonResult(buildSchemaOverview(ctx, adapter.Name(), overviewer, params.SchemaName, maxItems))
+		},
+	)
+}
+
+// registerCrossAdapterTools registers tools that work across database adapters
+func registerCrossAdapterTools(registry *ToolRegistry, adapters *AdapterRegistry) {
+	// reconcile_counts tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "reconcile_counts",
+			Description: "Compare the row count of a table on two database adapters (e.g. to validate replication or a migration). Both counts run concurrently; a missing table is reported as an error for that side",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema (PostgreSQL) or database (MySQL) containing the table on both adapters",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"source": map[string]interface{}{
+						"type":        "string",
+						"description": "First adapter name, e.g. postgres",
+					},
+					"target": map[string]interface{}{
+						"type":        "string",
+						"description": "Second adapter name, e.g. mysql",
+					},
+				},
+				Required: []string{"schema_name", "table_name", "source", "target"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+				Source     string `json:"source"`
+				Target     string `json:"target"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" || params.Source == "" || params.Target == "" {
+				return nil, fmt.Errorf("schema_name, table_name, source, and target are required")
+			}
+
+			sourceAdapter, ok := adapters.Get(params.Source)
+			if !ok {
+				return nil, fmt.Errorf("unknown adapter: %s (available: %v)", params.Source, adapters.List())
+			}
+			targetAdapter, ok := adapters.Get(params.Target)
+			if !ok {
+				return nil, fmt.Errorf("unknown adapter: %s (available: %v)", params.Target, adapters.List())
+			}
+
+			var source, target AdapterCount
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				source = countRows(ctx, adapters, sourceAdapter, params.SchemaName, params.TableName)
+			}()
+			go func() {
+				defer wg.Done()
+				target = countRows(ctx, adapters, targetAdapter, params.SchemaName, params.TableName)
+			}()
+			wg.Wait()
+
+			result := map[string]interface{}{
+				"schema": params.SchemaName,
+				"table":  params.TableName,
+				"source": source,
+				"target": target,
+				"match":  source.Count != nil && target.Count != nil && *source.Count == *target.Count,
+			}
+			if source.Count != nil && target.Count != nil {
+				result["difference"] = *source.Count - *target.Count
+			}
+
+			return jsonResult(result)
+		},
+	)
+}
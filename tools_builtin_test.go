@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestSetDefaultAdapterNeedsSessions(t *testing.T) {
+	for _, useSession := range []bool{false, true} {
+		cfg := &Config{UseSession: useSession}
+		adapters := NewAdapterRegistry()
+		if err := adapters.Register(&recordingAdapter{name: "alpha"}); err != nil {
+			t.Fatal(err)
+		}
+		registry := NewToolRegistry(cfg)
+		RegisterTools(registry, adapters, cfg)
+
+		registered := false
+		for _, tool := range registry.ListTools() {
+			registered = registered || tool.Name == "set_default_adapter"
+		}
+		if registered != useSession {
+			t.Errorf("UseSession %v: set_default_adapter registered = %v", useSession, registered)
+		}
+	}
+}
+
+func TestSessionDefaultAdaptersAreIndependent(t *testing.T) {
+	cfg := &Config{UseSession: true}
+	alpha := &recordingAdapter{name: "alpha"}
+	beta := &recordingAdapter{name: "beta"}
+	adapters := NewAdapterRegistry()
+	for _, a := range []*recordingAdapter{alpha, beta} {
+		if err := adapters.Register(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry := NewToolRegistry(cfg)
+	RegisterTools(registry, adapters, cfg)
+
+	sessions := NewSessionManager(0, 0)
+	first := withSession(context.Background(), sessions.CreateSession())
+	second := withSession(context.Background(), sessions.CreateSession())
+	call := func(ctx context.Context, name, arguments string) error {
+		_, err := registry.CallTool(ctx, name, json.RawMessage(arguments))
+		return err
+	}
+
+	if err := call(first, "set_default_adapter", `{"adapter":"alpha"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(second, "set_default_adapter", `{"adapter":"beta"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(second, "set_default_adapter", `{"adapter":"gamma"}`); err == nil {
+		t.Error("set_default_adapter accepted an unknown adapter")
+	}
+
+	if err := call(first, "query_select", `{"query":"SELECT 'first'"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(second, "query_select", `{"query":"SELECT 'second'"}`); err != nil {
+		t.Fatal(err)
+	}
+	if alpha.query != "SELECT 'first'" || beta.query != "SELECT 'second'" {
+		t.Errorf("alpha ran %q, beta ran %q; want each session's query on its own default", alpha.query, beta.query)
+	}
+
+	// Clearing one session's default leaves the other's alone
+	if err := call(first, "set_default_adapter", `{"adapter":""}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(first, "query_select", `{"query":"SELECT 1"}`); err == nil {
+		t.Error("query_select without an adapter or default succeeded with two adapters")
+	}
+	if err := call(second, "query_select", `{"query":"SELECT 'again'"}`); err != nil || beta.query != "SELECT 'again'" {
+		t.Errorf("second session lost its default: %v, beta ran %q", err, beta.query)
+	}
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// registerMySQLIntrospectionTools registers MySQL catalog introspection tools
+func registerMySQLIntrospectionTools(registry *ToolRegistry, mysqlAdapter *MySQLAdapter) {
+	// mysql_list_databases tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_list_databases",
+			Description: "List the databases on the MySQL server, marking the connected one as current. In MySQL a database and a schema are the same thing, so any of them can be passed as schema_name to the other MySQL tools",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			databases, err := mysqlAdapter.ListDatabases(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"databases": databases})
+		},
+	)
+
+	// mysql_largest_tables tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_largest_tables",
+			Description: "List the largest tables in a MySQL schema by data plus index length, biggest first",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Number of tables to return (default: %d, max: %d)", defaultLargestTablesLimit, maxLargestTablesLimit),
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				Limit      int    `json:"limit"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			tables, err := mysqlAdapter.LargestTables(ctx, params.SchemaName, clampLimit(params.Limit, defaultLargestTablesLimit, maxLargestTablesLimit))
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"tables": tables})
+		},
+	)
+
+	// mysql_list_partitions tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_list_partitions",
+			Description: "List the partitions of a MySQL table with the partition key and each partition's bounds, to help write filters that prune partitions. Non-partitioned tables are reported as such",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the partitioned (parent) table",
+					},
+				},
+				Required: []string{"schema_name", "table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" {
+				return nil, fmt.Errorf("schema_name and table_name are required")
+			}
+
+			info, err := mysqlAdapter.ListPartitions(ctx, params.SchemaName, params.TableName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(info)
+		},
+	)
+
+	// mysql_collation_info tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_collation_info",
+			Description: "Show the default character set and collation of a MySQL schema, each table's collation, and the character set and collation of every text column",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Limit column details to this table (default: all tables in the schema)",
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			info, err := mysqlAdapter.CollationInfo(ctx, params.SchemaName, params.TableName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(info)
+		},
+	)
+
+	// mysql_list_auto_increments tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_list_auto_increments",
+			Description: "List the AUTO_INCREMENT counters of tables in a MySQL schema with the counter column and its type. Values may lag slightly on MySQL 8, which caches table statistics",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			autoIncrements, err := mysqlAdapter.ListAutoIncrements(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"auto_increments": autoIncrements})
+		},
+	)
+
+	// mysql_show_settings tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_show_settings",
+			Description: "Show MySQL system variables (SHOW VARIABLES) with their current session values, e.g. sql_mode or max_connections. Filter by name pattern to keep the output small",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "SQL LIKE pattern on the setting name, e.g. %mem% (default: all settings)",
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Pattern string `json:"pattern"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			settings, err := mysqlAdapter.ShowSettings(ctx, params.Pattern)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"settings": settings})
+		},
+	)
+
+	// mysql_routine_ddl tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_routine_ddl",
+			Description: "Get the full CREATE FUNCTION/PROCEDURE source of one MySQL routine",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"routine_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the function or procedure",
+					},
+					"routine_type": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"FUNCTION", "PROCEDURE"},
+						"description": "Only needed when a function and a procedure share the name",
+					},
+				},
+				Required: []string{"schema_name", "routine_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName  string `json:"schema_name"`
+				RoutineName string `json:"routine_name"`
+				RoutineType string `json:"routine_type"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.RoutineName == "" {
+				return nil, fmt.Errorf("schema_name and routine_name are required")
+			}
+
+			routine, err := mysqlAdapter.RoutineDDL(ctx, params.SchemaName, params.RoutineName, params.RoutineType)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(routine)
+		},
+	)
+}
+
+// registerMySQLQueryTools registers MySQL tools that read table data
+func registerMySQLQueryTools(registry *ToolRegistry, adapters *AdapterRegistry, mysqlAdapter *MySQLAdapter) {
+	// mysql_get_row tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_get_row",
+			Description: "Fetch a single row from a MySQL table by primary key. The key must name exactly the table's primary key columns",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"key": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": true,
+						"description":          "Primary key column -> value, e.g. {\"id\": 42}. Pass integers beyond 2^53 as strings",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Columns to return (default: all columns)",
+					},
+					"dry_run": dryRunProperty,
+				},
+				Required: []string{"schema_name", "table_name", "key"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string                 `json:"schema_name"`
+				TableName  string                 `json:"table_name"`
+				Key        map[string]interface{} `json:"key"`
+				Columns    []string               `json:"columns"`
+				DryRun     FlexBool               `json:"dry_run"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" || len(params.Key) == 0 {
+				return nil, fmt.Errorf("schema_name, table_name, and key are required")
+			}
+
+			query, args, err := mysqlAdapter.GetRowQuery(ctx, params.SchemaName, params.TableName, params.Key, params.Columns)
+			if err != nil {
+				return nil, err
+			}
+			if params.DryRun {
+				return dryRunResult(query, args), nil
+			}
+
+			result, err := adapters.ExecuteSelect(withQueryArgs(ctx, args), mysqlAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(result.Rows) == 0 {
+				return jsonResult(map[string]interface{}{"found": false})
+			}
+			return jsonResult(map[string]interface{}{
+				"found":   true,
+				"columns": result.Columns,
+				"row":     result.Rows[0],
+			})
+		},
+	)
+
+	// mysql_check_orphans tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_check_orphans",
+			Description: fmt.Sprintf("Check referential integrity of a MySQL table: count child rows whose foreign key points to a missing parent row and return a sample of up to %d of them. The foreign key is read from the table's constraints or given explicitly as column lists. Rows with a NULL key column are not checked", maxOrphanSampleLimit),
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the child table",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Child table holding the foreign key",
+					},
+					"constraint_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Foreign key constraint to check (default: the table's only foreign key)",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Child key columns, for checking a relationship without a declared constraint. Requires parent_table and parent_columns",
+					},
+					"parent_schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema of the parent table when columns is given (default: schema_name)",
+					},
+					"parent_table": map[string]interface{}{
+						"type":        "string",
+						"description": "Parent table when columns is given",
+					},
+					"parent_columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Referenced parent columns, in the same order as columns",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum orphan rows to sample (default: %d, max: %d)", defaultOrphanSampleLimit, maxOrphanSampleLimit),
+					},
+				},
+				Required: []string{"schema_name", "table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName     string   `json:"schema_name"`
+				TableName      string   `json:"table_name"`
+				ConstraintName string   `json:"constraint_name"`
+				Columns        []string `json:"columns"`
+				ParentSchema   string   `json:"parent_schema"`
+				ParentTable    string   `json:"parent_table"`
+				ParentColumns  []string `json:"parent_columns"`
+				Limit          int      `json:"limit"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" {
+				return nil, fmt.Errorf("schema_name and table_name are required")
+			}
+			params.Limit = clampLimit(params.Limit, defaultOrphanSampleLimit, maxOrphanSampleLimit)
+
+			var fk ForeignKey
+			if len(params.Columns) > 0 {
+				if params.ParentSchema == "" {
+					params.ParentSchema = params.SchemaName
+				}
+				fk = ForeignKey{
+					Columns:       params.Columns,
+					ParentSchema:  params.ParentSchema,
+					ParentTable:   params.ParentTable,
+					ParentColumns: params.ParentColumns,
+				}
+				if err := validateForeignKey(fk); err != nil {
+					return nil, err
+				}
+			} else {
+				fks, err := mysqlAdapter.ForeignKeys(ctx, params.SchemaName, params.TableName)
+				if err != nil {
+					return nil, err
+				}
+				if fk, err = pickForeignKey(fks, params.ConstraintName); err != nil {
+					return nil, err
+				}
+			}
+
+			report, err := checkOrphans(ctx, adapters, mysqlAdapter, params.SchemaName, params.TableName, fk, params.Limit)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(report)
+		},
+	)
+
+	// mysql_query_series tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "mysql_query_series",
+			Description: "Execute a SELECT query on MySQL and reshape it into a chart-ready series: labels from the x column and numeric values from the y column. Returns both the raw result and the series",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to execute",
+					},
+					"x": map[string]interface{}{
+						"type":        "string",
+						"description": "Result column used for labels",
+					},
+					"y": map[string]interface{}{
+						"type":        "string",
+						"description": "Numeric result column used for values",
+					},
+				},
+				Required: []string{"query", "x", "y"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query string `json:"query"`
+				X     string `json:"x"`
+				Y     string `json:"y"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" || params.X == "" || params.Y == "" {
+				return nil, fmt.Errorf("query, x, and y are required")
+			}
+
+			result, err := adapters.ExecuteSelect(ctx, mysqlAdapter, params.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			series, err := buildSeries(result, params.X, params.Y)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"result": result,
+				"series": series,
+			})
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateQueryArguments(arguments, mysqlAdapter.policy, nil)
+		}),
+	)
+}
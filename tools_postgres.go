@@ -0,0 +1,2010 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPatternLimit = 100
+	maxPatternLimit     = 10000
+
+	defaultLargestTablesLimit = 10
+	maxLargestTablesLimit     = 100
+
+	defaultFuzzySearchLimit = 20
+	maxFuzzySearchLimit     = 200
+
+	defaultPreviewLimit = 10
+	maxPreviewLimit     = 100
+
+	defaultFindValueLimit = 20
+	maxFindValueLimit     = 200
+	maxFindValueColumns   = 100
+
+	defaultExtractLimit = 100
+	maxExtractLimit     = 1000
+
+	defaultColumnStatsValues = 10
+	maxColumnStatsValues     = 100
+
+	maxMultiQueries       = 20
+	multiQueryConcurrency = 4
+	multiQueryTimeout     = 15 * time.Second
+)
+
+// NamedQueryResult is the outcome of one query in a multi-query batch
+type NamedQueryResult struct {
+	Result *QueryResult `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// runNamedQueries runs queries concurrently (bounded by multiQueryConcurrency), each with
+// its own timeout, so one failing query does not affect the others
+func runNamedQueries(ctx context.Context, adapters *AdapterRegistry, adapter DatabaseAdapter, queries map[string]string) map[string]NamedQueryResult {
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, multiQueryConcurrency)
+		results = make(map[string]NamedQueryResult, len(queries))
+	)
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name, query string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			queryCtx, cancel := context.WithTimeout(ctx, multiQueryTimeout)
+			defer cancel()
+
+			var named NamedQueryResult
+			if result, err := adapters.ExecuteSelect(queryCtx, adapter, query); err != nil {
+				named.Error = err.Error()
+			} else {
+				named.Result = &result
+			}
+
+			mu.Lock()
+			results[name] = named
+			mu.Unlock()
+		}(name, queries[name])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// validateNamedQueriesArguments pre-checks multi-query arguments: there must be at
+// most maxMultiQueries queries and each must pass read-only validation
+func validateNamedQueriesArguments(arguments json.RawMessage, policy ReadOnlyPolicy) error {
+	var params struct {
+		Queries map[string]string `json:"queries"`
+	}
+
+	if err := parseArguments(arguments, &params); err != nil {
+		return err
+	}
+
+	if len(params.Queries) > maxMultiQueries {
+		return fmt.Errorf("too many queries: %d (maximum %d)", len(params.Queries), maxMultiQueries)
+	}
+
+	names := make([]string, 0, len(params.Queries))
+	for name := range params.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := validateReadOnlyQuery(params.Queries[name], policy); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// registerPostgresIntrospectionTools registers PostgreSQL catalog introspection tools
+func registerPostgresIntrospectionTools(registry *ToolRegistry, postgresAdapter *PostgresAdapter) {
+	// postgres_list_databases tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_list_databases",
+			Description: "List the databases on the PostgreSQL server with owner, encoding, and size, marking the connected one as current. A PostgreSQL server holds several databases, each with its own schemas; every other tool only sees the current database, and querying another one requires a separate connection",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			databases, err := postgresAdapter.ListDatabases(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"databases": databases})
+		},
+	)
+
+	// postgres_list_foreign_tables tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_list_foreign_tables",
+			Description: "List foreign (external) tables and the foreign servers they read from. Queries against these tables are federated to another data source and may be slow",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only list foreign tables in this schema (optional)",
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			tables, err := postgresAdapter.ListForeignTables(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"foreign_tables": tables})
+		},
+	)
+
+	// postgres_list_matviews tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_list_matviews",
+			Description: "List materialized views with their definitions, sizes, and whether they are populated. An unpopulated view has never been refreshed and errors when queried; a populated one may still be stale, and PostgreSQL does not record when it was last refreshed",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only list materialized views in this schema (optional)",
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			views, err := postgresAdapter.ListMaterializedViews(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"materialized_views": views})
+		},
+	)
+
+	// postgres_replication_status tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_replication_status",
+			Description: "Report whether the connected PostgreSQL server is a standby replica, its replay lag in seconds, and the primary it replicates from. Use this to judge how stale query results may be",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			status, err := postgresAdapter.ReplicationStatus(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(status)
+		},
+	)
+
+	// postgres_check_constraints tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_check_constraints",
+			Description: "List CHECK constraints with their expressions for a PostgreSQL schema (optionally one table). These encode validation rules the data must satisfy",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only list constraints on this table (optional)",
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			constraints, err := postgresAdapter.ListCheckConstraints(ctx, params.SchemaName, params.TableName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"check_constraints": constraints})
+		},
+	)
+
+	// postgres_largest_tables tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_largest_tables",
+			Description: "List the largest tables in a PostgreSQL schema by total on-disk size (table, indexes, and TOAST), biggest first",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Number of tables to return (default: %d, max: %d)", defaultLargestTablesLimit, maxLargestTablesLimit),
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				Limit      int    `json:"limit"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			tables, err := postgresAdapter.LargestTables(ctx, params.SchemaName, clampLimit(params.Limit, defaultLargestTablesLimit, maxLargestTablesLimit))
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"tables": tables})
+		},
+	)
+
+	// postgres_list_partitions tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_list_partitions",
+			Description: "List the partitions of a PostgreSQL table with the partition key and each partition's bounds, to help write filters that prune partitions. Non-partitioned tables are reported as such",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the partitioned (parent) table",
+					},
+				},
+				Required: []string{"schema_name", "table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" {
+				return nil, fmt.Errorf("schema_name and table_name are required")
+			}
+
+			info, err := postgresAdapter.ListPartitions(ctx, params.SchemaName, params.TableName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(info)
+		},
+	)
+
+	// postgres_collation_info tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_collation_info",
+			Description: "Show the PostgreSQL database encoding and default collation/ctype, and the collation of every text column in a schema. Useful before comparing or sorting strings",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Limit column details to this table (default: all tables in the schema)",
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			info, err := postgresAdapter.CollationInfo(ctx, params.SchemaName, params.TableName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(info)
+		},
+	)
+
+	// postgres_list_sequences tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_list_sequences",
+			Description: "List sequences in a PostgreSQL schema with their last value, increment, maximum, percentage of the range used, and owning column",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			sequences, err := postgresAdapter.ListSequences(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"sequences": sequences})
+		},
+	)
+
+	// postgres_routine_ddl tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_routine_ddl",
+			Description: "Get the full CREATE FUNCTION/PROCEDURE source of one PostgreSQL routine. Overloaded functions need arguments to select one",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"routine_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the function or procedure",
+					},
+					"arguments": map[string]interface{}{
+						"type":        "string",
+						"description": "Input argument types to pick one overload, e.g. \"integer, text\", or \"\" for the overload without arguments. Omit when not overloaded",
+					},
+				},
+				Required: []string{"schema_name", "routine_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName  string  `json:"schema_name"`
+				RoutineName string  `json:"routine_name"`
+				Arguments   *string `json:"arguments"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.RoutineName == "" {
+				return nil, fmt.Errorf("schema_name and routine_name are required")
+			}
+
+			routine, err := postgresAdapter.RoutineDDL(ctx, params.SchemaName, params.RoutineName, params.Arguments)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(routine)
+		},
+	)
+
+	// postgres_extensions tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_extensions",
+			Description: "List PostgreSQL extensions installed in the current database (with version and schema) and those available on the server but not installed. Use it to check for features such as pg_trgm, postgis, or uuid-ossp before relying on their functions",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			installed, available, err := postgresAdapter.Extensions(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"installed": installed,
+				"available": available,
+			})
+		},
+	)
+
+	// postgres_show_settings tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_show_settings",
+			Description: "Show PostgreSQL configuration settings from pg_settings with current value, unit, category, description, context, source, and whether a session can change them with SET. Filter by name pattern to keep the output small",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "SQL LIKE pattern on the setting name, e.g. %mem% (default: all settings)",
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Pattern string `json:"pattern"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			settings, err := postgresAdapter.ShowSettings(ctx, params.Pattern)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"settings": settings})
+		},
+	)
+
+	// postgres_my_privileges tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_my_privileges",
+			Description: "Show what the current PostgreSQL role can access: schemas it has USAGE or CREATE on, and tables and views with the privileges it holds (SELECT, INSERT, ...), including those inherited from other roles or granted to PUBLIC. Check this before querying to avoid permission errors",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only report this schema (default: all non-system schemas)",
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			privileges, err := postgresAdapter.MyPrivileges(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(privileges)
+		},
+	)
+
+	// postgres_data_dictionary tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_data_dictionary",
+			Description: "Export a data dictionary of a PostgreSQL schema: one entry per column of every table, view, materialized view, and foreign table, with type, nullability, default, primary and foreign key flags, foreign key targets, and comment. Returns JSON or CSV",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"json", "csv"},
+						"description": "Output format (default: json)",
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				Format     string `json:"format"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+			if params.Format == "" {
+				params.Format = "json"
+			}
+			if params.Format != "json" && params.Format != "csv" {
+				return nil, fmt.Errorf("format must be json or csv")
+			}
+
+			entries, err := postgresAdapter.DataDictionary(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			if params.Format == "csv" {
+				text, err := dataDictionaryCSV(entries)
+				if err != nil {
+					return nil, fmt.Errorf("failed to render CSV: %w", err)
+				}
+				return textResult(text), nil
+			}
+			return jsonResult(map[string]interface{}{
+				"schema":  params.SchemaName,
+				"columns": entries,
+			})
+		},
+		WithTimeout(schemaDumpTimeout),
+	)
+
+	// postgres_table_freshness tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_table_freshness",
+			Description: "Report when a PostgreSQL table was last vacuumed and analyzed (manually and by autovacuum) and how many rows changed since the last analyze, optionally with the latest value of an updated_at-style column. Useful for deciding whether cached results are stale",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"updated_column": map[string]interface{}{
+						"type":        "string",
+						"description": "Timestamp column tracking row changes, e.g. updated_at; its MAX is returned. Reads the table unless the column is indexed",
+					},
+				},
+				Required: []string{"schema_name", "table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName    string `json:"schema_name"`
+				TableName     string `json:"table_name"`
+				UpdatedColumn string `json:"updated_column"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" {
+				return nil, fmt.Errorf("schema_name and table_name are required")
+			}
+
+			freshness, err := postgresAdapter.TableFreshness(ctx, params.SchemaName, params.TableName, params.UpdatedColumn)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(freshness)
+		},
+	)
+
+	// postgres_schema_drift tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_schema_drift",
+			Description: "Compare a live PostgreSQL schema with target DDL (e.g. the expected result of migrations) and report missing/extra tables, missing/extra/changed columns (type and NOT NULL), and missing/extra/changed indexes. Only CREATE TABLE, CREATE INDEX, and ALTER TABLE ... ADD CONSTRAINT statements are read; other statements are counted as ignored",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the live schema; unqualified names in the DDL refer to it",
+					},
+					"ddl": map[string]interface{}{
+						"type":        "string",
+						"description": "Target DDL script, statements separated by semicolons",
+					},
+				},
+				Required: []string{"schema_name", "ddl"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				DDL        string `json:"ddl"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || strings.TrimSpace(params.DDL) == "" {
+				return nil, fmt.Errorf("schema_name and ddl are required")
+			}
+
+			liveDDL, err := postgresAdapter.GetSchemaDDL(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			live := parseSchemaDDL(liveDDL, params.SchemaName, false)
+			target := parseSchemaDDL(params.DDL, params.SchemaName, true)
+			return jsonResult(diffSchemas(params.SchemaName, live, target))
+		},
+		WithTimeout(schemaDumpTimeout),
+	)
+
+	// postgres_column_stats tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_column_stats",
+			Description: "Profile a PostgreSQL table's columns from the planner statistics gathered by ANALYZE (pg_stats): null fraction, estimated distinct values, average width, physical-order correlation, and most common values with their frequencies. Reads the statistics catalog only, no table scan; statistics are as fresh as the last ANALYZE",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Most common values to return per column (default: %d, max: %d)", defaultColumnStatsValues, maxColumnStatsValues),
+					},
+				},
+				Required: []string{"schema_name", "table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+				Limit      int    `json:"limit"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" {
+				return nil, fmt.Errorf("schema_name and table_name are required")
+			}
+
+			stats, err := postgresAdapter.ColumnStats(ctx, params.SchemaName, params.TableName, clampLimit(params.Limit, defaultColumnStatsValues, maxColumnStatsValues))
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(stats)
+		},
+	)
+}
+
+// registerPostgresQueryTools registers PostgreSQL tools that build and execute queries
+func registerPostgresQueryTools(registry *ToolRegistry, adapters *AdapterRegistry, postgresAdapter *PostgresAdapter, cfg *Config) {
+	// postgres_query_pattern tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_query_pattern",
+			Description: "Query all tables whose names match a LIKE pattern (e.g. events_2024_%) as one UNION ALL result. Each row includes a source_table column",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the tables (default: public)",
+					},
+					"table_pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "SQL LIKE pattern matched against table names",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Columns to select from each table (default: all columns; tables must then share the same columns)",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum total rows to return (default: %d)", defaultPatternLimit),
+					},
+					"dry_run": dryRunProperty,
+				},
+				Required: []string{"table_pattern"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName   string   `json:"schema_name"`
+				TablePattern string   `json:"table_pattern"`
+				Columns      []string `json:"columns"`
+				Limit        int      `json:"limit"`
+				DryRun       FlexBool `json:"dry_run"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.TablePattern == "" {
+				return nil, fmt.Errorf("table_pattern is required")
+			}
+			if params.SchemaName == "" {
+				params.SchemaName = "public"
+			}
+			params.Limit = clampLimit(params.Limit, defaultPatternLimit, maxPatternLimit)
+
+			tables, err := postgresAdapter.ListTablesLike(ctx, params.SchemaName, params.TablePattern)
+			if err != nil {
+				return nil, err
+			}
+			if len(tables) == 0 {
+				return nil, fmt.Errorf("no tables in schema %s match pattern %s", params.SchemaName, params.TablePattern)
+			}
+			if len(tables) > cfg.PatternMaxTables {
+				return nil, fmt.Errorf("pattern %s matches %d tables, more than the maximum of %d", params.TablePattern, len(tables), cfg.PatternMaxTables)
+			}
+
+			query := buildUnionQuery(params.SchemaName, tables, params.Columns, params.Limit)
+			if params.DryRun {
+				return dryRunResult(query, nil), nil
+			}
+
+			result, err := adapters.ExecuteSelect(ctx, postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"tables": tables,
+				"result": result,
+			})
+		},
+	)
+
+	// postgres_multi_query tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_multi_query",
+			Description: fmt.Sprintf("Run several named SELECT queries in one call (up to %d, %d at a time) and return each result by name. A failing query reports its error without failing the others", maxMultiQueries, multiQueryConcurrency),
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"queries": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": map[string]interface{}{"type": "string"},
+						"description":          "Map of result name to SELECT query",
+					},
+				},
+				Required: []string{"queries"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Queries map[string]string `json:"queries"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if len(params.Queries) == 0 {
+				return nil, fmt.Errorf("queries is required")
+			}
+			if len(params.Queries) > maxMultiQueries {
+				return nil, fmt.Errorf("too many queries: %d (maximum %d)", len(params.Queries), maxMultiQueries)
+			}
+
+			return jsonResult(runNamedQueries(ctx, adapters, postgresAdapter, params.Queries))
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateNamedQueriesArguments(arguments, postgresAdapter.policy)
+		}),
+	)
+
+	// postgres_query_lint tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_query_lint",
+			Description: "Statically check a SELECT without running it and return warnings: missing LIMIT, ORDER BY without LIMIT or LIMIT without ORDER BY, SELECT * on a wide table, and joins that may produce a cartesian product. When the query has no LIMIT, a suggested_query with one added is returned",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to check",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query string `json:"query"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+
+			query, err := validateReadOnlyQuery(params.Query, postgresAdapter.policy)
+			if err != nil {
+				return nil, err
+			}
+
+			// Column counts are only looked up for tables read with SELECT *
+			lint := lintQuery(query, func(schemaName, tableName string) (int, bool) {
+				columns, err := postgresAdapter.TableColumns(ctx, schemaName, tableName)
+				if err != nil {
+					return 0, false
+				}
+				return len(columns), true
+			})
+
+			return jsonResult(lint)
+		},
+	)
+
+	// postgres_estimate_rows tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_estimate_rows",
+			Description: "Estimate how many rows a SELECT query would return using the planner's EXPLAIN estimate, without executing the query. Use it to decide whether to add a LIMIT",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to estimate",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query string `json:"query"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+
+			plan, err := postgresAdapter.Explain(ctx, params.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"estimated_rows": int64(plan.PlanRows),
+				"top_node":       plan.NodeType,
+				"total_cost":     plan.TotalCost,
+				"note":           "Approximate planner estimate based on table statistics; the actual row count may differ significantly, especially if statistics are stale",
+			})
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateQueryArguments(arguments, postgresAdapter.policy, nil)
+		}),
+	)
+
+	// postgres_plan_fingerprint tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_plan_fingerprint",
+			Description: "Fingerprint the execution plan of a SELECT query without executing it. The fingerprint is a hash of the plan's structure (node types, join types, relations, indexes) and ignores costs and row estimates, so storing it and comparing later detects plan changes such as a lost index scan",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to fingerprint",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query string `json:"query"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+
+			plan, err := postgresAdapter.Explain(ctx, params.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"fingerprint": planFingerprint(plan),
+				"shape":       planShape(plan),
+				"total_cost":  plan.TotalCost,
+			})
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateQueryArguments(arguments, postgresAdapter.policy, nil)
+		}),
+	)
+
+	// postgres_suggest_indexes tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_suggest_indexes",
+			Description: "Suggest CREATE INDEX statements for a SELECT query from its EXPLAIN plan, without executing the query or the DDL. Columns used in sequential scan filters and in join conditions on sequentially scanned tables are proposed, equality columns first, skipping those an existing index already leads with. Review the suggestions before running them",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to analyze",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query string `json:"query"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+
+			plan, suggestions, err := postgresAdapter.SuggestIndexes(ctx, params.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"suggestions": suggestions,
+				"shape":       planShape(plan),
+				"total_cost":  plan.TotalCost,
+				"note":        "Suggestions only; nothing was executed. The planner may scan small tables sequentially on purpose, so weigh estimated_rows before adding an index. CREATE INDEX CONCURRENTLY avoids blocking writes but cannot run inside a transaction",
+			})
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateQueryArguments(arguments, postgresAdapter.policy, nil)
+		}),
+	)
+
+	// postgres_estimate_time tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_estimate_time",
+			Description: "Roughly estimate how long a SELECT query will take, without executing it. Uses the mean time of earlier runs of the same normalized query from pg_stat_statements when available, otherwise reports the planner cost and a low/medium/high band. Use it to decide whether to run or refine a query; treat the result as an order of magnitude",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to estimate",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query string `json:"query"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+
+			estimate, err := postgresAdapter.EstimateExecutionTime(ctx, params.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(estimate)
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateQueryArguments(arguments, postgresAdapter.policy, nil)
+		}),
+	)
+
+	// postgres_fuzzy_search tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_fuzzy_search",
+			Description: "Fuzzy search a text column using pg_trgm trigram similarity and return matching rows ordered by similarity (highest first). Requires the pg_trgm extension",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the table (default: public)",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table to search",
+					},
+					"column_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Column to match against (cast to text)",
+					},
+					"term": map[string]interface{}{
+						"type":        "string",
+						"description": "Search term",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Columns to return besides similarity (default: all columns)",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum rows to return (default: %d, max: %d)", defaultFuzzySearchLimit, maxFuzzySearchLimit),
+					},
+					"dry_run": dryRunProperty,
+				},
+				Required: []string{"table_name", "column_name", "term"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string   `json:"schema_name"`
+				TableName  string   `json:"table_name"`
+				ColumnName string   `json:"column_name"`
+				Term       string   `json:"term"`
+				Columns    []string `json:"columns"`
+				Limit      int      `json:"limit"`
+				DryRun     FlexBool `json:"dry_run"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.TableName == "" || params.ColumnName == "" || params.Term == "" {
+				return nil, fmt.Errorf("table_name, column_name, and term are required")
+			}
+			if params.SchemaName == "" {
+				params.SchemaName = "public"
+			}
+			params.Limit = clampLimit(params.Limit, defaultFuzzySearchLimit, maxFuzzySearchLimit)
+
+			installed, err := postgresAdapter.HasExtension(ctx, "pg_trgm")
+			if err != nil {
+				return nil, err
+			}
+			if !installed {
+				return nil, fmt.Errorf("the pg_trgm extension is not installed in this database (CREATE EXTENSION pg_trgm)")
+			}
+
+			exists, err := postgresAdapter.ColumnExists(ctx, params.SchemaName, params.TableName, params.ColumnName)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, fmt.Errorf("column %s not found in %s.%s", params.ColumnName, params.SchemaName, params.TableName)
+			}
+
+			projection, err := postgresAdapter.selectList(ctx, params.SchemaName, params.TableName, params.Columns)
+			if err != nil {
+				return nil, err
+			}
+
+			query := buildFuzzySearchQuery(params.SchemaName, params.TableName, params.ColumnName, params.Term, projection, params.Limit)
+			if params.DryRun {
+				return dryRunResult(query, nil), nil
+			}
+
+			result, err := adapters.ExecuteSelect(ctx, postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(result)
+		},
+	)
+
+	// postgres_preview_table tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_preview_table",
+			Description: "Show the first rows of a PostgreSQL table as a markdown table for a human-readable preview. Column headers include each column's type and NULL values are shown as NULL",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the table (default: public)",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table to preview",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Number of rows to show (default: %d, max: %d)", defaultPreviewLimit, maxPreviewLimit),
+					},
+				},
+				Required: []string{"table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+				TableName  string `json:"table_name"`
+				Limit      int    `json:"limit"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.TableName == "" {
+				return nil, fmt.Errorf("table_name is required")
+			}
+			if params.SchemaName == "" {
+				params.SchemaName = "public"
+			}
+			params.Limit = clampLimit(params.Limit, defaultPreviewLimit, maxPreviewLimit)
+
+			query := fmt.Sprintf("SELECT * FROM %s.%s LIMIT %d",
+				quotePostgresIdent(params.SchemaName), quotePostgresIdent(params.TableName), params.Limit)
+			result, err := adapters.ExecuteSelect(ctx, postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			return textResult(fmt.Sprintf("%s.%s: %d rows (limit %d)\n\n%s",
+				params.SchemaName, params.TableName, len(result.Rows), params.Limit, markdownTable(result))), nil
+		},
+	)
+
+	// postgres_find_value tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_find_value",
+			Description: "Find rows of a PostgreSQL table where any column, cast to text, equals a value (e.g. \"where does this ID appear?\"). Every column is compared, so this scans the table; use it for exploration on moderately sized tables",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the table (default: public)",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table to search",
+					},
+					"value": map[string]interface{}{
+						"type":        "string",
+						"description": "Value to look for, compared exactly against each column's text form",
+					},
+					"search_columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": fmt.Sprintf("Columns to compare (default: all columns, at most %d)", maxFindValueColumns),
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum rows to return (default: %d, max: %d)", defaultFindValueLimit, maxFindValueLimit),
+					},
+					"dry_run": dryRunProperty,
+				},
+				Required: []string{"table_name", "value"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName    string   `json:"schema_name"`
+				TableName     string   `json:"table_name"`
+				Value         string   `json:"value"`
+				SearchColumns []string `json:"search_columns"`
+				Limit         int      `json:"limit"`
+				DryRun        FlexBool `json:"dry_run"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.TableName == "" || params.Value == "" {
+				return nil, fmt.Errorf("table_name and value are required")
+			}
+			if params.SchemaName == "" {
+				params.SchemaName = "public"
+			}
+			params.Limit = clampLimit(params.Limit, defaultFindValueLimit, maxFindValueLimit)
+
+			available, err := postgresAdapter.TableColumns(ctx, params.SchemaName, params.TableName)
+			if err != nil {
+				return nil, err
+			}
+			columns := available
+			if len(params.SearchColumns) > 0 {
+				known := make(map[string]bool, len(available))
+				for _, col := range available {
+					known[col] = true
+				}
+				for _, col := range params.SearchColumns {
+					if !known[col] {
+						return nil, fmt.Errorf("column %s not found in %s.%s", col, params.SchemaName, params.TableName)
+					}
+				}
+				columns = params.SearchColumns
+			}
+			if len(columns) > maxFindValueColumns {
+				return nil, fmt.Errorf("%s.%s has %d columns, more than the %d that can be searched at once; pass search_columns", params.SchemaName, params.TableName, len(columns), maxFindValueColumns)
+			}
+
+			query := buildFindValueQuery(params.SchemaName, params.TableName, columns, params.Limit)
+			args := []interface{}{params.Value}
+			if params.DryRun {
+				return dryRunResult(query, args), nil
+			}
+
+			result, err := adapters.ExecuteSelect(withQueryArgs(ctx, args), postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"searched_columns": columns,
+				"columns":          result.Columns,
+				"rows":             result.Rows,
+				"row_count":        len(result.Rows),
+			})
+		},
+	)
+
+	// postgres_get_row tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_get_row",
+			Description: "Fetch a single row from a PostgreSQL table by primary key. The key must name exactly the table's primary key columns",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"key": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": true,
+						"description":          "Primary key column -> value, e.g. {\"id\": 42}. Pass integers beyond 2^53 as strings",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Columns to return (default: all columns)",
+					},
+					"dry_run": dryRunProperty,
+				},
+				Required: []string{"schema_name", "table_name", "key"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string                 `json:"schema_name"`
+				TableName  string                 `json:"table_name"`
+				Key        map[string]interface{} `json:"key"`
+				Columns    []string               `json:"columns"`
+				DryRun     FlexBool               `json:"dry_run"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" || len(params.Key) == 0 {
+				return nil, fmt.Errorf("schema_name, table_name, and key are required")
+			}
+
+			query, args, err := postgresAdapter.GetRowQuery(ctx, params.SchemaName, params.TableName, params.Key, params.Columns)
+			if err != nil {
+				return nil, err
+			}
+			if params.DryRun {
+				return dryRunResult(query, args), nil
+			}
+
+			result, err := adapters.ExecuteSelect(withQueryArgs(ctx, args), postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(result.Rows) == 0 {
+				return jsonResult(map[string]interface{}{"found": false})
+			}
+			return jsonResult(map[string]interface{}{
+				"found":   true,
+				"columns": result.Columns,
+				"row":     result.Rows[0],
+			})
+		},
+	)
+
+	// postgres_exists tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_exists",
+			Description: "Check whether any row in a PostgreSQL table matches a filter, without fetching rows. The filter may use :name placeholders, which are bound as query parameters",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"where": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter condition without the WHERE keyword, e.g. email = :email AND active (default: any row)",
+					},
+					"params": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": true,
+						"description":          "Placeholder name -> value for the :name placeholders in where",
+					},
+					"dry_run": dryRunProperty,
+				},
+				Required: []string{"schema_name", "table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string                 `json:"schema_name"`
+				TableName  string                 `json:"table_name"`
+				Where      string                 `json:"where"`
+				Params     map[string]interface{} `json:"params"`
+				DryRun     FlexBool               `json:"dry_run"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" || params.TableName == "" {
+				return nil, fmt.Errorf("schema_name and table_name are required")
+			}
+
+			query, args, err := buildExistsQuery(params.SchemaName, params.TableName, params.Where, params.Params)
+			if err != nil {
+				return nil, err
+			}
+			if params.DryRun {
+				return dryRunResult(query, args), nil
+			}
+
+			result, err := adapters.ExecuteSelect(withQueryArgs(ctx, args), postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			exists := false
+			if len(result.Rows) == 1 && len(result.Rows[0]) == 1 {
+				exists, _ = result.Rows[0][0].(bool)
+			}
+			return jsonResult(map[string]interface{}{"exists": exists})
+		},
+	)
+
+	// postgres_check_orphans tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_check_orphans",
+			Description: fmt.Sprintf("Check referential integrity of a PostgreSQL table: count child rows whose foreign key points to a missing parent row and return a sample of up to %d of them. The foreign key is read from the table's constraints or given explicitly as column lists. Rows with a NULL key column are not checked", maxOrphanSampleLimit),
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the child table (default: public)",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Child table holding the foreign key",
+					},
+					"constraint_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Foreign key constraint to check (default: the table's only foreign key)",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Child key columns, for checking a relationship without a declared constraint. Requires parent_table and parent_columns",
+					},
+					"parent_schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema of the parent table when columns is given (default: schema_name)",
+					},
+					"parent_table": map[string]interface{}{
+						"type":        "string",
+						"description": "Parent table when columns is given",
+					},
+					"parent_columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Referenced parent columns, in the same order as columns",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum orphan rows to sample (default: %d, max: %d)", defaultOrphanSampleLimit, maxOrphanSampleLimit),
+					},
+				},
+				Required: []string{"table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName     string   `json:"schema_name"`
+				TableName      string   `json:"table_name"`
+				ConstraintName string   `json:"constraint_name"`
+				Columns        []string `json:"columns"`
+				ParentSchema   string   `json:"parent_schema"`
+				ParentTable    string   `json:"parent_table"`
+				ParentColumns  []string `json:"parent_columns"`
+				Limit          int      `json:"limit"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.TableName == "" {
+				return nil, fmt.Errorf("table_name is required")
+			}
+			if params.SchemaName == "" {
+				params.SchemaName = "public"
+			}
+			params.Limit = clampLimit(params.Limit, defaultOrphanSampleLimit, maxOrphanSampleLimit)
+
+			var fk ForeignKey
+			if len(params.Columns) > 0 {
+				if params.ParentSchema == "" {
+					params.ParentSchema = params.SchemaName
+				}
+				fk = ForeignKey{
+					Columns:       params.Columns,
+					ParentSchema:  params.ParentSchema,
+					ParentTable:   params.ParentTable,
+					ParentColumns: params.ParentColumns,
+				}
+				if err := validateForeignKey(fk); err != nil {
+					return nil, err
+				}
+			} else {
+				fks, err := postgresAdapter.ForeignKeys(ctx, params.SchemaName, params.TableName)
+				if err != nil {
+					return nil, err
+				}
+				if fk, err = pickForeignKey(fks, params.ConstraintName); err != nil {
+					return nil, err
+				}
+			}
+
+			report, err := checkOrphans(ctx, adapters, postgresAdapter, params.SchemaName, params.TableName, fk, params.Limit)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(report)
+		},
+	)
+
+	// postgres_extract_data tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_extract_data",
+			Description: fmt.Sprintf("Extract rows from a PostgreSQL table as replayable INSERT statements, e.g. for test fixtures. Reads at most %d rows", maxExtractLimit),
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema containing the table (default: public)",
+					},
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Columns to include in the INSERT statements (default: all columns)",
+					},
+					"where": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional WHERE condition without the WHERE keyword, e.g. created_at > now() - interval '1 day'",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum rows to extract (default: %d, max: %d)", defaultExtractLimit, maxExtractLimit),
+					},
+					"dry_run": dryRunProperty,
+				},
+				Required: []string{"table_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string   `json:"schema_name"`
+				TableName  string   `json:"table_name"`
+				Columns    []string `json:"columns"`
+				Where      string   `json:"where"`
+				Limit      int      `json:"limit"`
+				DryRun     FlexBool `json:"dry_run"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.TableName == "" {
+				return nil, fmt.Errorf("table_name is required")
+			}
+			if params.SchemaName == "" {
+				params.SchemaName = "public"
+			}
+			params.Limit = clampLimit(params.Limit, defaultExtractLimit, maxExtractLimit)
+
+			query, err := postgresAdapter.ExtractRowsQuery(ctx, params.SchemaName, params.TableName, params.Columns, params.Where, params.Limit)
+			if err != nil {
+				return nil, err
+			}
+			if params.DryRun {
+				return dryRunResult(query, nil), nil
+			}
+
+			result, err := adapters.ExecuteSelect(withResultOptions(ctx, extractResultOptions), postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			statements := buildInsertStatements(params.SchemaName, params.TableName, result)
+			if len(statements) == 0 {
+				return textResult("-- no rows matched"), nil
+			}
+			return textResult(strings.Join(statements, "\n")), nil
+		},
+	)
+
+	// postgres_query_series tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_query_series",
+			Description: "Execute a SELECT query on PostgreSQL and reshape it into a chart-ready series: labels from the x column and numeric values from the y column. Returns both the raw result and the series",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query to execute",
+					},
+					"x": map[string]interface{}{
+						"type":        "string",
+						"description": "Result column used for labels",
+					},
+					"y": map[string]interface{}{
+						"type":        "string",
+						"description": "Numeric result column used for values",
+					},
+				},
+				Required: []string{"query", "x", "y"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query string `json:"query"`
+				X     string `json:"x"`
+				Y     string `json:"y"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" || params.X == "" || params.Y == "" {
+				return nil, fmt.Errorf("query, x, and y are required")
+			}
+
+			result, err := adapters.ExecuteSelect(ctx, postgresAdapter, params.Query)
+			if err != nil {
+				return nil, err
+			}
+
+			series, err := buildSeries(result, params.X, params.Y)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{
+				"result": result,
+				"series": series,
+			})
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateQueryArguments(arguments, postgresAdapter.policy, nil)
+		}),
+	)
+
+	// postgres_query_named tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_query_named",
+			Description: "Execute a read-only SELECT query with :name placeholders bound from a params object. Values are sent as query parameters, never spliced into the SQL. Placeholders inside string literals, quoted identifiers, comments, and :: casts are ignored",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SELECT query using :name placeholders, e.g. SELECT * FROM users WHERE email = :email",
+					},
+					"params": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": true,
+						"description":          "Placeholder name -> value. Every placeholder needs a value and every value must be used",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Query  string                 `json:"query"`
+				Params map[string]interface{} `json:"params"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.Query == "" {
+				return nil, fmt.Errorf("query is required")
+			}
+
+			query, args, err := bindNamedParams(params.Query, params.Params)
+			if err != nil {
+				return nil, err
+			}
+
+			result, err := adapters.ExecuteSelect(withQueryArgs(ctx, args), postgresAdapter, query)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(result)
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			return validateQueryArguments(arguments, postgresAdapter.policy, nil)
+		}),
+	)
+}
+
+// registerPostgresDiagnosticTools registers PostgreSQL tools that read cluster-wide
+// statistics or other sessions' activity. Only registered when ENABLE_DIAGNOSTIC_TOOLS is set.
+func registerPostgresDiagnosticTools(registry *ToolRegistry, postgresAdapter *PostgresAdapter) {
+	// postgres_index_advisor tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_index_advisor",
+			Description: "Suggest index work from usage statistics: tables dominated by sequential scans (missing index candidates) and non-unique indexes never scanned (unused). Statistics accumulate since the last stats reset",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only report on this schema (optional)",
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			advice, err := postgresAdapter.IndexAdvice(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(advice)
+		},
+	)
+
+	// postgres_index_usage tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_index_usage",
+			Description: "Report per-index scan counts, tuples read/fetched, and size for a schema, least scanned first. Non-unique indexes with zero scans are flagged as removal candidates. Statistics accumulate since the last stats reset",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the schema",
+					},
+				},
+				Required: []string{"schema_name"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName string `json:"schema_name"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			if params.SchemaName == "" {
+				return nil, fmt.Errorf("schema_name is required")
+			}
+
+			usage, err := postgresAdapter.IndexUsage(ctx, params.SchemaName)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(map[string]interface{}{"indexes": usage})
+		},
+	)
+
+	// postgres_bloat_report tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_bloat_report",
+			Description: fmt.Sprintf("Estimate wasted space in PostgreSQL tables and btree indexes to decide on VACUUM FULL, pg_repack, or REINDEX. Reports relations with at least min_bloat_percent and 1 MB of estimated bloat, largest first (up to %d of each). Estimates are approximate: they are computed from planner statistics, need a recent ANALYZE, and are flagged estimate_unreliable when columns lack statistics", bloatReportLimit),
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only report on this schema (optional)",
+					},
+					"min_bloat_percent": map[string]interface{}{
+						"type":        "number",
+						"description": fmt.Sprintf("Minimum estimated bloat as a percentage of the relation's size (default: %d)", defaultBloatMinPercent),
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				SchemaName      string   `json:"schema_name"`
+				MinBloatPercent *float64 `json:"min_bloat_percent"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			minPercent := float64(defaultBloatMinPercent)
+			if params.MinBloatPercent != nil {
+				if *params.MinBloatPercent < 0 || *params.MinBloatPercent > 100 {
+					return nil, fmt.Errorf("min_bloat_percent must be between 0 and 100")
+				}
+				minPercent = *params.MinBloatPercent
+			}
+
+			report, err := postgresAdapter.BloatReport(ctx, params.SchemaName, minPercent)
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(report)
+		},
+		WithTimeout(schemaDumpTimeout),
+	)
+
+	// postgres_locks tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_locks",
+			Description: "Show current PostgreSQL locks: each session holding or waiting for a lock with its query, lock types, modes, and relations, plus the blocking graph (which pid waits on which). Use it to diagnose blocked updates",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"blocked_only": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Only report sessions that are waiting or blocking another session (default: false)",
+					},
+				},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				BlockedOnly FlexBool `json:"blocked_only"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			report, err := postgresAdapter.LockReport(ctx, bool(params.BlockedOnly))
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(report)
+		},
+	)
+}
+
+// registerPostgresWriteTools registers PostgreSQL tools that modify the database.
+// Only registered when ALLOW_WRITES is set.
+func registerPostgresWriteTools(registry *ToolRegistry, adapters *AdapterRegistry, postgresAdapter *PostgresAdapter) {
+	// postgres_run_migration tool
+	registry.RegisterTool(
+		Tool{
+			Name:        "postgres_run_migration",
+			Description: "Run a PostgreSQL migration script (DDL and DML statements separated by ;) in a single transaction. If any statement fails, the whole script is rolled back. Returns each statement's status, rows affected, and error. Dollar-quoted function bodies may contain ;. The script must not contain BEGIN/COMMIT/ROLLBACK",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"script": map[string]interface{}{
+						"type":        "string",
+						"description": "SQL script with one or more statements separated by ;",
+					},
+				},
+				Required: []string{"script"},
+			},
+		},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			var params struct {
+				Script string `json:"script"`
+			}
+
+			if err := parseArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			statements := splitPostgresStatements(params.Script)
+			if err := validateMigrationStatements(statements); err != nil {
+				return nil, err
+			}
+
+			result, err := postgresAdapter.RunMigration(ctx, statements, func(ctx context.Context, statement string, err error) {
+				adapters.ObserveQuery(ctx, postgresAdapter, statement, QueryResult{}, err)
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return jsonResult(result)
+		},
+		WithValidator(func(arguments json.RawMessage) error {
+			var params struct {
+				Script string `json:"script"`
+			}
+			if err := parseArguments(arguments, &params); err != nil {
+				return err
+			}
+			return validateMigrationStatements(splitPostgresStatements(params.Script))
+		}),
+	)
+}
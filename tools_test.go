@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLimitOutput(t *testing.T) {
+	text := func(s string) Content { return TextContent{Type: "text", Text: s} }
+	image := ImageContent{Type: "image", Data: "iVBORw0KGgo=", MimeType: "image/png"}
+	notice := func(maxChars, total int) Content {
+		return text(fmt.Sprintf("[output truncated: showing %d of %d characters (MAX_OUTPUT_CHARS=%d); narrow the query or lower its limit to see the rest]", maxChars, total, maxChars))
+	}
+
+	tests := []struct {
+		name        string
+		content     []Content
+		maxChars    int
+		want        []Content
+		wantDropped int
+	}{
+		{
+			name:     "disabled",
+			content:  []Content{text("0123456789")},
+			maxChars: 0,
+			want:     []Content{text("0123456789")},
+		},
+		{
+			name:     "exactly at the limit",
+			content:  []Content{text("01234"), text("56789")},
+			maxChars: 10,
+			want:     []Content{text("01234"), text("56789")},
+		},
+		{
+			name:        "single block cut",
+			content:     []Content{text("0123456789")},
+			maxChars:    4,
+			want:        []Content{text("0123"), notice(4, 10)},
+			wantDropped: 6,
+		},
+		{
+			name:        "budget spans blocks and later blocks are dropped",
+			content:     []Content{text("abc"), text("defgh"), text("ijk")},
+			maxChars:    5,
+			want:        []Content{text("abc"), text("de"), notice(5, 11)},
+			wantDropped: 6,
+		},
+		{
+			name:        "budget ends on a block boundary",
+			content:     []Content{text("abc"), text("def")},
+			maxChars:    3,
+			want:        []Content{text("abc"), notice(3, 6)},
+			wantDropped: 3,
+		},
+		{
+			name:        "non-text content is kept and not counted",
+			content:     []Content{text("abcdef"), image, text("ghi")},
+			maxChars:    4,
+			want:        []Content{text("abcd"), image, notice(4, 9)},
+			wantDropped: 5,
+		},
+		{
+			name:        "multibyte characters are counted and cut whole",
+			content:     []Content{text("héllo wörld")},
+			maxChars:    7,
+			want:        []Content{text("héllo w"), notice(7, 11)},
+			wantDropped: 4,
+		},
+		{
+			name:        "emoji",
+			content:     []Content{text("🙂🙃🙂🙃")},
+			maxChars:    1,
+			want:        []Content{text("🙂"), notice(1, 4)},
+			wantDropped: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &CallToolResult{Content: tt.content}
+			dropped := limitOutput(result, tt.maxChars)
+			if dropped != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+			if !reflect.DeepEqual(result.Content, tt.want) {
+				t.Errorf("content = %#v, want %#v", result.Content, tt.want)
+			}
+		})
+	}
+
+	if got := limitOutput(nil, 10); got != 0 {
+		t.Errorf("limitOutput(nil) = %d, want 0", got)
+	}
+}
+
+func TestCallToolAppliesMaxOutputChars(t *testing.T) {
+	registry := NewToolRegistry(&Config{MaxOutputChars: 5})
+	registry.RegisterTool(Tool{Name: "chatty", InputSchema: InputSchema{Type: "object"}},
+		func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			return &CallToolResult{Content: []Content{
+				TextContent{Type: "text", Text: "abc"},
+				TextContent{Type: "text", Text: "defgh"},
+			}}, nil
+		})
+
+	result, err := registry.CallTool(context.Background(), "chatty", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 3 || result.Content[1].(TextContent).Text != "de" ||
+		!strings.HasPrefix(result.Content[2].(TextContent).Text, "[output truncated: showing 5 of 8 characters") {
+		t.Errorf("content = %#v, want 5 characters and the truncation notice", result.Content)
+	}
+}
+
+func TestRegisterToolMaxTools(t *testing.T) {
+	handler := func(text string) ToolHandler {
+		return func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error) {
+			return &CallToolResult{Content: []Content{TextContent{Type: "text", Text: text}}}, nil
+		}
+	}
+	tool := func(name string) Tool {
+		return Tool{Name: name, Description: name, InputSchema: InputSchema{Type: "object"}}
+	}
+	toolNames := func(r *ToolRegistry) []string {
+		var names []string
+		for _, tool := range r.ListTools() {
+			names = append(names, tool.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	registry := NewToolRegistry(&Config{MaxTools: 2})
+	registry.RegisterTool(tool("a"), handler("a"))
+	registry.RegisterTool(tool("b"), handler("b"))
+	registry.RegisterTool(tool("c"), handler("c"))
+	registry.RegisterTool(tool("d"), handler("d"))
+
+	if got := registry.SkippedTools(); got != 2 {
+		t.Errorf("SkippedTools() = %d, want 2", got)
+	}
+	if got := toolNames(registry); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("registered tools = %v, want [a b]", got)
+	}
+	if _, err := registry.CallTool(context.Background(), "c", nil); err == nil {
+		t.Error("calling a tool refused by the cap succeeded")
+	}
+
+	// Replacing a registered tool at the cap is not refused
+	registry.RegisterTool(tool("b"), handler("b2"))
+	if got := registry.SkippedTools(); got != 2 {
+		t.Errorf("SkippedTools() after replacing = %d, want 2", got)
+	}
+	result, err := registry.CallTool(context.Background(), "b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].(TextContent).Text; text != "b2" {
+		t.Errorf("replaced tool returned %q, want b2", text)
+	}
+
+	unlimited := NewToolRegistry(&Config{})
+	for _, name := range []string{"a", "b", "c"} {
+		unlimited.RegisterTool(tool(name), handler(name))
+	}
+	if got := len(unlimited.ListTools()); got != 3 || unlimited.SkippedTools() != 0 {
+		t.Errorf("MAX_TOOLS=0 registered %d tools, skipped %d; want 3, 0", got, unlimited.SkippedTools())
+	}
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MCPTransport handles HTTP transport for MCP protocol
@@ -16,20 +25,41 @@ type MCPTransport struct {
 	handler        *JSONRPCHandler
 	sessionManager *SessionManager
 	useSession     bool
+	cfg            *Config
+	queryMetrics   *QueryMetrics
+	adapters       *AdapterRegistry
+	healthHeaders  [][2]string
 }
 
+// healthCheckTimeout bounds each adapter ping made by the health endpoint
+const healthCheckTimeout = 2 * time.Second
+
 // NewMCPTransport creates a new MCP transport
-func NewMCPTransport(handler *JSONRPCHandler, useSession bool) *MCPTransport {
+func NewMCPTransport(handler *JSONRPCHandler, cfg *Config, queryMetrics *QueryMetrics, adapters *AdapterRegistry) *MCPTransport {
 	var sm *SessionManager
-	if useSession {
-		// 30 minute session timeout
-		sm = NewSessionManager(30 * time.Minute)
+	if cfg.UseSession {
+		// 30 minute idle session timeout
+		sm = NewSessionManager(30*time.Minute, cfg.SessionMaxLifetime)
+	}
+
+	var healthHeaders [][2]string
+	for _, h := range cfg.HealthHeaders {
+		name, value, ok := strings.Cut(h, ":")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			log.Warn().Str("header", h).Msg("Ignoring HEALTH_HEADERS entry, expected Name: value")
+			continue
+		}
+		healthHeaders = append(healthHeaders, [2]string{name, strings.TrimSpace(value)})
 	}
 
 	return &MCPTransport{
 		handler:        handler,
 		sessionManager: sm,
-		useSession:     useSession,
+		useSession:     cfg.UseSession,
+		cfg:            cfg,
+		queryMetrics:   queryMetrics,
+		adapters:       adapters,
+		healthHeaders:  healthHeaders,
 	}
 }
 
@@ -38,29 +68,231 @@ func (t *MCPTransport) SetupRoutes(app *fiber.App) {
 	// Health check endpoint
 	app.Get("/health", t.handleHealth)
 
-	// Main MCP endpoint - handles all MCP protocol messages
-	app.Post("/", t.handleMCPRequest)
+	// Runtime stats for self-monitoring, disabled by default
+	if t.cfg.EnableDebugStats {
+		app.Get("/debug/stats", t.handleDebugStats)
+	}
+
+	// Discovery descriptor for clients and humans probing the base URL
+	app.Get("/", t.handleDiscovery)
 
-	// OAuth mock endpoints for Claude Code compatibility
-	t.setupOAuthMockEndpoints(app)
+	// Log notifications queued for a session; there is no stream to push them on
+	if t.useSession {
+		app.Get("/notifications", t.handleNotifications)
+	}
+
+	// Main MCP endpoint - handles all MCP protocol messages, optionally
+	// requiring an HMAC signature of the request body
+	if t.cfg.HMACSecret != "" {
+		app.Post("/", NewSignatureVerifier(t.cfg.HMACSecret), t.handleMCPRequest)
+	} else {
+		app.Post("/", t.handleMCPRequest)
+	}
+
+	// OAuth mock endpoints for Claude Code compatibility; without them the
+	// routes fall through to the JSON 404 handler
+	if t.cfg.OAuthMock {
+		t.setupOAuthMockEndpoints(app)
+	}
+}
+
+// ErrorHandler renders errors returned by routes, including Fiber's own 404 and 405
+// responses, as JSON. A 405 carries an Allow header listing the methods registered
+// for the path.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	message := "Internal Server Error"
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code = fiberErr.Code
+		message = fiberErr.Message
+	} else {
+		log.Error().Err(err).Str("scope", "ErrorHandler").Str("path", c.Path()).Msg("Unhandled request error")
+	}
+
+	if code == fiber.StatusMethodNotAllowed {
+		c.Set(fiber.HeaderAllow, strings.Join(allowedMethods(c.App(), c.Path()), ", "))
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// allowedMethods returns the methods of the routes registered for path, in Fiber's
+// method order. Paths are compared without a trailing slash and ignoring case, as
+// Fiber routes them by default; every route of this server has a static path.
+func allowedMethods(app *fiber.App, path string) []string {
+	path = strings.TrimSuffix(path, "/")
+	registered := make(map[string]bool)
+	for _, route := range app.GetRoutes(true) {
+		if strings.EqualFold(strings.TrimSuffix(route.Path, "/"), path) {
+			registered[route.Method] = true
+		}
+	}
+
+	var methods []string
+	for _, method := range app.Config().RequestMethods {
+		if registered[method] {
+			methods = append(methods, method)
+		}
+	}
+	return methods
 }
 
 // handleHealth handles health check requests
 func (t *MCPTransport) handleHealth(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
+	for _, h := range t.healthHeaders {
+		c.Set(h[0], h[1])
+	}
+
+	body := fiber.Map{
 		"status":  "healthy",
 		"time":    time.Now().UTC().Format(time.RFC3339),
 		"version": ProtocolVersion,
+	}
+	status := t.cfg.HealthStatusHealthy
+
+	if t.cfg.HealthCheckDatabases {
+		checks, healthy := t.pingAdapters(c.Context())
+		body["adapters"] = checks
+		if !healthy {
+			body["status"] = "unhealthy"
+			status = t.cfg.HealthStatusUnhealthy
+		}
+	}
+
+	// Fiber routes HEAD here too; fasthttp drops the body and keeps status and headers
+	return c.Status(status).JSON(body)
+}
+
+// pingAdapters pings every adapter and reports "ok" or the error for each, and
+// whether all of them answered
+func (t *MCPTransport) pingAdapters(ctx context.Context) (map[string]string, bool) {
+	checks := make(map[string]string)
+	healthy := true
+	for _, name := range t.adapters.List() {
+		adapter, _ := t.adapters.Get(name)
+		pinger, ok := adapter.(Pinger)
+		if !ok {
+			continue
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := pinger.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			checks[name] = err.Error()
+			healthy = false
+			continue
+		}
+		checks[name] = "ok"
+	}
+	return checks, healthy
+}
+
+// handleDiscovery describes the server and where to send MCP requests
+func (t *MCPTransport) handleDiscovery(c *fiber.Ctx) error {
+	descriptor := fiber.Map{
+		"name":             ServerName,
+		"version":          ServerVersion,
+		"protocol_version": ProtocolVersion,
+		"transports":       []string{"http"},
+		"mcp_endpoint":     "/",
+		"mcp_method":       fiber.MethodPost,
+		"health_endpoint":  "/health",
+		"sessions":         t.useSession,
+		"signed_requests":  t.cfg.HMACSecret != "",
+		"oauth_mock":       t.cfg.OAuthMock,
+	}
+	if t.useSession {
+		descriptor["notifications_endpoint"] = "/notifications"
+	}
+	return c.JSON(descriptor)
+}
+
+// handleNotifications returns and clears the notifications/message notifications
+// queued for the session named by Mcp-Session-Id, as a JSON array
+func (t *MCPTransport) handleNotifications(c *fiber.Ctx) error {
+	session, exists := t.sessionManager.GetSession(c.Get("Mcp-Session-Id"))
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Unknown session",
+		})
+	}
+	if !t.sessionClientAllowed(session) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "A session initialized by an allowed client is required",
+		})
+	}
+	return c.JSON(session.TakeNotifications())
+}
+
+// handleDebugStats reports goroutine, memory, and GC statistics, and per-adapter query counters
+func (t *MCPTransport) handleDebugStats(c *fiber.Ctx) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var lastPause time.Duration
+	if mem.NumGC > 0 {
+		lastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+
+	return c.JSON(fiber.Map{
+		"uptime_seconds":    int64(time.Since(startTime).Seconds()),
+		"goroutines":        runtime.NumGoroutine(),
+		"heap_alloc_bytes":  mem.HeapAlloc,
+		"heap_sys_bytes":    mem.HeapSys,
+		"heap_objects":      mem.HeapObjects,
+		"gc_count":          mem.NumGC,
+		"gc_last_pause_ms":  float64(lastPause) / float64(time.Millisecond),
+		"gc_total_pause_ms": float64(mem.PauseTotalNs) / float64(time.Millisecond),
+		"gc_cpu_fraction":   mem.GCCPUFraction,
+		"queries":           t.queryMetrics.Snapshot(),
 	})
 }
 
 // handleMCPRequest handles MCP protocol requests
 func (t *MCPTransport) handleMCPRequest(c *fiber.Ctx) error {
-	l := log.With().Str("scope", "handleMCPRequest").Logger()
+	// Correlate logs and the response with the caller's request ID, or a new one
+	requestID := c.Get(RequestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+	c.Set(RequestIDHeader, requestID)
+
+	// End the request context when the client disconnects, so a batch or a
+	// long query stops instead of running for nobody
+	ctx, cancel := watchDisconnect(extractTraceContext(c), c.Context().Conn())
+	defer cancel()
+
+	// Trace the request, continuing the client's trace if it sent one
+	ctx, span := tracer.Start(ctx, "mcp.request", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	ctx = withRequestID(ctx, requestID)
+
+	l := loggerFrom(ctx).With().Str("scope", "handleMCPRequest").Logger()
 
 	// Set content type
 	c.Set("Content-Type", "application/json")
 
+	// Decode compressed request bodies
+	requestBody, err := decodeRequestBody(c, t.cfg.MaxDecompressedBodyBytes)
+	if err != nil {
+		l.Warn().Err(err).Str("content_encoding", c.Get(fiber.HeaderContentEncoding)).Msg("Failed to decode request body")
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, errUnsupportedEncoding):
+			status = fiber.StatusUnsupportedMediaType
+		case errors.Is(err, errDecodedBodyTooLarge):
+			status = fiber.StatusRequestEntityTooLarge
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Log request in debug mode
 	if debugMode {
 		// Collect all headers
@@ -68,20 +300,20 @@ func (t *MCPTransport) handleMCPRequest(c *fiber.Ctx) error {
 		c.Request().Header.VisitAll(func(key, value []byte) {
 			headers[string(key)] = string(value)
 		})
-		
+
 		// Pretty print body if JSON
 		var prettyBody string
 		var jsonData interface{}
-		if err := json.Unmarshal(c.Body(), &jsonData); err == nil {
+		if err := json.Unmarshal(requestBody, &jsonData); err == nil {
 			if prettyBytes, err := json.MarshalIndent(jsonData, "", "  "); err == nil {
 				prettyBody = string(prettyBytes)
 			} else {
-				prettyBody = string(c.Body())
+				prettyBody = string(requestBody)
 			}
 		} else {
-			prettyBody = string(c.Body())
+			prettyBody = string(requestBody)
 		}
-		
+
 		l.Debug().
 			Str("method", c.Method()).
 			Str("path", c.Path()).
@@ -104,14 +336,22 @@ func (t *MCPTransport) handleMCPRequest(c *fiber.Ctx) error {
 		}
 	}
 
-	// Process the request
-	requestBody := c.Body()
+	ctx = withSession(ctx, session)
 
 	// Parse request to check if it's an initialize request
 	var req JSONRPCRequest
 	if err := json.Unmarshal(requestBody, &req); err == nil && req.Method == "initialize" {
 		// Handle initialize specially to create/return session
-		return t.handleInitialize(c, &req, session)
+		return t.handleInitialize(ctx, c, requestBody, &req, session)
+	}
+
+	// With an allowlist, every other method needs a session that an allowed client
+	// initialized; otherwise skipping initialize would bypass the check
+	if !t.sessionClientAllowed(session) {
+		l.Warn().Str("method", req.Method).Msg("Rejected request without a session from an allowed client")
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "A session initialized by an allowed client is required",
+		})
 	}
 
 	// For other requests, check if session is required and initialized
@@ -122,7 +362,7 @@ func (t *MCPTransport) handleMCPRequest(c *fiber.Ctx) error {
 	}
 
 	// Process request through JSON-RPC handler
-	response := t.handler.HandleRequest(requestBody)
+	response := t.handler.HandleRequest(ctx, requestBody)
 
 	// If no response (notification), return 204 No Content
 	if response == nil {
@@ -143,21 +383,93 @@ func (t *MCPTransport) handleMCPRequest(c *fiber.Ctx) error {
 		} else {
 			prettyResponse = string(response)
 		}
-		
+
 		l.Debug().
 			Str("response", prettyResponse).
 			Msg("=== OUTGOING HTTP RESPONSE ===")
 	}
 
+	return t.sendResponse(c, response)
+}
+
+// sessionClientAllowed reports whether a request on session passes ALLOWED_CLIENTS.
+// With an allowlist set, the session must exist and have been initialized by one of
+// the allowed clients.
+func (t *MCPTransport) sessionClientAllowed(session *Session) bool {
+	if len(t.cfg.AllowedClients) == 0 {
+		return true
+	}
+	if session == nil || !session.IsInitialized() {
+		return false
+	}
+	return clientAllowed(t.cfg.AllowedClients, session.ClientName())
+}
+
+// sendResponse writes a JSON-RPC response body, indented when PRETTY_JSON is set
+func (t *MCPTransport) sendResponse(c *fiber.Ctx, response []byte) error {
+	if t.cfg.PrettyJSON {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, response, "", "  "); err == nil {
+			response = buf.Bytes()
+		}
+	}
 	return c.Send(response)
 }
 
+// errDecodedBodyTooLarge is returned when a compressed request body expands past
+// MAX_DECOMPRESSED_BODY_BYTES
+var errDecodedBodyTooLarge = errors.New("decompressed request body too large")
+
+// errUnsupportedEncoding is returned for a Content-Encoding other than gzip or deflate
+var errUnsupportedEncoding = errors.New("unsupported Content-Encoding")
+
+// decodeRequestBody returns the request body, decompressing it according to Content-Encoding.
+// Decompressed bodies larger than limit bytes are rejected (limit <= 0 disables the check).
+func decodeRequestBody(c *fiber.Ctx, limit int) ([]byte, error) {
+	body := c.Request().Body()
+
+	var zr io.ReadCloser
+	switch encoding := strings.ToLower(strings.TrimSpace(c.Get(fiber.HeaderContentEncoding))); encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip request body: %w", err)
+		}
+		zr = r
+	case "deflate":
+		// HTTP deflate is zlib-wrapped, but some clients send raw DEFLATE data
+		if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			zr = r
+		} else {
+			zr = flate.NewReader(bytes.NewReader(body))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s (supported: gzip, deflate)", errUnsupportedEncoding, encoding)
+	}
+	defer zr.Close()
+
+	var r io.Reader = zr
+	if limit > 0 {
+		r = io.LimitReader(zr, int64(limit)+1)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed request body: %w", err)
+	}
+	if limit > 0 && len(decoded) > limit {
+		return nil, fmt.Errorf("%w (limit %d bytes)", errDecodedBodyTooLarge, limit)
+	}
+	return decoded, nil
+}
+
 // handleInitialize handles the initialize request specially
-func (t *MCPTransport) handleInitialize(c *fiber.Ctx, req *JSONRPCRequest, session *Session) error {
-	l := log.With().Str("scope", "handleInitialize").Logger()
+func (t *MCPTransport) handleInitialize(ctx context.Context, c *fiber.Ctx, body []byte, req *JSONRPCRequest, session *Session) error {
+	l := loggerFrom(ctx).With().Str("scope", "handleInitialize").Logger()
 
 	// Process through handler
-	response := t.handler.HandleRequest(c.Body())
+	response := t.handler.HandleRequest(ctx, body)
 
 	// Parse response to check if successful
 	var resp JSONRPCResponse
@@ -185,7 +497,7 @@ func (t *MCPTransport) handleInitialize(c *fiber.Ctx, req *JSONRPCRequest, sessi
 		}
 	}
 
-	return c.Send(response)
+	return t.sendResponse(c, response)
 }
 
 // setupOAuthMockEndpoints sets up mock OAuth endpoints for Claude Code compatibility
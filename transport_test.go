@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAllowedClientsGuardsEveryMethod(t *testing.T) {
+	cfg := &Config{UseSession: true, AllowedClients: []string{"good-client"}}
+	handler := NewJSONRPCHandler(0)
+	registerMCPMethods(handler, NewToolRegistry(cfg), NewMetadataResources(cfg, NewAdapterRegistry()), cfg)
+	transport := NewMCPTransport(handler, cfg, NewQueryMetrics(), NewAdapterRegistry())
+	app := fiber.New()
+	transport.SetupRoutes(app)
+
+	post := func(sessionID, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	initialize := func(client string) string {
+		resp := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+ProtocolVersion+`","clientInfo":{"name":"`+client+`"}}}`)
+		return resp.Header.Get("Mcp-Session-Id")
+	}
+	toolsList := `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`
+
+	if id := initialize("bad-client"); id != "" {
+		t.Errorf("rejected client got session %q", id)
+	}
+	if resp := post("", toolsList); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("tools/list without a session: status %d, want 403", resp.StatusCode)
+	}
+	if resp := post("unknown-session", toolsList); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("tools/list with an unknown session: status %d, want 403", resp.StatusCode)
+	}
+
+	id := initialize("Good-Client")
+	if id == "" {
+		t.Fatal("allowed client got no session")
+	}
+	if resp := post(id, toolsList); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("tools/list with an allowed session: status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	cfg := &Config{}
+	transport := NewMCPTransport(NewJSONRPCHandler(0), cfg, NewQueryMetrics(), NewAdapterRegistry())
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	transport.SetupRoutes(app)
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{method: http.MethodPost, path: "/health", status: fiber.StatusMethodNotAllowed, allow: "GET, HEAD"},
+		{method: http.MethodPut, path: "/", status: fiber.StatusMethodNotAllowed, allow: "GET, HEAD, POST"},
+		{method: http.MethodDelete, path: "/health/", status: fiber.StatusMethodNotAllowed, allow: "GET, HEAD"},
+		// GET / serves the discovery descriptor, so it is allowed
+		{method: http.MethodGet, path: "/", status: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get(fiber.HeaderAllow); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+
+			var body map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if tt.status == fiber.StatusMethodNotAllowed && body["error"] != "Method Not Allowed" {
+				t.Errorf("body = %v, want the JSON 405 error", body)
+			}
+			if tt.status == fiber.StatusOK && body["mcp_endpoint"] != "/" {
+				t.Errorf("body = %v, want the discovery descriptor", body)
+			}
+		})
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	app := fiber.New()
+	app.Get("/health", func(c *fiber.Ctx) error { return nil })
+	app.Post("/", func(c *fiber.Ctx) error { return nil })
+	app.Delete("/", func(c *fiber.Ctx) error { return nil })
+
+	if got := strings.Join(allowedMethods(app, "/"), ", "); got != "POST, DELETE" {
+		t.Errorf("allowedMethods(/) = %q, want POST, DELETE", got)
+	}
+	if got := strings.Join(allowedMethods(app, "/HEALTH/"), ", "); got != "GET, HEAD" {
+		t.Errorf("allowedMethods(/HEALTH/) = %q, want GET, HEAD", got)
+	}
+	if got := allowedMethods(app, "/missing"); got != nil {
+		t.Errorf("allowedMethods(/missing) = %q, want none", got)
+	}
+}
+
+func TestSessionLogNotifications(t *testing.T) {
+	cfg := &Config{UseSession: true}
+	handler := NewJSONRPCHandler(0)
+	registerMCPMethods(handler, NewToolRegistry(cfg), NewMetadataResources(cfg, NewAdapterRegistry()), cfg)
+	adapters := NewAdapterRegistry()
+	adapters.AddAfterQueryHook(NewQueryLogHook(cfg))
+	transport := NewMCPTransport(handler, cfg, NewQueryMetrics(), adapters)
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	transport.SetupRoutes(app)
+
+	post := func(sessionID, body string) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("%s: status %d", body, resp.StatusCode)
+		}
+	}
+	fetch := func(sessionID string) []LogEntry {
+		req := httptest.NewRequest(http.MethodGet, "/notifications", nil)
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var notifications []JSONRPCNotification
+		if err := json.NewDecoder(resp.Body).Decode(&notifications); err != nil {
+			t.Fatalf("notifications are not a JSON array: %v", err)
+		}
+		entries := []LogEntry{}
+		for _, n := range notifications {
+			var entry LogEntry
+			if n.Method != "notifications/message" || json.Unmarshal(n.Params, &entry) != nil {
+				t.Fatalf("unexpected notification %+v", n)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	open := func(level LogLevel, query string) string {
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(
+			`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+ProtocolVersion+`"}}`)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := resp.Header.Get("Mcp-Session-Id")
+		post(id, `{"jsonrpc":"2.0","id":2,"method":"logging/setLevel","params":{"level":"`+string(level)+`"}}`)
+
+		// One successful (debug) and one failed (warning) query on this session
+		session, _ := transport.sessionManager.GetSession(id)
+		ctx := withSession(context.Background(), session)
+		adapter := &recordingAdapter{name: "postgres"}
+		if _, err := adapters.ExecuteSelect(ctx, adapter, query); err != nil {
+			t.Fatal(err)
+		}
+		adapters.ObserveQuery(ctx, adapter, query+" broken", QueryResult{}, errors.New("syntax error"))
+		return id
+	}
+
+	verbose := open(LogLevelDebug, "SELECT 'verbose'")
+	quiet := open(LogLevelWarning, "SELECT 'quiet'")
+
+	got := fetch(verbose)
+	if len(got) != 2 || got[0].Level != LogLevelDebug || got[1].Level != LogLevelWarning {
+		t.Fatalf("debug session got %+v, want its debug and warning messages", got)
+	}
+	for _, entry := range got {
+		if !strings.Contains(entry.Data, "verbose") {
+			t.Errorf("debug session got another session's message %+v", entry)
+		}
+	}
+
+	got = fetch(quiet)
+	if len(got) != 1 || got[0].Level != LogLevelWarning || !strings.Contains(got[0].Data, "SELECT 'quiet' broken") {
+		t.Fatalf("warning session got %+v, want only its failed query", got)
+	}
+
+	if got := fetch(verbose); len(got) != 0 {
+		t.Errorf("second fetch got %+v, want the queue cleared", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notifications", nil)
+	req.Header.Set("Mcp-Session-Id", "unknown-session")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("unknown session: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestCompressedRequestBodies(t *testing.T) {
+	toolsList := []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	compress := func(newWriter func(*bytes.Buffer) io.WriteCloser, data []byte) []byte {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	gzipped := func(data []byte) []byte {
+		return compress(func(b *bytes.Buffer) io.WriteCloser { return gzip.NewWriter(b) }, data)
+	}
+	zlibbed := compress(func(b *bytes.Buffer) io.WriteCloser { return zlib.NewWriter(b) }, toolsList)
+	rawDeflate := compress(func(b *bytes.Buffer) io.WriteCloser {
+		w, _ := flate.NewWriter(b, flate.DefaultCompression)
+		return w
+	}, toolsList)
+	// Padding the request past the limit with whitespace keeps it valid JSON
+	oversized := gzipped(append(bytes.Repeat([]byte(" "), 4096), toolsList...))
+	truncated := gzipped(toolsList)
+	truncated = truncated[:len(truncated)/2]
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipped(toolsList), status: fiber.StatusOK},
+		{name: "x-gzip", encoding: "x-gzip", body: gzipped(toolsList), status: fiber.StatusOK},
+		{name: "zlib deflate", encoding: "deflate", body: zlibbed, status: fiber.StatusOK},
+		{name: "raw deflate", encoding: "Deflate", body: rawDeflate, status: fiber.StatusOK},
+		{name: "identity", encoding: "identity", body: toolsList, status: fiber.StatusOK},
+		{name: "corrupt gzip header", encoding: "gzip", body: toolsList, status: fiber.StatusBadRequest},
+		{name: "truncated gzip", encoding: "gzip", body: truncated, status: fiber.StatusBadRequest},
+		{name: "corrupt deflate", encoding: "deflate", body: []byte{0xff, 0xff, 0xff, 0xff}, status: fiber.StatusBadRequest},
+		{name: "unknown encoding", encoding: "br", body: toolsList, status: fiber.StatusUnsupportedMediaType},
+		{name: "over MAX_DECOMPRESSED_BODY_BYTES", encoding: "gzip", body: oversized, status: fiber.StatusRequestEntityTooLarge},
+	}
+
+	cfg := &Config{MaxDecompressedBodyBytes: 1024}
+	handler := NewJSONRPCHandler(0)
+	registerMCPMethods(handler, NewToolRegistry(cfg), NewMetadataResources(cfg, NewAdapterRegistry()), cfg)
+	transport := NewMCPTransport(handler, cfg, NewQueryMetrics(), NewAdapterRegistry())
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	transport.SetupRoutes(app)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", tt.encoding)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				body, _ := io.ReadAll(resp.Body)
+				t.Fatalf("status = %d, want %d (%s)", resp.StatusCode, tt.status, body)
+			}
+
+			var body map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if tt.status == fiber.StatusOK {
+				if _, ok := body["result"]; !ok {
+					t.Errorf("body = %v, want the tools/list result", body)
+				}
+			} else if _, ok := body["error"]; !ok {
+				t.Errorf("body = %v, want an error", body)
+			}
+		})
+	}
+}
+
+func TestPingKeepsSessionAlive(t *testing.T) {
+	cfg := &Config{UseSession: true}
+	handler := NewJSONRPCHandler(0)
+	registerMCPMethods(handler, NewToolRegistry(cfg), NewMetadataResources(cfg, NewAdapterRegistry()), cfg)
+	transport := NewMCPTransport(handler, cfg, NewQueryMetrics(), NewAdapterRegistry())
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	transport.SetupRoutes(app)
+
+	post := func(sessionID, body string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	resp, _ := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+ProtocolVersion+`","clientInfo":{"name":"c"}}}`)
+	id := resp.Header.Get("Mcp-Session-Id")
+	session, ok := transport.sessionManager.GetSession(id)
+	if !ok {
+		t.Fatal("initialize created no session")
+	}
+
+	// Idle for just under the TTL
+	session.mu.Lock()
+	session.LastActivity = time.Now().Add(-29 * time.Minute)
+	session.mu.Unlock()
+
+	resp, body := post(id, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	if resp.StatusCode != fiber.StatusOK || body != `{"jsonrpc":"2.0","id":2,"result":{}}` {
+		t.Fatalf("ping: status %d, body %s", resp.StatusCode, body)
+	}
+
+	session.mu.RLock()
+	idle := time.Since(session.LastActivity)
+	session.mu.RUnlock()
+	if idle > time.Minute {
+		t.Errorf("session idle for %v after ping, want it refreshed", idle)
+	}
+	if transport.sessionManager.isExpired(session, time.Now().Add(10*time.Minute)) {
+		t.Error("session expires 10 minutes after a ping, want the TTL restarted")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlexBool is a boolean tool argument that also accepts string forms such as
+// "true", "false", "1", or "0", which LLM clients frequently send
+type FlexBool bool
+
+// UnmarshalJSON accepts a JSON boolean or a string representation of one
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	parsed, ok := parseFlexBool(v)
+	if !ok {
+		return fmt.Errorf("invalid boolean value: %s", string(data))
+	}
+	*b = FlexBool(parsed)
+	return nil
+}
+
+// parseFlexBool interprets a decoded JSON value as a boolean
+func parseFlexBool(v interface{}) (bool, bool) {
+	switch val := v.(type) {
+	case nil:
+		return false, true
+	case bool:
+		return val, true
+	case string:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(val))
+		return parsed, err == nil
+	default:
+		return false, false
+	}
+}
+
+// normalizeBooleanArguments rewrites string booleans in properties the schema declares
+// as boolean into JSON booleans. Validation accepts "true" for a boolean, so every
+// handler sees a real boolean whether it decodes into bool or FlexBool. Other values
+// are passed through verbatim.
+func normalizeBooleanArguments(schema InputSchema, arguments json.RawMessage) json.RawMessage {
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return arguments
+	}
+
+	changed := false
+	for name, raw := range args {
+		prop, ok := schema.Properties[name].(map[string]interface{})
+		if !ok || prop["type"] != "boolean" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			continue
+		}
+		if parsed, ok := parseFlexBool(s); ok {
+			args[name] = json.RawMessage(strconv.FormatBool(parsed))
+			changed = true
+		}
+	}
+	if !changed {
+		return arguments
+	}
+
+	normalized, err := json.Marshal(args)
+	if err != nil {
+		return arguments
+	}
+	return normalized
+}
+
+// ValidationResult reports whether tool arguments are valid and why not
+type ValidationResult struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// validateArguments checks arguments against a tool's input schema, collecting every problem found
+func validateArguments(schema InputSchema, arguments json.RawMessage) []string {
+	args := map[string]interface{}{}
+	if len(arguments) > 0 && string(arguments) != "null" {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return []string{"arguments must be a JSON object"}
+		}
+	}
+
+	var errs []string
+	for _, name := range schema.Required {
+		if value, ok := args[name]; !ok || value == nil {
+			errs = append(errs, fmt.Sprintf("%s is required", name))
+		}
+	}
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := args[name]
+		if value == nil {
+			continue
+		}
+		prop, ok := schema.Properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		expected, ok := prop["type"].(string)
+		if !ok {
+			continue
+		}
+		if !matchesJSONType(value, expected) {
+			errs = append(errs, fmt.Sprintf("%s must be of type %s", name, expected))
+		}
+	}
+
+	return errs
+}
+
+// matchesJSONType reports whether a decoded JSON value matches a JSON Schema type
+func matchesJSONType(value interface{}, expected string) bool {
+	switch expected {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := parseFlexBool(value)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return true
+	}
+}
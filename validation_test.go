@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFlexBool(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    bool
+		wantErr bool
+	}{
+		{in: `true`, want: true},
+		{in: `false`, want: false},
+		{in: `"true"`, want: true},
+		{in: `" FALSE "`, want: false},
+		{in: `"1"`, want: true},
+		{in: `"0"`, want: false},
+		{in: `null`, want: false},
+		{in: `"yes"`, wantErr: true},
+		{in: `1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var b FlexBool
+		err := json.Unmarshal([]byte(tt.in), &b)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && bool(b) != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, b, tt.want)
+		}
+	}
+}
+
+var validationTestSchema = InputSchema{
+	Type: "object",
+	Properties: map[string]interface{}{
+		"query":   map[string]interface{}{"type": "string"},
+		"limit":   map[string]interface{}{"type": "integer"},
+		"dry_run": map[string]interface{}{"type": "boolean"},
+		"columns": map[string]interface{}{"type": "array"},
+	},
+	Required: []string{"query"},
+}
+
+func TestValidateArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		want      []string
+	}{
+		{name: "valid", arguments: `{"query":"SELECT 1","limit":5,"dry_run":true,"columns":["a"]}`},
+		{name: "string boolean", arguments: `{"query":"SELECT 1","dry_run":"false"}`},
+		{name: "null optional", arguments: `{"query":"SELECT 1","limit":null}`},
+		{name: "unknown property", arguments: `{"query":"SELECT 1","other":1}`},
+		{name: "missing required", arguments: `{}`, want: []string{"query is required"}},
+		{name: "null arguments", arguments: `null`, want: []string{"query is required"}},
+		{
+			name:      "every type error",
+			arguments: `{"query":1,"limit":1.5,"dry_run":"maybe","columns":"a"}`,
+			want: []string{
+				"columns must be of type array",
+				"dry_run must be of type boolean",
+				"limit must be of type integer",
+				"query must be of type string",
+			},
+		},
+		{name: "not an object", arguments: `[1]`, want: []string{"arguments must be a JSON object"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validateArguments(validationTestSchema, json.RawMessage(tt.arguments))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateArguments(%s) = %q, want %q", tt.arguments, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeBooleanArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		want      string
+	}{
+		{name: "string true", arguments: `{"dry_run":"true"}`, want: `{"dry_run":true}`},
+		{name: "string zero", arguments: `{"dry_run":"0"}`, want: `{"dry_run":false}`},
+		{name: "already boolean", arguments: `{"dry_run":true}`, want: `{"dry_run":true}`},
+		{name: "string property untouched", arguments: `{"query":"true"}`, want: `{"query":"true"}`},
+		{name: "invalid string left for the handler", arguments: `{"dry_run":"maybe"}`, want: `{"dry_run":"maybe"}`},
+		{name: "large integers kept verbatim", arguments: `{"dry_run":"1","limit":9007199254740993}`, want: `{"dry_run":true,"limit":9007199254740993}`},
+		{name: "null arguments", arguments: `null`, want: `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeBooleanArguments(validationTestSchema, json.RawMessage(tt.arguments))
+			if string(got) != tt.want {
+				t.Errorf("normalizeBooleanArguments(%s) = %s, want %s", tt.arguments, got, tt.want)
+			}
+
+			// Whatever validation accepts must decode into a plain bool
+			if validateArguments(validationTestSchema, json.RawMessage(tt.arguments)) == nil {
+				var params struct {
+					DryRun bool `json:"dry_run"`
+				}
+				if err := parseArguments(got, &params); err != nil {
+					t.Errorf("parseArguments(%s) = %v", got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresQueryToolValidators(t *testing.T) {
+	cfg := &Config{}
+	registry := NewToolRegistry(cfg)
+	registerPostgresQueryTools(registry, NewAdapterRegistry(), catalogTestAdapter(nil, nil), cfg)
+
+	tests := []struct {
+		tool      string
+		arguments string
+		valid     bool
+	}{
+		{tool: "postgres_estimate_rows", arguments: `{"query":"SELECT * FROM users"}`, valid: true},
+		{tool: "postgres_estimate_rows", arguments: `{"query":"DELETE FROM users"}`},
+		{tool: "postgres_multi_query", arguments: `{"queries":{"a":"SELECT 1","b":"SELECT 2"}}`, valid: true},
+		{tool: "postgres_multi_query", arguments: `{"queries":{"a":"SELECT 1","b":"DROP TABLE users"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool+" "+tt.arguments, func(t *testing.T) {
+			result, err := registry.ValidateTool(tt.tool, json.RawMessage(tt.arguments))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != tt.valid {
+				t.Errorf("ValidateTool = %+v, want valid %v", result, tt.valid)
+			}
+		})
+	}
+}